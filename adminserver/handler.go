@@ -0,0 +1,122 @@
+package adminserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/unregistration"
+)
+
+const (
+	RoutingTablePath    = "/routing-table"
+	DeadLettersPath     = "/dead-letters"
+	UnregistrationsPath = "/unregistrations"
+)
+
+// DeadLetterSource lists the messages the emitter gave up on publishing.
+type DeadLetterSource interface {
+	DeadLetters() []emitter.DeadLetter
+}
+
+// UnregistrationSource lists the unregistrations that are still being sent.
+type UnregistrationSource interface {
+	Snapshot() []unregistration.Message
+}
+
+type handler struct {
+	logger lager.Logger
+	table  routingtable.RoutingTable
+}
+
+// NewHandler returns a read-only handler that serves the contents of the
+// routing table as JSON. Entries can be filtered with the process_guid,
+// hostname and router_group_guid query parameters. The dead letters and the
+// pending unregistrations are served as well when their source is given.
+func NewHandler(
+	logger lager.Logger,
+	table routingtable.RoutingTable,
+	deadLetters DeadLetterSource,
+	unregistrations UnregistrationSource,
+) http.Handler {
+	logger = logger.Session("admin-server")
+	mux := http.NewServeMux()
+	mux.Handle(RoutingTablePath, &handler{
+		logger: logger,
+		table:  table,
+	})
+	if deadLetters != nil {
+		mux.Handle(DeadLettersPath, &listHandler{
+			logger: logger.Session("dead-letters"),
+			list:   func() interface{} { return deadLetters.DeadLetters() },
+		})
+	}
+	if unregistrations != nil {
+		mux.Handle(UnregistrationsPath, &listHandler{
+			logger: logger.Session("unregistrations"),
+			list:   func() interface{} { return unregistrations.Snapshot() },
+		})
+	}
+	return mux
+}
+
+func (h *handler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	logger := h.logger.Session("routing-table")
+
+	if req.Method != http.MethodGet {
+		resp.Header().Set("Allow", http.MethodGet)
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	filter := routingtable.EntryFilter{
+		ProcessGUID:     query.Get("process_guid"),
+		Hostname:        query.Get("hostname"),
+		RouterGroupGUID: query.Get("router_group_guid"),
+	}
+
+	payload, err := json.Marshal(h.table.Entries(filter))
+	if err != nil {
+		logger.Error("failed-to-marshal-entries", err)
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+	_, err = resp.Write(payload)
+	if err != nil {
+		logger.Error("failed-to-write-response", err)
+	}
+}
+
+// listHandler serves the result of list as JSON.
+type listHandler struct {
+	logger lager.Logger
+	list   func() interface{}
+}
+
+func (h *listHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		resp.Header().Set("Allow", http.MethodGet)
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := json.Marshal(h.list())
+	if err != nil {
+		h.logger.Error("failed-to-marshal-list", err)
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+	_, err = resp.Write(payload)
+	if err != nil {
+		h.logger.Error("failed-to-write-response", err)
+	}
+}
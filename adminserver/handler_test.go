@@ -0,0 +1,182 @@
+package adminserver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/adminserver"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	"code.cloudfoundry.org/route-emitter/unregistration"
+	"code.cloudfoundry.org/route-emitter/unregistration/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		fakeTable *fakeroutingtable.FakeRoutingTable
+		handler   http.Handler
+		recorder  *httptest.ResponseRecorder
+		entries   routingtable.TableEntries
+	)
+
+	BeforeEach(func() {
+		fakeTable = &fakeroutingtable.FakeRoutingTable{}
+		handler = adminserver.NewHandler(lagertest.NewTestLogger("test"), fakeTable, nil, nil)
+		recorder = httptest.NewRecorder()
+
+		key := routingtable.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+		entries = routingtable.TableEntries{
+			HTTP: map[string]routingtable.Entry{
+				key.String(): {
+					RoutingKey:       key,
+					Domain:           "domain",
+					Routes:           []routingtable.Route{{Hostname: "foo.example.com", LogGUID: "log-guid"}},
+					Endpoints:        []routingtable.Endpoint{{InstanceGUID: "instance-guid", Host: "1.1.1.1", Port: 61000, ContainerPort: 8080}},
+					DesiredInstances: 1,
+				},
+			},
+			TCP:      map[string]routingtable.Entry{},
+			Internal: map[string]routingtable.Entry{},
+		}
+		fakeTable.EntriesReturns(entries)
+	})
+
+	It("serves the routing table entries as json", func() {
+		req := httptest.NewRequest(http.MethodGet, adminserver.RoutingTablePath, nil)
+		handler.ServeHTTP(recorder, req)
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var response routingtable.TableEntries
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.HTTP).To(HaveKey("process-guid:8080"))
+		entry := response.HTTP["process-guid:8080"]
+		Expect(entry.Domain).To(Equal("domain"))
+		Expect(entry.DesiredInstances).To(BeEquivalentTo(1))
+		Expect(entry.Routes).To(Equal(entries.HTTP["process-guid:8080"].Routes))
+		Expect(entry.Endpoints).To(HaveLen(1))
+		Expect(entry.Endpoints[0].InstanceGUID).To(Equal("instance-guid"))
+		Expect(response.TCP).To(BeEmpty())
+		Expect(response.Internal).To(BeEmpty())
+	})
+
+	It("passes the query parameters to the routing table as a filter", func() {
+		req := httptest.NewRequest(http.MethodGet, adminserver.RoutingTablePath+"?process_guid=pg&hostname=foo.example.com&router_group_guid=rg", nil)
+		handler.ServeHTTP(recorder, req)
+
+		Expect(fakeTable.EntriesCallCount()).To(Equal(1))
+		Expect(fakeTable.EntriesArgsForCall(0)).To(Equal(routingtable.EntryFilter{
+			ProcessGUID:     "pg",
+			Hostname:        "foo.example.com",
+			RouterGroupGUID: "rg",
+		}))
+	})
+
+	It("does not filter when no query parameters are given", func() {
+		req := httptest.NewRequest(http.MethodGet, adminserver.RoutingTablePath, nil)
+		handler.ServeHTTP(recorder, req)
+
+		Expect(fakeTable.EntriesArgsForCall(0)).To(Equal(routingtable.EntryFilter{}))
+	})
+
+	Context("when the request is not a GET", func() {
+		It("rejects the request", func() {
+			req := httptest.NewRequest(http.MethodPost, adminserver.RoutingTablePath, nil)
+			handler.ServeHTTP(recorder, req)
+
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(fakeTable.EntriesCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the path is unknown", func() {
+		It("returns not found", func() {
+			req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+			handler.ServeHTTP(recorder, req)
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("dead letters", func() {
+		It("is not served without a source", func() {
+			req := httptest.NewRequest(http.MethodGet, adminserver.DeadLettersPath, nil)
+			handler.ServeHTTP(recorder, req)
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+
+		Context("when a source is given", func() {
+			var deadLetters []emitter.DeadLetter
+
+			BeforeEach(func() {
+				deadLetters = []emitter.DeadLetter{{
+					Subject:   "router.register",
+					Message:   routingtable.RegistryMessage{URIs: []string{"foo.example.com"}, Host: "1.1.1.1", Port: 61000},
+					Attempts:  5,
+					LastError: "nats down",
+				}}
+				handler = adminserver.NewHandler(lagertest.NewTestLogger("test"), fakeTable, deadLetterSource(deadLetters), nil)
+			})
+
+			It("serves the dead letters as json", func() {
+				req := httptest.NewRequest(http.MethodGet, adminserver.DeadLettersPath, nil)
+				handler.ServeHTTP(recorder, req)
+
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+
+				var response []emitter.DeadLetter
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response).To(Equal(deadLetters))
+			})
+		})
+	})
+
+	Describe("unregistrations", func() {
+		It("is not served without a source", func() {
+			req := httptest.NewRequest(http.MethodGet, adminserver.UnregistrationsPath, nil)
+			handler.ServeHTTP(recorder, req)
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+
+		Context("when a source is given", func() {
+			var messages []unregistration.Message
+
+			BeforeEach(func() {
+				messages = []unregistration.Message{{
+					RouteType:       unregistration.HTTPRoute,
+					RegistryMessage: routingtable.RegistryMessage{URIs: []string{"foo.example.com"}, Host: "1.1.1.1", Port: 61000},
+					SentCount:       2,
+				}}
+				fakeCache := &fakes.FakeCache{}
+				fakeCache.SnapshotReturns(messages)
+				handler = adminserver.NewHandler(lagertest.NewTestLogger("test"), fakeTable, nil, fakeCache)
+			})
+
+			It("serves the pending unregistrations as json", func() {
+				req := httptest.NewRequest(http.MethodGet, adminserver.UnregistrationsPath, nil)
+				handler.ServeHTTP(recorder, req)
+
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+
+				var response []unregistration.Message
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response).To(Equal(messages))
+			})
+		})
+	})
+})
+
+type deadLetterSource []emitter.DeadLetter
+
+func (s deadLetterSource) DeadLetters() []emitter.DeadLetter {
+	return s
+}
@@ -0,0 +1 @@
+package adminserver // import "code.cloudfoundry.org/route-emitter/adminserver"
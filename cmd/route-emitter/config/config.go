@@ -11,6 +11,39 @@ import (
 	"code.cloudfoundry.org/locket"
 )
 
+// Sinks the external http routes can be emitted to.
+const (
+	RouteSinkNATS       = "nats"
+	RouteSinkRoutingAPI = "routing_api"
+	RouteSinkBoth       = "both"
+)
+
+// Route types a NATS target can be limited to.
+const (
+	NATSTargetRouteTypeHTTP     = "http"
+	NATSTargetRouteTypeInternal = "internal"
+)
+
+// NATSTargetConfig is an additional NATS cluster the routes are emitted to,
+// e.g. while the routers are migrated to a new cluster. RouteTypes limits
+// the target to http or internal routes and Domains limits the http routes
+// to hostnames in these domains, everything is emitted when they are empty.
+type NATSTargetConfig struct {
+	Name           string   `json:"name"`
+	Addresses      string   `json:"addresses"`
+	Username       string   `json:"username,omitempty"`
+	Password       string   `json:"password,omitempty"`
+	PasswordFile   string   `json:"password_file,omitempty"`
+	NKeySeedFile   string   `json:"nkey_seed_file,omitempty"`
+	CredsFile      string   `json:"creds_file,omitempty"`
+	TLSEnabled     bool     `json:"tls_enabled,omitempty"`
+	CACertFile     string   `json:"ca_cert_file,omitempty"`
+	ClientCertFile string   `json:"client_cert_file,omitempty"`
+	ClientKeyFile  string   `json:"client_key_file,omitempty"`
+	RouteTypes     []string `json:"route_types,omitempty"`
+	Domains        []string `json:"domains,omitempty"`
+}
+
 type RoutingAPIConfig struct {
 	URL            string `json:"url"`
 	Port           int    `json:"port"`
@@ -41,28 +74,62 @@ type RouteEmitterConfig struct {
 	RegisterDirectInstanceRoutes bool                  `json:"register_direct_instance_routes,omitempty"`
 	CommunicationTimeout         durationjson.Duration `json:"communication_timeout,omitempty"`
 	HealthCheckAddress           string                `json:"healthcheck_address,omitempty"`
+	ReadinessSyncThreshold       durationjson.Duration `json:"readiness_sync_threshold,omitempty"`
+	HealthSyncThreshold          durationjson.Duration `json:"health_sync_threshold,omitempty"`
+	HealthSubscriptionThreshold  durationjson.Duration `json:"health_subscription_threshold,omitempty"`
+	AdminAddress                 string                `json:"admin_address,omitempty"`
+	XDSAddress                   string                `json:"xds_address,omitempty"`
+	XDSServerCertFile            string                `json:"xds_server_cert_file,omitempty"`
+	XDSServerKeyFile             string                `json:"xds_server_key_file,omitempty"`
+	XDSCACertFile                string                `json:"xds_ca_cert_file,omitempty"`
+	DNSAddress                   string                `json:"dns_address,omitempty"`
+	PrometheusAddress            string                `json:"prometheus_address,omitempty"`
 	LockRetryInterval            durationjson.Duration `json:"lock_retry_interval,omitempty"`
 	LockTTL                      durationjson.Duration `json:"lock_ttl,omitempty"`
 	NATSAddresses                string                `json:"nats_addresses,omitempty"`
 	NATSUsername                 string                `json:"nats_username,omitempty"`
 	NATSPassword                 string                `json:"nats_password,omitempty"`
+	NATSPasswordFile             string                `json:"nats_password_file,omitempty"`
+	NATSNKeySeedFile             string                `json:"nats_nkey_seed_file,omitempty"`
+	NATSCredsFile                string                `json:"nats_creds_file,omitempty"`
 	NATSTLSEnabled               bool                  `json:"nats_tls_enabled"`
 	NATSCACertFile               string                `json:"nats_ca_cert_file"`
 	NATSClientCertFile           string                `json:"nats_client_cert_file"`
 	NATSClientKeyFile            string                `json:"nats_client_key_file"`
+	TLSReloadInterval            durationjson.Duration `json:"tls_reload_interval,omitempty"`
 	RouteEmittingWorkers         int                   `json:"route_emitting_workers,omitempty"`
+	EmitShards                   int                   `json:"emit_shards,omitempty"`
+	EmitQueueSize                int                   `json:"emit_queue_size,omitempty"`
+	NATSRetryMaxAttempts         int                   `json:"nats_retry_max_attempts,omitempty"`
+	NATSRetryInitialBackoff      durationjson.Duration `json:"nats_retry_initial_backoff,omitempty"`
+	NATSRetryMaxBackoff          durationjson.Duration `json:"nats_retry_max_backoff,omitempty"`
+	NATSRetryMaxPending          int                   `json:"nats_retry_max_pending,omitempty"`
+	NATSDeadLetterCapacity       int                   `json:"nats_dead_letter_capacity,omitempty"`
+	NATSTargets                  []NATSTargetConfig    `json:"nats_targets,omitempty"`
 	SyncInterval                 durationjson.Duration `json:"sync_interval,omitempty"`
 	TCPRouteTTL                  durationjson.Duration `json:"tcp_route_ttl,omitempty"`
 	OAuth                        OAuthConfig           `json:"oauth"`
 	RoutingAPI                   RoutingAPIConfig      `json:"routing_api"`
 	EnableTCPEmitter             bool                  `json:"enable_tcp_emitter"`
+	HTTPRouteSink                string                `json:"http_route_sink,omitempty"`
+	HTTPRouteTTL                 durationjson.Duration `json:"http_route_ttl,omitempty"`
+	TCPReconcileEnabled          bool                  `json:"tcp_reconcile_enabled,omitempty"`
+	TCPReconcileRouterGroups     []string              `json:"tcp_reconcile_router_groups,omitempty"`
 	LoggregatorConfig            loggingclient.Config  `json:"loggregator"`
 	ReportInterval               durationjson.Duration `json:"report_interval,omitempty"`
 	UnregistrationInterval       durationjson.Duration `json:"unregistration_interval,omitempty"`
 	UnregistrationSendCount      int                   `json:"unregistration_send_count,omitempty"`
+	UnregistrationCacheMaxSize   int                   `json:"unregistration_cache_max_size,omitempty"`
+	UnregistrationCacheTTL       durationjson.Duration `json:"unregistration_cache_ttl,omitempty"`
+	UnregistrationCacheFile      string                `json:"unregistration_cache_file,omitempty"`
+	MassUnregistrationThreshold  int                   `json:"mass_unregistration_threshold_percent,omitempty"`
 	EnableInternalEmitter        bool                  `json:"enable_internal_emitter"`
 	LocketEnabled                bool                  `json:"locket_enabled"`
 	LocketSessionName            string                `json:"locket_session_name"`
+	HotStandby                   bool                  `json:"hot_standby,omitempty"`
+	SnapshotFile                 string                `json:"snapshot_file,omitempty"`
+	SnapshotInterval             durationjson.Duration `json:"snapshot_interval,omitempty"`
+	SnapshotMaxAge               durationjson.Duration `json:"snapshot_max_age,omitempty"`
 
 	lagerflags.LagerConfig
 	debugserver.DebugServerConfig
@@ -80,6 +147,8 @@ func NewRouteEmitterConfig(configPath string) (RouteEmitterConfig, error) {
 	defer configFile.Close()
 
 	decoder := json.NewDecoder(configFile)
+	// a misspelled key would otherwise silently fall back to the default
+	decoder.DisallowUnknownFields()
 	err = decoder.Decode(&routeEmitterConfig)
 	if err != nil {
 		return RouteEmitterConfig{}, err
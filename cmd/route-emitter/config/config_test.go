@@ -23,6 +23,16 @@ var _ = Describe("Config", func() {
 	BeforeEach(func() {
 		configData = `{
 			"healthcheck_address": "127.0.0.1:8090",
+			"readiness_sync_threshold": "2m",
+			"health_sync_threshold": "5m",
+			"health_subscription_threshold": "1m",
+			"admin_address": "127.0.0.1:8091",
+			"xds_address": "127.0.0.1:8092",
+			"xds_server_cert_file": "/tmp/xds_server_cert",
+			"xds_server_key_file": "/tmp/xds_server_key",
+			"xds_ca_cert_file": "/tmp/xds_ca_cert",
+			"dns_address": "127.0.0.1:8053",
+			"prometheus_address": "127.0.0.1:9090",
 			"cell_id": "cellID",
 			"uuid": "bosh-boshy-bosh-bosh",
 			"communication_timeout":"2s",
@@ -34,19 +44,43 @@ var _ = Describe("Config", func() {
 			"bbs_client_session_cache_size": 100,
 			"bbs_max_idle_conns_per_host": 10,
 			"route_emitting_workers": 18,
+			"emit_shards": 6,
+			"emit_queue_size": 1000,
+			"nats_retry_max_attempts": 5,
+			"nats_retry_initial_backoff": "2s",
+			"nats_retry_max_backoff": "1m",
+			"nats_retry_max_pending": 500,
+			"nats_dead_letter_capacity": 50,
+			"nats_targets": [{
+				"name": "new-cluster",
+				"addresses": "127.0.0.3:4222",
+				"creds_file": "/var/vcap/jobs/route_emitter/config/new-cluster.creds",
+				"tls_enabled": true,
+				"ca_cert_file": "/tmp/new_cluster_ca_cert",
+				"route_types": ["http"],
+				"domains": ["apps.example.com"]
+			}],
 			"nats_addresses": "http://127.0.0.2:4222",
 			"nats_username": "user",
 			"nats_password": "password",
+			"nats_password_file": "/var/vcap/jobs/route_emitter/config/nats_password",
+			"nats_nkey_seed_file": "/var/vcap/jobs/route_emitter/config/nats.nk",
+			"nats_creds_file": "/var/vcap/jobs/route_emitter/config/nats.creds",
 			"nats_tls_enabled": true,
 			"nats_ca_cert_file": "/tmp/nats_ca_cert",
 			"nats_client_cert_file": "/tmp/nats_client_cert",
 			"nats_client_key_file": "/tmp/nats_client_key",
+			"tls_reload_interval": "30s",
 			"lock_retry_interval": "15s",
 			"lock_ttl": "20s",
 			"tcp_route_ttl": "2m",
 			"log_level": "debug",
 			"debug_address": "127.0.0.1:9999",
 			"enable_tcp_emitter": true,
+			"http_route_sink": "both",
+			"http_route_ttl": "2m",
+			"tcp_reconcile_enabled": true,
+			"tcp_reconcile_router_groups": ["router-group-1", "router-group-2"],
 			"enable_internal_emitter": true,
 			"register_direct_instance_routes": true,
 			"routing_api": {
@@ -57,6 +91,14 @@ var _ = Describe("Config", func() {
 				"client_key_file": "/tmp/routing_api_client_key_file"
 			},
 			"locket_enabled": true,
+			"hot_standby": true,
+			"mass_unregistration_threshold_percent": 40,
+			"unregistration_cache_max_size": 5000,
+			"unregistration_cache_ttl": "5m",
+			"unregistration_cache_file": "/var/vcap/data/route-emitter/unregistrations.json",
+			"snapshot_file": "/var/vcap/data/route-emitter/routing-table.json",
+			"snapshot_interval": "30s",
+			"snapshot_max_age": "10m",
 			"locket_address": "127.0.0.1:18018",
 			"locket_ca_cert_file": "locket-ca-cert",
 			"report_interval": "1m",
@@ -109,6 +151,16 @@ var _ = Describe("Config", func() {
 
 		expectedConfig := config.RouteEmitterConfig{
 			HealthCheckAddress:           "127.0.0.1:8090",
+			ReadinessSyncThreshold:       durationjson.Duration(2 * time.Minute),
+			HealthSyncThreshold:          durationjson.Duration(5 * time.Minute),
+			HealthSubscriptionThreshold:  durationjson.Duration(time.Minute),
+			AdminAddress:                 "127.0.0.1:8091",
+			XDSAddress:                   "127.0.0.1:8092",
+			XDSServerCertFile:            "/tmp/xds_server_cert",
+			XDSServerKeyFile:             "/tmp/xds_server_key",
+			XDSCACertFile:                "/tmp/xds_ca_cert",
+			DNSAddress:                   "127.0.0.1:8053",
+			PrometheusAddress:            "127.0.0.1:9090",
 			CellID:                       "cellID",
 			UUID:                         "bosh-boshy-bosh-bosh",
 			CommunicationTimeout:         durationjson.Duration(2 * time.Second),
@@ -122,19 +174,42 @@ var _ = Describe("Config", func() {
 			NATSAddresses:                "http://127.0.0.2:4222",
 			NATSUsername:                 "user",
 			NATSPassword:                 "password",
+			NATSPasswordFile:             "/var/vcap/jobs/route_emitter/config/nats_password",
+			NATSNKeySeedFile:             "/var/vcap/jobs/route_emitter/config/nats.nk",
+			NATSCredsFile:                "/var/vcap/jobs/route_emitter/config/nats.creds",
 			NATSTLSEnabled:               true,
 			NATSCACertFile:               "/tmp/nats_ca_cert",
 			NATSClientCertFile:           "/tmp/nats_client_cert",
 			NATSClientKeyFile:            "/tmp/nats_client_key",
+			TLSReloadInterval:            durationjson.Duration(30 * time.Second),
 			LockRetryInterval:            durationjson.Duration(15 * time.Second),
 			LockTTL:                      durationjson.Duration(20 * time.Second),
 			RouteEmittingWorkers:         18,
+			EmitShards:                   6,
+			EmitQueueSize:                1000,
+			NATSRetryMaxAttempts:         5,
+			NATSRetryInitialBackoff:      durationjson.Duration(2 * time.Second),
+			NATSRetryMaxBackoff:          durationjson.Duration(time.Minute),
+			NATSRetryMaxPending:          500,
+			NATSDeadLetterCapacity:       50,
 			TCPRouteTTL:                  durationjson.Duration(2 * time.Minute),
 			ReportInterval:               durationjson.Duration(1 * time.Minute),
 			EnableTCPEmitter:             true,
+			HTTPRouteSink:                "both",
+			HTTPRouteTTL:                 durationjson.Duration(2 * time.Minute),
+			TCPReconcileEnabled:          true,
+			TCPReconcileRouterGroups:     []string{"router-group-1", "router-group-2"},
 			EnableInternalEmitter:        true,
 			RegisterDirectInstanceRoutes: true,
 			LocketEnabled:                true,
+			HotStandby:                   true,
+			MassUnregistrationThreshold:  40,
+			UnregistrationCacheMaxSize:   5000,
+			UnregistrationCacheTTL:       durationjson.Duration(5 * time.Minute),
+			UnregistrationCacheFile:      "/var/vcap/data/route-emitter/unregistrations.json",
+			SnapshotFile:                 "/var/vcap/data/route-emitter/routing-table.json",
+			SnapshotInterval:             durationjson.Duration(30 * time.Second),
+			SnapshotMaxAge:               durationjson.Duration(10 * time.Minute),
 			RoutingAPI: config.RoutingAPIConfig{
 				URL:            "https://routing-api.cf.service.internal",
 				Port:           443,
@@ -142,6 +217,15 @@ var _ = Describe("Config", func() {
 				ClientCertFile: "/tmp/routing_api_client_cert_file",
 				ClientKeyFile:  "/tmp/routing_api_client_key_file",
 			},
+			NATSTargets: []config.NATSTargetConfig{{
+				Name:       "new-cluster",
+				Addresses:  "127.0.0.3:4222",
+				CredsFile:  "/var/vcap/jobs/route_emitter/config/new-cluster.creds",
+				TLSEnabled: true,
+				CACertFile: "/tmp/new_cluster_ca_cert",
+				RouteTypes: []string{"http"},
+				Domains:    []string{"apps.example.com"},
+			}},
 			DebugServerConfig: debugserver.DebugServerConfig{
 				DebugAddress: "127.0.0.1:9999",
 			},
@@ -186,6 +270,17 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Context("when the file contains an unknown field", func() {
+		BeforeEach(func() {
+			configData = `{"sync_interval": "4s", "sync_intreval": "4s"}`
+		})
+
+		It("returns an error naming the field", func() {
+			_, err := config.NewRouteEmitterConfig(configPath)
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "sync_intreval"`)))
+		})
+	})
+
 	Context("when the file does not contain valid json", func() {
 		BeforeEach(func() {
 			configData = "{{"
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Reload returns the config to run with after the config file was read again
+// as next. The settings that can be changed at runtime are taken from next,
+// everything else is kept. The JSON keys of the fields that changed but need
+// a restart are returned as well, in the order of the config.
+func (c RouteEmitterConfig) Reload(next RouteEmitterConfig) (RouteEmitterConfig, []string) {
+	reloaded := c
+	reloaded.SyncInterval = next.SyncInterval
+	reloaded.UnregistrationInterval = next.UnregistrationInterval
+	reloaded.UnregistrationSendCount = next.UnregistrationSendCount
+	reloaded.RouteEmittingWorkers = next.RouteEmittingWorkers
+	reloaded.LagerConfig.LogLevel = next.LagerConfig.LogLevel
+
+	return reloaded, changedFields(reflect.ValueOf(reloaded), reflect.ValueOf(next))
+}
+
+func changedFields(current, next reflect.Value) []string {
+	changed := []string{}
+	for i := 0; i < current.NumField(); i++ {
+		field := current.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			changed = append(changed, changedFields(current.Field(i), next.Field(i))...)
+			continue
+		}
+		if !reflect.DeepEqual(current.Field(i).Interface(), next.Field(i).Interface()) {
+			changed = append(changed, jsonKey(field))
+		}
+	}
+	return changed
+}
+
+func jsonKey(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
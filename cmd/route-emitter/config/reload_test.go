@@ -0,0 +1,58 @@
+package config_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager/v3/lagerflags"
+	"code.cloudfoundry.org/locket"
+	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reload", func() {
+	var current config.RouteEmitterConfig
+
+	BeforeEach(func() {
+		current = config.RouteEmitterConfig{
+			BBSAddress:              "https://bbs.service.cf.internal:8889",
+			SyncInterval:            durationjson.Duration(time.Minute),
+			UnregistrationInterval:  durationjson.Duration(5 * time.Second),
+			UnregistrationSendCount: 3,
+			RouteEmittingWorkers:    20,
+			EnableInternalEmitter:   true,
+			LagerConfig:             lagerflags.LagerConfig{LogLevel: lagerflags.INFO},
+			ClientLocketConfig:      locket.ClientLocketConfig{LocketAddress: "127.0.0.1:8891"},
+		}
+	})
+
+	It("applies the settings that can be changed at runtime", func() {
+		next := current
+		next.SyncInterval = durationjson.Duration(30 * time.Second)
+		next.UnregistrationInterval = durationjson.Duration(time.Second)
+		next.UnregistrationSendCount = 5
+		next.RouteEmittingWorkers = 8
+		next.LagerConfig.LogLevel = lagerflags.DEBUG
+
+		reloaded, rejected := current.Reload(next)
+		Expect(rejected).To(BeEmpty())
+		Expect(reloaded).To(Equal(next))
+	})
+
+	It("keeps and reports the settings that need a restart", func() {
+		next := current
+		next.SyncInterval = durationjson.Duration(30 * time.Second)
+		next.BBSAddress = "https://other-bbs.service.cf.internal:8889"
+		next.EnableInternalEmitter = false
+		next.LocketAddress = "127.0.0.1:9999"
+
+		reloaded, rejected := current.Reload(next)
+		Expect(rejected).To(Equal([]string{"bbs_address", "enable_internal_emitter", "locket_address"}))
+
+		expected := current
+		expected.SyncInterval = next.SyncInterval
+		Expect(reloaded).To(Equal(expected))
+	})
+})
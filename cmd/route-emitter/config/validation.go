@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagerflags"
+)
+
+// maxTCPRouteTTL is the largest TTL the routing API accepts, in seconds.
+const maxTCPRouteTTL = 65535 * time.Second
+
+// FieldError is a problem with the value of a config field, the field is
+// named by its JSON key.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every problem Validate found.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Error())
+	}
+	return "invalid config: " + strings.Join(messages, "; ")
+}
+
+// Validate checks the values that would otherwise only fail once the emitter
+// is running. It returns a ValidationError listing all of the problems.
+func (c RouteEmitterConfig) Validate() error {
+	var errs ValidationError
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.BBSAddress == "" {
+		add("bbs_address", "must be set")
+	}
+	if c.BBSCACertFile == "" || c.BBSClientCertFile == "" || c.BBSClientKeyFile == "" {
+		add("bbs_client_cert_file", "bbs_ca_cert_file, bbs_client_cert_file and bbs_client_key_file must be set, the bbs is only reached over mutual TLS")
+	}
+	if c.NATSAddresses == "" {
+		add("nats_addresses", "must be set")
+	}
+	if c.SyncInterval <= 0 {
+		add("sync_interval", "must be greater than zero, got %s", time.Duration(c.SyncInterval))
+	}
+	if c.UnregistrationInterval <= 0 {
+		add("unregistration_interval", "must be greater than zero, got %s", time.Duration(c.UnregistrationInterval))
+	}
+	if c.RouteEmittingWorkers <= 0 {
+		add("route_emitting_workers", "must be greater than zero, got %d", c.RouteEmittingWorkers)
+	}
+	if c.EmitShards < 0 {
+		add("emit_shards", "must not be negative, got %d", c.EmitShards)
+	}
+	if c.EmitQueueSize < 0 {
+		add("emit_queue_size", "must not be negative, 0 disables the emit queue, got %d", c.EmitQueueSize)
+	}
+	if c.NATSRetryMaxAttempts < 0 {
+		add("nats_retry_max_attempts", "must not be negative, 0 disables the retries, got %d", c.NATSRetryMaxAttempts)
+	}
+	if c.NATSRetryMaxPending < 0 {
+		add("nats_retry_max_pending", "must not be negative, got %d", c.NATSRetryMaxPending)
+	}
+	if c.NATSDeadLetterCapacity < 0 {
+		add("nats_dead_letter_capacity", "must not be negative, got %d", c.NATSDeadLetterCapacity)
+	}
+	if c.UnregistrationSendCount < 0 {
+		add("unregistration_send_count", "must not be negative, got %d", c.UnregistrationSendCount)
+	}
+	if c.UnregistrationCacheMaxSize < 0 {
+		add("unregistration_cache_max_size", "must not be negative, got %d", c.UnregistrationCacheMaxSize)
+	}
+	if c.MassUnregistrationThreshold < 0 || c.MassUnregistrationThreshold > 100 {
+		add("mass_unregistration_threshold_percent", "must be between 0 and 100, got %d", c.MassUnregistrationThreshold)
+	}
+	switch c.LagerConfig.LogLevel {
+	case "", lagerflags.DEBUG, lagerflags.INFO, lagerflags.ERROR, lagerflags.FATAL:
+	default:
+		add("log_level", "must be one of %q, %q, %q or %q, got %q", lagerflags.DEBUG, lagerflags.INFO, lagerflags.ERROR, lagerflags.FATAL, c.LagerConfig.LogLevel)
+	}
+	if ttl := time.Duration(c.TCPRouteTTL); ttl > maxTCPRouteTTL {
+		add("tcp_route_ttl", "must be at most %s, got %s", maxTCPRouteTTL, ttl)
+	}
+
+	if (c.XDSServerCertFile == "") != (c.XDSServerKeyFile == "") {
+		add("xds_server_cert_file", "xds_server_cert_file and xds_server_key_file must be set together")
+	}
+	if c.XDSCACertFile != "" && c.XDSServerCertFile == "" {
+		add("xds_ca_cert_file", "requires xds_server_cert_file and xds_server_key_file")
+	}
+
+	if c.NATSTLSEnabled && (c.NATSCACertFile == "" || c.NATSClientCertFile == "" || c.NATSClientKeyFile == "") {
+		add("nats_tls_enabled", "requires nats_ca_cert_file, nats_client_cert_file and nats_client_key_file")
+	}
+	validateNATSCredentials(add, "nats_", c.NATSPassword, c.NATSPasswordFile, c.NATSNKeySeedFile, c.NATSCredsFile)
+
+	switch c.HTTPRouteSink {
+	case "", RouteSinkNATS, RouteSinkRoutingAPI, RouteSinkBoth:
+	default:
+		add("http_route_sink", "must be one of %q, %q or %q, got %q", RouteSinkNATS, RouteSinkRoutingAPI, RouteSinkBoth, c.HTTPRouteSink)
+	}
+
+	usesRoutingAPI := c.EnableTCPEmitter || c.HTTPRouteSink == RouteSinkRoutingAPI || c.HTTPRouteSink == RouteSinkBoth
+	if usesRoutingAPI {
+		if c.RoutingAPI.URL == "" || c.RoutingAPI.Port == 0 {
+			add("routing_api", "url and port must be set when the tcp emitter is enabled or http routes are emitted to the routing api")
+		}
+		if c.RoutingAPI.CACertFile == "" || c.RoutingAPI.ClientCertFile == "" || c.RoutingAPI.ClientKeyFile == "" {
+			add("routing_api", "ca_cert_file, client_cert_file and client_key_file must be set when the tcp emitter is enabled or http routes are emitted to the routing api")
+		}
+	}
+
+	if c.LocketEnabled && c.CellID == "" {
+		if c.UUID == "" {
+			add("uuid", "must be set when locket is enabled, it identifies the lock owner")
+		}
+		if c.LocketAddress == "" {
+			add("locket_address", "must be set when locket is enabled")
+		}
+	}
+
+	names := map[string]bool{}
+	for i, target := range c.NATSTargets {
+		field := fmt.Sprintf("nats_targets[%d]", i)
+		if target.Name == "" {
+			add(field+".name", "must be set")
+		} else if names[target.Name] {
+			add(field+".name", "%q is used by another target", target.Name)
+		}
+		names[target.Name] = true
+
+		if target.Addresses == "" {
+			add(field+".addresses", "must be set")
+		}
+		if target.TLSEnabled && (target.CACertFile == "" || target.ClientCertFile == "" || target.ClientKeyFile == "") {
+			add(field+".tls_enabled", "requires ca_cert_file, client_cert_file and client_key_file")
+		}
+		validateNATSCredentials(add, field+".", target.Password, target.PasswordFile, target.NKeySeedFile, target.CredsFile)
+
+		for _, routeType := range target.RouteTypes {
+			switch routeType {
+			case NATSTargetRouteTypeHTTP:
+			case NATSTargetRouteTypeInternal:
+				if !c.EnableInternalEmitter {
+					add(field+".route_types", "internal routes require enable_internal_emitter")
+				}
+			default:
+				add(field+".route_types", "must be %q or %q, got %q", NATSTargetRouteTypeHTTP, NATSTargetRouteTypeInternal, routeType)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateNATSCredentials(add func(field, format string, args ...interface{}), prefix, password, passwordFile, nkeySeedFile, credsFile string) {
+	files := 0
+	for _, file := range []string{passwordFile, nkeySeedFile, credsFile} {
+		if file != "" {
+			files++
+		}
+	}
+	if files > 1 {
+		add(prefix+"creds_file", "only one of %spassword_file, %snkey_seed_file and %screds_file can be set", prefix, prefix, prefix)
+	}
+	if password != "" && files > 0 {
+		add(prefix+"password", "cannot be combined with a credentials file")
+	}
+}
@@ -0,0 +1,141 @@
+package config_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/locket"
+	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	var cfg config.RouteEmitterConfig
+
+	fields := func(err error) []string {
+		var validationErr config.ValidationError
+		Expect(err).To(BeAssignableToTypeOf(validationErr))
+		fieldNames := []string{}
+		for _, fieldErr := range err.(config.ValidationError) {
+			fieldNames = append(fieldNames, fieldErr.Field)
+		}
+		return fieldNames
+	}
+
+	BeforeEach(func() {
+		cfg = config.RouteEmitterConfig{
+			BBSAddress:             "https://bbs.service.cf.internal:8889",
+			BBSCACertFile:          "/tmp/bbs_ca_cert",
+			BBSClientCertFile:      "/tmp/bbs_client_cert",
+			BBSClientKeyFile:       "/tmp/bbs_client_key",
+			NATSAddresses:          "127.0.0.1:4222",
+			SyncInterval:           durationjson.Duration(time.Minute),
+			UnregistrationInterval: durationjson.Duration(5 * time.Second),
+			RouteEmittingWorkers:   20,
+			TCPRouteTTL:            durationjson.Duration(2 * time.Minute),
+		}
+	})
+
+	It("accepts a valid config", func() {
+		Expect(cfg.Validate()).To(Succeed())
+	})
+
+	It("lists every problem", func() {
+		cfg.SyncInterval = 0
+		cfg.TCPRouteTTL = durationjson.Duration(24 * time.Hour)
+		cfg.HTTPRouteSink = "kafka"
+		cfg.LagerConfig.LogLevel = "verbose"
+
+		err := cfg.Validate()
+		Expect(fields(err)).To(Equal([]string{"sync_interval", "log_level", "tcp_route_ttl", "http_route_sink"}))
+		Expect(err.Error()).To(ContainSubstring(`http_route_sink: must be one of "nats", "routing_api" or "both", got "kafka"`))
+	})
+
+	It("rejects negative sizes and counts", func() {
+		cfg.EmitQueueSize = -1
+		cfg.NATSRetryMaxAttempts = -1
+		cfg.NATSRetryMaxPending = -1
+		cfg.NATSDeadLetterCapacity = -1
+		cfg.UnregistrationSendCount = -1
+		cfg.UnregistrationCacheMaxSize = -1
+
+		Expect(fields(cfg.Validate())).To(Equal([]string{
+			"emit_queue_size",
+			"nats_retry_max_attempts",
+			"nats_retry_max_pending",
+			"nats_dead_letter_capacity",
+			"unregistration_send_count",
+			"unregistration_cache_max_size",
+		}))
+	})
+
+	It("accepts zero sizes and counts", func() {
+		cfg.EmitQueueSize = 0
+		cfg.NATSRetryMaxAttempts = 0
+		cfg.NATSRetryMaxPending = 0
+		cfg.NATSDeadLetterCapacity = 0
+		cfg.UnregistrationSendCount = 0
+		cfg.UnregistrationCacheMaxSize = 0
+
+		Expect(cfg.Validate()).To(Succeed())
+	})
+
+	It("requires the xds server key pair to be complete", func() {
+		cfg.XDSServerCertFile = "/tmp/xds_server_cert"
+		cfg.XDSCACertFile = "/tmp/xds_ca_cert"
+		Expect(fields(cfg.Validate())).To(Equal([]string{"xds_server_cert_file"}))
+
+		cfg.XDSServerKeyFile = "/tmp/xds_server_key"
+		Expect(cfg.Validate()).To(Succeed())
+
+		cfg.XDSServerCertFile = ""
+		cfg.XDSServerKeyFile = ""
+		Expect(fields(cfg.Validate())).To(Equal([]string{"xds_ca_cert_file"}))
+	})
+
+	It("requires the routing api certificates when the tcp emitter is enabled", func() {
+		cfg.EnableTCPEmitter = true
+		cfg.RoutingAPI = config.RoutingAPIConfig{URL: "https://routing-api", Port: 3001}
+
+		Expect(fields(cfg.Validate())).To(Equal([]string{"routing_api"}))
+
+		cfg.RoutingAPI.CACertFile = "/tmp/routing_api_ca_cert"
+		cfg.RoutingAPI.ClientCertFile = "/tmp/routing_api_client_cert"
+		cfg.RoutingAPI.ClientKeyFile = "/tmp/routing_api_client_key"
+		Expect(cfg.Validate()).To(Succeed())
+	})
+
+	It("requires a uuid when locket is enabled", func() {
+		cfg.LocketEnabled = true
+		cfg.ClientLocketConfig = locket.ClientLocketConfig{LocketAddress: "127.0.0.1:8891"}
+
+		Expect(fields(cfg.Validate())).To(Equal([]string{"uuid"}))
+
+		cfg.CellID = "cell-1"
+		Expect(cfg.Validate()).To(Succeed())
+	})
+
+	It("rejects combined nats credentials", func() {
+		cfg.NATSPassword = "secret"
+		cfg.NATSNKeySeedFile = "/tmp/nats.nk"
+		cfg.NATSCredsFile = "/tmp/nats.creds"
+
+		Expect(fields(cfg.Validate())).To(Equal([]string{"nats_creds_file", "nats_password"}))
+	})
+
+	It("validates the nats targets", func() {
+		cfg.NATSTargets = []config.NATSTargetConfig{
+			{Name: "new-cluster", Addresses: "10.0.0.1:4222", RouteTypes: []string{"internal"}},
+			{Name: "new-cluster", RouteTypes: []string{"tcp"}},
+		}
+
+		Expect(fields(cfg.Validate())).To(Equal([]string{
+			"nats_targets[0].route_types",
+			"nats_targets[1].name",
+			"nats_targets[1].addresses",
+			"nats_targets[1].route_types",
+		}))
+	})
+})
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -22,23 +25,31 @@ import (
 	"code.cloudfoundry.org/locket/jointlock"
 	"code.cloudfoundry.org/locket/lock"
 	locketmodels "code.cloudfoundry.org/locket/models"
+	"code.cloudfoundry.org/route-emitter/adminserver"
 	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
 	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/dnsserver"
 	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/health"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/scheduler"
+	"code.cloudfoundry.org/route-emitter/snapshot"
 	"code.cloudfoundry.org/route-emitter/syncer"
+	"code.cloudfoundry.org/route-emitter/tlsreload"
 	"code.cloudfoundry.org/route-emitter/unregistration"
 	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/route-emitter/xds"
 	routing_api "code.cloudfoundry.org/routing-api"
 	"code.cloudfoundry.org/routing-api/uaaclient"
-	"code.cloudfoundry.org/tlsconfig"
-	"code.cloudfoundry.org/workpool"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/grouper"
 	"github.com/tedsuo/ifrit/http_server"
 	"github.com/tedsuo/ifrit/sigmon"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 )
 
 var configFilePath = flag.String(
@@ -47,34 +58,77 @@ var configFilePath = flag.String(
 	"Path to JSON configuration file",
 )
 
+var validateConfig = flag.Bool(
+	"validate-config",
+	false,
+	"Validate the configuration file, print all problems and exit",
+)
+
 const (
 	routeEmitterLockKey = "route_emitter"
+	defaultHTTPRouteTTL = 2 * time.Minute
+
+	defaultPublishRetryInitialBackoff = time.Second
+	defaultPublishRetryMaxBackoff     = 30 * time.Second
+	defaultPublishRetryMaxPending     = 10000
+	defaultDeadLetterCapacity         = 100
+
+	defaultUnregistrationCacheMaxSize = 10000
+	defaultUnregistrationCacheTTL     = 10 * time.Minute
+
+	defaultTLSReloadInterval = time.Minute
 )
 
 func main() {
 	flag.Parse()
 
 	cfg, err := config.NewRouteEmitterConfig(*configFilePath)
+	if *validateConfig {
+		os.Exit(printConfigProblems(os.Stderr, cfg, err))
+	}
 	if err != nil {
 		logger, _ := lagerflags.NewFromConfig("route-emitter", lagerflags.DefaultLagerConfig())
 		logger.Fatal("failed-to-parse-config", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		logger, _ := lagerflags.NewFromConfig("route-emitter", lagerflags.DefaultLagerConfig())
+		logger.Fatal("invalid-config", err)
+	}
 
 	logger, reconfigurableSink := lagerflags.NewFromConfig(cfg.LocketSessionName, cfg.LagerConfig)
 
-	natsClient, err := initializeNATSClient(logger, cfg.NATSTLSEnabled, cfg.NATSCACertFile, cfg.NATSClientCertFile, cfg.NATSClientKeyFile)
+	var natsTLSSource *tlsreload.Source
+	if cfg.NATSTLSEnabled {
+		natsTLSSource, err = tlsreload.NewSource("NATSCertificateExpiry", metrics.Labels{}, cfg.NATSClientCertFile, cfg.NATSClientKeyFile, cfg.NATSCACertFile)
+		if err != nil {
+			logger.Error("failed-to-initialize-nats-client", err)
+			os.Exit(1)
+		}
+	}
+	natsClient, err := initializeNATSClient(logger, natsTLSSource)
 	if err != nil {
 		logger.Error("failed-to-initialize-nats-client", err)
 		os.Exit(1)
 	}
+	natsCredentials, err := natsClientCredentials(cfg.NATSUsername, cfg.NATSPassword, cfg.NATSPasswordFile, cfg.NATSNKeySeedFile, cfg.NATSCredsFile)
+	if err != nil {
+		logger.Fatal("invalid-nats-credentials", err)
+	}
+	natsClient.SetCredentials(natsCredentials)
 
 	clock := clock.NewClock()
 
 	externalChan := make(chan struct{}, 1)
 	internalChan := make(chan struct{}, 1)
+	tcpChan := make(chan struct{}, 1)
+	httpChan := make(chan struct{}, 1)
 	syncer := syncer.NewSyncer(clock, time.Duration(cfg.SyncInterval), logger)
-	externalScheduler := scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, "router", externalChan)
-	internalScheduler := scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, "service-discovery", internalChan)
+
+	externalServices := []string{"router"}
+	if cfg.EnableInternalEmitter {
+		externalServices = append(externalServices, "service-discovery")
+	}
+	healthState := health.NewState(clock, natsClient, healthThresholds(cfg), externalServices...)
 
 	metronClient, err := initializeMetron(logger, cfg)
 	if err != nil {
@@ -82,49 +136,176 @@ func main() {
 		os.Exit(1)
 	}
 
-	natsClientRunner := diegonats.NewClientRunner(cfg.NATSAddresses, cfg.NATSUsername, cfg.NATSPassword, logger, natsClient)
+	var prometheusClient *metrics.PrometheusClient
+	if cfg.PrometheusAddress != "" {
+		prometheusClient = metrics.NewPrometheusClient(metronClient)
+		metronClient = prometheusClient
+	}
+
+	// new connections use rotated certificates without a restart, the
+	// watcher also reports when the certificates expire
+	tlsWatcher := tlsreload.NewWatcher(logger, clock, metronClient, tlsReloadInterval(cfg))
+	if natsTLSSource != nil {
+		tlsWatcher.Add(natsTLSSource)
+	}
+
+	natsUsername, natsPassword := cfg.NATSUsername, cfg.NATSPassword
+	if natsCredentials.FromFiles() {
+		// authenticate with the credentials files only
+		natsUsername, natsPassword = "", ""
+	}
+	natsClientRunner := diegonats.NewClientRunner(cfg.NATSAddresses, natsUsername, natsPassword, logger, natsClient)
 
-	bbsClient := initializeBBSClient(logger, cfg)
+	bbsClient := initializeBBSClient(logger, cfg, tlsWatcher)
 
 	localMode := cfg.CellID != ""
-	table := routingtable.NewRoutingTable(cfg.RegisterDirectInstanceRoutes, metronClient)
-	natsEmitter := initializeNatsEmitter(logger, natsClient, cfg.RouteEmittingWorkers, metronClient, cfg.EnableInternalEmitter)
+	table := routingtable.NewLiveRoutingTable(logger, cfg.RegisterDirectInstanceRoutes, metronClient)
+	if cfg.SnapshotFile != "" {
+		restoreSnapshot(logger, clock, cfg, table, externalChan, internalChan)
+	}
+	var publishRetrier *emitter.PublishRetrier
+	if cfg.NATSRetryMaxAttempts > 0 {
+		publishRetrier = emitter.NewPublishRetrier(logger, clock, natsClient, metronClient, publishRetryPolicy(cfg), deadLetterCapacity(cfg))
+	}
+	primaryNATSEmitter := initializeNatsEmitter(logger, natsClient, cfg.RouteEmittingWorkers, metronClient, cfg.EnableInternalEmitter, publishRetrier)
+	// unregistrations are buffered per nats connection, an outage of one
+	// does not hold them back from the other sinks
+	primaryBufferingEmitter := emitter.NewBufferingNATSEmitter(logger, primaryNATSEmitter)
+	natsClient.AddConnectionListener(primaryBufferingEmitter.ConnectionStateChanged)
+	var natsEmitter emitter.NATSEmitter = primaryBufferingEmitter
+
+	natsTargets := initializeNATSTargets(logger, cfg, healthState, metronClient, tlsWatcher)
+	if len(natsTargets.emitters) > 0 {
+		natsEmitter = emitter.NewFanOutNATSEmitter(append([]emitter.NATSEmitter{natsEmitter}, natsTargets.emitters...)...)
+	}
 
 	routeTTL := time.Duration(cfg.TCPRouteTTL)
-	if routeTTL.Seconds() > 65535 {
-		logger.Fatal("invalid-route-ttl", errors.New("route TTL value too large"), lager.Data{"ttl": routeTTL.Seconds()})
+	tcpRefreshScheduler := scheduler.NewTCPRefreshScheduler(clock, logger, routeTTL, tcpChan)
+
+	httpRouteSink := cfg.HTTPRouteSink
+	if httpRouteSink == "" {
+		httpRouteSink = config.RouteSinkNATS
+	}
+
+	var routingAPIClient routing_api.Client
+	var uaaTokenFetcher uaaclient.TokenFetcher
+	if cfg.EnableTCPEmitter || httpRouteSink != config.RouteSinkNATS {
+		uaaTokenFetcher = newUaaTokenFetcher(logger, &cfg, clock)
+		routingAPIClient = initializeRoutingAPIClient(logger, cfg, tlsWatcher)
+	}
+
+	// the http routes in the routing api are refreshed before their ttl
+	// expires, whether or not a router greets
+	var httpEmitter emitter.NATSEmitter
+	var httpRefreshScheduler *scheduler.RefreshScheduler
+	if httpRouteSink != config.RouteSinkNATS {
+		httpRouteTTL := time.Duration(cfg.HTTPRouteTTL)
+		if httpRouteTTL == 0 {
+			httpRouteTTL = defaultHTTPRouteTTL
+		}
+		httpEmitter = emitter.NewRoutingAPIHTTPEmitter(logger, routingAPIClient, uaaTokenFetcher, metronClient, int(httpRouteTTL.Seconds()))
+		httpRefreshScheduler = scheduler.NewHTTPRefreshScheduler(clock, logger, httpRouteTTL, httpChan)
+		if httpRouteSink == config.RouteSinkRoutingAPI {
+			natsEmitter = emitter.NewInternalOnlyNATSEmitter(natsEmitter)
+		}
+		natsEmitter = emitter.NewFanOutNATSEmitter(natsEmitter, httpEmitter)
 	}
 
 	var routingAPIEmitter emitter.RoutingAPIEmitter
+	var tcpReconciler emitter.TCPReconciler
 	if cfg.EnableTCPEmitter {
 		tcpLogger := logger.Session("tcp")
-		uaaTokenFetcher := newUaaTokenFetcher(tcpLogger, &cfg, clock)
-
-		routingAPIAddress := fmt.Sprintf("%s:%d", cfg.RoutingAPI.URL, cfg.RoutingAPI.Port)
-		logger.Debug("creating-routing-api-client", lager.Data{"api-location": routingAPIAddress})
-
-		var routingAPIClient routing_api.Client
-		if cfg.RoutingAPI.ClientCertFile != "" && cfg.RoutingAPI.ClientKeyFile != "" && cfg.RoutingAPI.CACertFile != "" {
-			tlsConfig, err := tlsconfig.Build(
-				tlsconfig.WithInternalServiceDefaults(),
-				tlsconfig.WithIdentityFromFile(cfg.RoutingAPI.ClientCertFile, cfg.RoutingAPI.ClientKeyFile),
-			).Client(
-				tlsconfig.WithAuthorityFromFile(cfg.RoutingAPI.CACertFile),
-			)
-			if err != nil {
-				logger.Fatal("failed-to-create-routing-api-tls-config", err)
-			}
-			routingAPIClient = routing_api.NewClientWithTLSConfig(routingAPIAddress, tlsConfig)
-		} else {
-			routingAPIClient = routing_api.NewClient(routingAPIAddress, false)
+		routingAPIEmitter = emitter.NewRoutingAPIEmitter(tcpLogger, routingAPIClient, uaaTokenFetcher, int(routeTTL.Seconds()))
+		if cfg.TCPReconcileEnabled {
+			tcpReconciler = emitter.NewTCPReconciler(tcpLogger, routingAPIClient, uaaTokenFetcher, metronClient, cfg.TCPReconcileRouterGroups, localMode)
 		}
+	}
 
-		routingAPIEmitter = emitter.NewRoutingAPIEmitter(tcpLogger, routingAPIClient, uaaTokenFetcher, int(routeTTL.Seconds()))
+	var xdsServer *xds.Server
+	if cfg.XDSAddress != "" {
+		xdsServer = xds.NewServer(logger, table, cfg.XDSAddress, cfg.RegisterDirectInstanceRoutes, xdsTLSConfig(logger, cfg, tlsWatcher))
+		natsEmitter = emitter.NewFanOutNATSEmitter(natsEmitter, xdsServer)
 	}
 
-	unregistrationCache := unregistration.NewCache(logger)
+	// in hot standby mode the watcher keeps the routing table up to date
+	// while waiting for the lock, but nothing is emitted until it is acquired
+	hotStandby := cfg.HotStandby && cfg.CellID == "" && cfg.LocketEnabled
+	standbyGate := emitter.NewStandbyGate()
+	if hotStandby {
+		natsEmitter = standbyGate.NATSEmitter(natsEmitter)
+		if httpEmitter != nil {
+			httpEmitter = standbyGate.NATSEmitter(httpEmitter)
+		}
+		routingAPIEmitter = standbyGate.RoutingAPIEmitter(routingAPIEmitter)
+		tcpReconciler = standbyGate.TCPReconciler(tcpReconciler)
+	}
 
-	handler := routehandlers.NewHandler(table, natsEmitter, routingAPIEmitter, localMode, metronClient, unregistrationCache)
+	// with an emit queue the handler only enqueues messages, they are emitted
+	// from separate runners so that a slow sink does not stall the watcher.
+	// The handler is created with the queues, an overflow of the nats queue
+	// asks it for a full emit once it exists.
+	var handler *routehandlers.Handler
+	asyncEmitters := grouper.Members{}
+	if cfg.EmitQueueSize > 0 {
+		natsEmitChans := []chan<- struct{}{externalChan}
+		if cfg.EnableInternalEmitter {
+			natsEmitChans = append(natsEmitChans, internalChan)
+		}
+		requestFullEmit := func() { handler.RequestFullEmit() }
+		asyncNATSEmitter := emitter.NewAsyncNATSEmitter(logger, natsEmitter, metronClient, cfg.EmitQueueSize, emitter.TriggerFullEmit(requestFullEmit, natsEmitChans...))
+		natsEmitter = asyncNATSEmitter
+		asyncEmitters = append(asyncEmitters, grouper.Member{Name: "nats-emit-queue", Runner: asyncNATSEmitter})
+
+		if routingAPIEmitter != nil {
+			asyncRoutingAPIEmitter := emitter.NewAsyncRoutingAPIEmitter(logger, routingAPIEmitter, metronClient, cfg.EmitQueueSize, emitter.TriggerEmit(tcpChan))
+			routingAPIEmitter = asyncRoutingAPIEmitter
+			asyncEmitters = append(asyncEmitters, grouper.Member{Name: "routing-api-emit-queue", Runner: asyncRoutingAPIEmitter})
+		}
+
+		if httpEmitter != nil {
+			asyncHTTPEmitter := emitter.NewAsyncRoutingAPIHTTPEmitter(logger, httpEmitter, metronClient, cfg.EmitQueueSize, emitter.TriggerEmit(httpChan))
+			httpEmitter = asyncHTTPEmitter
+			asyncEmitters = append(asyncEmitters, grouper.Member{Name: "routing-api-http-emit-queue", Runner: asyncHTTPEmitter})
+		}
+
+		if tcpReconciler != nil {
+			asyncTCPReconciler := emitter.NewAsyncTCPReconciler(logger, tcpReconciler)
+			tcpReconciler = asyncTCPReconciler
+			asyncEmitters = append(asyncEmitters, grouper.Member{Name: "tcp-reconcile-queue", Runner: asyncTCPReconciler})
+		}
+	}
+
+	unregistrationCache := unregistration.NewBoundedCache(
+		logger,
+		clock,
+		metronClient,
+		unregistrationCacheMaxSize(cfg),
+		unregistrationCacheTTL(cfg),
+	)
+	handler = routehandlers.NewHandler(table, natsEmitter, routingAPIEmitter, httpEmitter, localMode, metronClient, unregistrationCache, cfg.MassUnregistrationThreshold, cfg.EmitShards, tcpReconciler)
+
+	// emit everything as soon as nats or a nats target is back instead of
+	// waiting for the next tick, the buffered unregistrations are replayed
+	// with it
+	reconnectEmitChans := []chan<- struct{}{externalChan}
+	if cfg.EnableInternalEmitter {
+		reconnectEmitChans = append(reconnectEmitChans, internalChan)
+	}
+	emitAfterReconnect := emitter.TriggerEmit(reconnectEmitChans...)
+	natsClient.AddConnectionListener(diegonats.NewConnectionMetrics(logger, metronClient))
+	emitAllAfterReconnect := func(state diegonats.ConnectionState) {
+		if state == diegonats.Reconnected {
+			handler.RequestFullEmit()
+			emitAfterReconnect()
+		}
+	}
+	natsClient.AddConnectionListener(emitAllAfterReconnect)
+	natsTargets.addConnectionListener(emitAllAfterReconnect)
+
+	// a router that just started needs every route right away, the schedulers
+	// ask the handler for a full emit instead of the next shard
+	externalScheduler := scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, "router", externalChan, cfg.EmitShards, handler, healthState, metronClient)
+	internalScheduler := scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, "service-discovery", internalChan, 1, nil, healthState, metronClient)
 
 	watcher := watcher.NewWatcher(
 		cfg.CellID,
@@ -134,31 +315,84 @@ func main() {
 		syncer.SyncCh(),
 		externalScheduler.EmitCh(),
 		internalScheduler.EmitCh(),
+		tcpRefreshScheduler.EmitCh(),
+		httpChan,
 		logger,
 		metronClient,
+		healthState,
 	)
 
-	healthHandler := func(resp http.ResponseWriter, req *http.Request) {
-		resp.WriteHeader(http.StatusOK)
+	healthCheckServer := http_server.New(cfg.HealthCheckAddress, health.NewHandler(logger, healthState))
+	unregistrationSender := unregistration.NewSender(logger, clock, unregistrationCache, natsEmitter, routingAPIEmitter, time.Duration(cfg.UnregistrationInterval), cfg.UnregistrationSendCount)
+	reloader := &configReloader{
+		logger:            logger,
+		configPath:        *configFilePath,
+		cfg:               cfg,
+		sink:              reconfigurableSink,
+		syncer:            syncer,
+		sender:            unregistrationSender,
+		healthState:       healthState,
+		resizableEmitters: append([]emitter.ResizableNATSEmitter{primaryNATSEmitter}, natsTargets.resizableEmitters...),
 	}
-	healthCheckServer := http_server.New(cfg.HealthCheckAddress, http.HandlerFunc(healthHandler))
-	unregistrationSender := unregistration.NewSender(logger, clock, unregistrationCache, natsEmitter, time.Duration(cfg.UnregistrationInterval), cfg.UnregistrationSendCount)
 	members := grouper.Members{
+		{Name: "config-reloader", Runner: reloader},
 		{Name: "nats-client", Runner: natsClientRunner},
 		{Name: "healthcheck", Runner: healthCheckServer},
-		{Name: "unregistration", Runner: unregistrationSender},
+		{Name: "tls-watcher", Runner: tlsWatcher},
+	}
+	members = append(members, natsTargets.clients...)
+	members = append(members, asyncEmitters...)
+
+	if publishRetrier != nil {
+		members = append(members, grouper.Member{Name: "publish-retrier", Runner: publishRetrier})
+	}
+
+	if cfg.AdminAddress != "" {
+		var deadLetters adminserver.DeadLetterSource
+		if publishRetrier != nil {
+			deadLetters = publishRetrier
+		}
+		adminServer := http_server.New(cfg.AdminAddress, adminserver.NewHandler(logger, table, deadLetters, unregistrationCache))
+		members = append(members, grouper.Member{Name: "admin-server", Runner: adminServer})
+	}
+
+	if prometheusClient != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", prometheusClient.Handler())
+		prometheusServer := http_server.New(cfg.PrometheusAddress, mux)
+		members = append(members, grouper.Member{Name: "prometheus-server", Runner: prometheusServer})
+	}
+
+	watcherMembers := grouper.Members{
+		{Name: "watcher", Runner: watcher},
+		{Name: "external-scheduler", Runner: externalScheduler},
+		{Name: "syncer", Runner: syncer},
+	}
+
+	if cfg.EnableInternalEmitter {
+		watcherMembers = append(watcherMembers, grouper.Member{Name: "internal-scheduler", Runner: internalScheduler})
+	}
+
+	if cfg.EnableTCPEmitter {
+		watcherMembers = append(watcherMembers, grouper.Member{Name: "tcp-refresh-scheduler", Runner: tcpRefreshScheduler})
+	}
+
+	if httpRefreshScheduler != nil {
+		watcherMembers = append(watcherMembers, grouper.Member{Name: "http-refresh-scheduler", Runner: httpRefreshScheduler})
+	}
+
+	watcherMembers = append(watcherMembers, natsTargets.schedulers(clock, cfg, handler, externalChan, internalChan)...)
+
+	if hotStandby {
+		members = append(members, watcherMembers...)
 	}
 
 	if cfg.CellID == "" && cfg.LocketEnabled {
-		locketClient, err := locket.NewClient(logger, cfg.ClientLocketConfig)
+		locketClient, err := initializeLocketClient(logger, cfg, tlsWatcher)
 		if err != nil {
 			logger.Fatal("failed-to-create-locket-client", err)
 		}
 
-		if cfg.UUID == "" {
-			logger.Fatal("invalid-uuid", errors.New("invalid-uuid-from-config"))
-		}
-
 		lockIdentifier := &locketmodels.Resource{
 			Key:      routeEmitterLockKey,
 			Owner:    cfg.UUID,
@@ -181,14 +415,41 @@ func main() {
 		)
 	}
 
-	members = append(members,
-		grouper.Member{Name: "watcher", Runner: watcher},
-		grouper.Member{Name: "external-scheduler", Runner: externalScheduler},
-		grouper.Member{Name: "syncer", Runner: syncer},
-	)
+	// the unregistrations are restored, sent and saved only while holding the
+	// lock, an instance waiting for it must not unregister the routes of the
+	// one holding it
+	if cfg.UnregistrationCacheFile != "" {
+		unregistrationWriter := unregistration.NewWriter(logger, clock, unregistrationCache, cfg.UnregistrationCacheFile, time.Duration(cfg.UnregistrationInterval))
+		members = append(members, grouper.Member{Name: "unregistration-writer", Runner: unregistrationWriter})
+	}
+	members = append(members, grouper.Member{Name: "unregistration", Runner: unregistrationSender})
 
-	if cfg.EnableInternalEmitter {
-		members = append(members, grouper.Member{Name: "internal-scheduler", Runner: internalScheduler})
+	if hotStandby {
+		emitChans := []chan<- struct{}{externalChan}
+		if cfg.EnableInternalEmitter {
+			emitChans = append(emitChans, internalChan)
+		}
+		members = append(members, grouper.Member{Name: "standby-gate", Runner: standbyGate.Activator(logger, handler.RequestFullEmit, emitChans...)})
+	} else {
+		members = append(members, watcherMembers...)
+	}
+
+	// the xds server starts after the lock and, in hot standby, after the
+	// standby gate, so that envoy never pulls the routes of a standby whose
+	// table may be empty or stale
+	if xdsServer != nil {
+		members = append(members, grouper.Member{Name: "xds-server", Runner: xdsServer})
+	}
+
+	if cfg.DNSAddress != "" {
+		dnsServer := dnsserver.NewServer(logger, table, healthState, cfg.DNSAddress)
+		members = append(members, grouper.Member{Name: "dns-server", Runner: dnsServer})
+	}
+
+	if cfg.SnapshotFile != "" {
+		snapshotWriter := snapshot.NewWriter(logger, clock, table, healthState, cfg.SnapshotFile, snapshotInterval(cfg))
+		reloader.snapshotWriter = snapshotWriter
+		members = append(members, grouper.Member{Name: "snapshot-writer", Runner: snapshotWriter})
 	}
 
 	if cfg.DebugAddress != "" {
@@ -212,6 +473,86 @@ func main() {
 	logger.Info("exited")
 }
 
+// healthThresholds defaults the thresholds that are not configured to
+// multiples of the sync interval.
+func healthThresholds(cfg config.RouteEmitterConfig) health.Thresholds {
+	thresholds := health.Thresholds{
+		ReadinessSyncAge:   time.Duration(cfg.ReadinessSyncThreshold),
+		SyncAge:            time.Duration(cfg.HealthSyncThreshold),
+		SubscriptionOutage: time.Duration(cfg.HealthSubscriptionThreshold),
+	}
+
+	syncInterval := time.Duration(cfg.SyncInterval)
+	if thresholds.ReadinessSyncAge == 0 {
+		thresholds.ReadinessSyncAge = 2 * syncInterval
+	}
+	if thresholds.SyncAge == 0 {
+		thresholds.SyncAge = 5 * syncInterval
+	}
+	if thresholds.SubscriptionOutage == 0 {
+		thresholds.SubscriptionOutage = 5 * syncInterval
+	}
+	return thresholds
+}
+
+// snapshotInterval defaults the snapshot interval to the sync interval when
+// it is not configured.
+func snapshotInterval(cfg config.RouteEmitterConfig) time.Duration {
+	if cfg.SnapshotInterval == 0 {
+		return time.Duration(cfg.SyncInterval)
+	}
+	return time.Duration(cfg.SnapshotInterval)
+}
+
+// publishRetryPolicy defaults the backoff of failed publishes when it is not
+// configured.
+func publishRetryPolicy(cfg config.RouteEmitterConfig) emitter.RetryPolicy {
+	policy := emitter.RetryPolicy{
+		InitialBackoff: time.Duration(cfg.NATSRetryInitialBackoff),
+		MaxBackoff:     time.Duration(cfg.NATSRetryMaxBackoff),
+		MaxAttempts:    cfg.NATSRetryMaxAttempts,
+		MaxPending:     cfg.NATSRetryMaxPending,
+	}
+	if policy.InitialBackoff == 0 {
+		policy.InitialBackoff = defaultPublishRetryInitialBackoff
+	}
+	if policy.MaxBackoff == 0 {
+		policy.MaxBackoff = defaultPublishRetryMaxBackoff
+	}
+	if policy.MaxPending == 0 {
+		policy.MaxPending = defaultPublishRetryMaxPending
+	}
+	return policy
+}
+
+func deadLetterCapacity(cfg config.RouteEmitterConfig) int {
+	if cfg.NATSDeadLetterCapacity == 0 {
+		return defaultDeadLetterCapacity
+	}
+	return cfg.NATSDeadLetterCapacity
+}
+
+func unregistrationCacheMaxSize(cfg config.RouteEmitterConfig) int {
+	if cfg.UnregistrationCacheMaxSize == 0 {
+		return defaultUnregistrationCacheMaxSize
+	}
+	return cfg.UnregistrationCacheMaxSize
+}
+
+func tlsReloadInterval(cfg config.RouteEmitterConfig) time.Duration {
+	if cfg.TLSReloadInterval == 0 {
+		return defaultTLSReloadInterval
+	}
+	return time.Duration(cfg.TLSReloadInterval)
+}
+
+func unregistrationCacheTTL(cfg config.RouteEmitterConfig) time.Duration {
+	if cfg.UnregistrationCacheTTL == 0 {
+		return defaultUnregistrationCacheTTL
+	}
+	return time.Duration(cfg.UnregistrationCacheTTL)
+}
+
 func lockRunner(logger lager.Logger, clk clock.Clock, locks []grouper.Member) ifrit.Runner {
 	switch len(locks) {
 	case 0:
@@ -260,6 +601,31 @@ func newUaaTokenFetcher(logger lager.Logger, c *config.RouteEmitterConfig, klok
 	return uaaTokenFetcher
 }
 
+func restoreSnapshot(
+	logger lager.Logger,
+	clk clock.Clock,
+	cfg config.RouteEmitterConfig,
+	table routingtable.RoutingTable,
+	externalChan, internalChan chan struct{},
+) {
+	err := snapshot.Restore(logger, clk, cfg.SnapshotFile, time.Duration(cfg.SnapshotMaxAge), table)
+	if os.IsNotExist(err) {
+		logger.Info("no-routing-table-snapshot-found", lager.Data{"path": cfg.SnapshotFile})
+		return
+	}
+	if err != nil {
+		logger.Error("failed-to-restore-routing-table-snapshot", err, lager.Data{"path": cfg.SnapshotFile})
+		return
+	}
+
+	// emit the restored routes as soon as the watcher starts instead of
+	// waiting for the first sync
+	externalChan <- struct{}{}
+	if cfg.EnableInternalEmitter {
+		internalChan <- struct{}{}
+	}
+}
+
 func initializeMetron(logger lager.Logger, locketConfig config.RouteEmitterConfig) (loggingclient.IngressClient, error) {
 	client, err := loggingclient.NewIngressClient(locketConfig.LoggregatorConfig)
 	if err != nil {
@@ -280,28 +646,288 @@ func initializeNatsEmitter(
 	routeEmittingWorkers int,
 	metronClient loggingclient.IngressClient,
 	emitInternalRoutes bool,
-) emitter.NATSEmitter {
-	workPool, err := workpool.NewWorkPool(routeEmittingWorkers)
+	retrier *emitter.PublishRetrier,
+) emitter.ResizableNATSEmitter {
+	lanes, err := emitter.NewEmitLanes(routeEmittingWorkers)
 	if err != nil {
 		logger.Fatal("failed-to-construct-nats-emitter-workpool", err, lager.Data{"num-workers": routeEmittingWorkers}) // should never happen
 	}
 
-	return emitter.NewNATSEmitter(natsClient, workPool, logger, metronClient, emitInternalRoutes)
+	return emitter.NewNATSEmitter(natsClient, lanes, logger, metronClient, emitInternalRoutes, retrier)
+}
+
+// natsTargets is what initializeNATSTargets set up for the additional NATS
+// targets, the emitters wrapped in their filters and the ones whose lanes
+// are resized on a reload.
+type natsTargets struct {
+	emitters          []emitter.NATSEmitter
+	resizableEmitters []emitter.ResizableNATSEmitter
+	clients           grouper.Members
+	greeters          []natsTargetGreeter
+}
+
+// natsTargetGreeter is what the route broadcast schedulers of a target need.
+// The schedulers are only created with the handler, which is created with
+// the emitters of the targets.
+type natsTargetGreeter struct {
+	name             string
+	logger           lager.Logger
+	natsClient       diegonats.NATSClient
+	filter           emitter.NATSTargetFilter
+	greetingReporter scheduler.GreetingReporter
+	metronClient     loggingclient.IngressClient
+}
+
+// schedulers returns the route broadcast schedulers of the targets, they
+// share the emit channels of the primary NATS cluster.
+func (targets natsTargets) schedulers(
+	clk clock.Clock,
+	cfg config.RouteEmitterConfig,
+	fullEmitRequester scheduler.FullEmitRequester,
+	externalChan, internalChan chan struct{},
+) grouper.Members {
+	members := grouper.Members{}
+	for _, greeter := range targets.greeters {
+		if greeter.filter.HTTP {
+			members = append(members, grouper.Member{
+				Name:   "nats-target-external-scheduler-" + greeter.name,
+				Runner: scheduler.NewRouteBroadcastScheduler(clk, greeter.natsClient, greeter.logger, "router", externalChan, cfg.EmitShards, fullEmitRequester, greeter.greetingReporter, greeter.metronClient),
+			})
+		}
+		if greeter.filter.Internal {
+			members = append(members, grouper.Member{
+				Name:   "nats-target-internal-scheduler-" + greeter.name,
+				Runner: scheduler.NewRouteBroadcastScheduler(clk, greeter.natsClient, greeter.logger, "service-discovery", internalChan, 1, nil, greeter.greetingReporter, greeter.metronClient),
+			})
+		}
+	}
+	return members
+}
+
+// addConnectionListener adds the listener to the client of every target.
+func (targets natsTargets) addConnectionListener(listener diegonats.ConnectionListener) {
+	for _, greeter := range targets.greeters {
+		greeter.natsClient.AddConnectionListener(listener)
+	}
+}
+
+// initializeNATSTargets sets up the additional NATS targets. Every target
+// has its own client, emitter and route broadcast schedulers (see
+// natsTargets.schedulers), so that an unavailable target does not hold up
+// the others. Its client keeps
+// connecting in the background instead of failing the emitter.
+func initializeNATSTargets(
+	logger lager.Logger,
+	cfg config.RouteEmitterConfig,
+	healthState *health.State,
+	metronClient loggingclient.IngressClient,
+	tlsWatcher *tlsreload.Watcher,
+) natsTargets {
+	targets := natsTargets{}
+
+	for _, targetCfg := range cfg.NATSTargets {
+		targetLogger := logger.Session("nats-target", lager.Data{"target": targetCfg.Name})
+		filter := natsTargetFilter(cfg, targetCfg)
+		// the emitter and schedulers of a target report the same metrics as
+		// the ones of the primary cluster, labeled with the target instead of
+		// counted into the metrics of the primary cluster
+		targetMetronClient := metrics.NewTargetClient(metronClient, targetCfg.Name)
+
+		var tlsSource *tlsreload.Source
+		var err error
+		if targetCfg.TLSEnabled {
+			tlsSource, err = tlsreload.NewSource("NATSCertificateExpiry", metrics.Labels{Target: targetCfg.Name}, targetCfg.ClientCertFile, targetCfg.ClientKeyFile, targetCfg.CACertFile)
+			if err != nil {
+				targetLogger.Fatal("failed-to-initialize-nats-client", err)
+			}
+			tlsWatcher.Add(tlsSource)
+		}
+		natsClient, err := initializeNATSClient(targetLogger, tlsSource)
+		if err != nil {
+			targetLogger.Fatal("failed-to-initialize-nats-client", err)
+		}
+		credentials, err := natsClientCredentials(targetCfg.Username, targetCfg.Password, targetCfg.PasswordFile, targetCfg.NKeySeedFile, targetCfg.CredsFile)
+		if err != nil {
+			targetLogger.Fatal("invalid-nats-credentials", err)
+		}
+		natsClient.SetCredentials(credentials)
+		natsClient.SetRetryOnFailedConnect(true)
+		natsClient.AddConnectionListener(diegonats.NewTargetConnectionMetrics(logger, metronClient, targetCfg.Name))
+
+		username, password := targetCfg.Username, targetCfg.Password
+		if credentials.FromFiles() {
+			username, password = "", ""
+		}
+		targets.clients = append(targets.clients, grouper.Member{
+			Name:   "nats-target-client-" + targetCfg.Name,
+			Runner: diegonats.NewClientRunner(targetCfg.Addresses, username, password, targetLogger, natsClient),
+		})
+
+		externalServices := []string{}
+		if filter.HTTP {
+			externalServices = append(externalServices, "router")
+		}
+		if filter.Internal {
+			externalServices = append(externalServices, "service-discovery")
+		}
+		targets.greeters = append(targets.greeters, natsTargetGreeter{
+			name:             targetCfg.Name,
+			logger:           targetLogger,
+			natsClient:       natsClient,
+			filter:           filter,
+			greetingReporter: healthState.AddNATSTarget(targetCfg.Name, natsClient, externalServices...),
+			metronClient:     targetMetronClient,
+		})
+
+		targetEmitter := initializeNatsEmitter(targetLogger, natsClient, cfg.RouteEmittingWorkers, targetMetronClient, filter.Internal, nil)
+		targets.resizableEmitters = append(targets.resizableEmitters, targetEmitter)
+		bufferingEmitter := emitter.NewBufferingNATSEmitter(targetLogger, targetEmitter)
+		natsClient.AddConnectionListener(bufferingEmitter.ConnectionStateChanged)
+		targets.emitters = append(targets.emitters, emitter.NewNATSTargetEmitter(logger, targetCfg.Name, bufferingEmitter, metronClient, filter))
+	}
+
+	return targets
+}
+
+// natsTargetFilter returns the routes emitted to a target, by default the
+// same routes as to the primary nats cluster. The route types were checked
+// by Validate.
+func natsTargetFilter(cfg config.RouteEmitterConfig, targetCfg config.NATSTargetConfig) emitter.NATSTargetFilter {
+	filter := emitter.NATSTargetFilter{Domains: targetCfg.Domains}
+	if len(targetCfg.RouteTypes) == 0 {
+		filter.HTTP = true
+		filter.Internal = cfg.EnableInternalEmitter
+		return filter
+	}
+
+	for _, routeType := range targetCfg.RouteTypes {
+		switch routeType {
+		case config.NATSTargetRouteTypeHTTP:
+			filter.HTTP = true
+		case config.NATSTargetRouteTypeInternal:
+			filter.Internal = true
+		}
+	}
+	return filter
+}
+
+// printConfigProblems reports the result of -validate-config and returns the
+// exit code.
+func printConfigProblems(w io.Writer, cfg config.RouteEmitterConfig, parseErr error) int {
+	if parseErr != nil {
+		fmt.Fprintf(w, "%s: %s\n", *configFilePath, parseErr)
+		return 1
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		fmt.Fprintf(w, "%s: config is valid\n", *configFilePath)
+		return 0
+	}
+
+	if problems, ok := err.(config.ValidationError); ok {
+		fmt.Fprintf(w, "%s: %d problem(s) found\n", *configFilePath, len(problems))
+		for _, problem := range problems {
+			fmt.Fprintf(w, "  %s\n", problem)
+		}
+	} else {
+		fmt.Fprintf(w, "%s: %s\n", *configFilePath, err)
+	}
+	return 1
+}
+
+func initializeRoutingAPIClient(logger lager.Logger, cfg config.RouteEmitterConfig, tlsWatcher *tlsreload.Watcher) routing_api.Client {
+	routingAPIAddress := fmt.Sprintf("%s:%d", cfg.RoutingAPI.URL, cfg.RoutingAPI.Port)
+	logger.Debug("creating-routing-api-client", lager.Data{"api-location": routingAPIAddress})
+
+	if cfg.RoutingAPI.ClientCertFile != "" && cfg.RoutingAPI.ClientKeyFile != "" && cfg.RoutingAPI.CACertFile != "" {
+		tlsSource, err := tlsreload.NewSource("RoutingAPIClientCertificateExpiry", metrics.Labels{}, cfg.RoutingAPI.ClientCertFile, cfg.RoutingAPI.ClientKeyFile, cfg.RoutingAPI.CACertFile)
+		if err != nil {
+			logger.Fatal("failed-to-create-routing-api-tls-config", err)
+		}
+		tlsWatcher.Add(tlsSource)
+		tlsConfig, err := tlsSource.ClientTLSConfig()
+		if err != nil {
+			logger.Fatal("failed-to-create-routing-api-tls-config", err)
+		}
+		return routing_api.NewClientWithTLSConfig(routingAPIAddress, tlsConfig)
+	}
+	return routing_api.NewClient(routingAPIAddress, false)
+}
+
+// xdsTLSConfig returns the TLS config of the xds server, nil when no server
+// certificate is configured. Envoy has to present a client certificate when a
+// CA is configured.
+func xdsTLSConfig(logger lager.Logger, cfg config.RouteEmitterConfig, tlsWatcher *tlsreload.Watcher) *tls.Config {
+	if cfg.XDSServerCertFile == "" {
+		return nil
+	}
+
+	tlsSource, err := tlsreload.NewSource("XDSServerCertificateExpiry", metrics.Labels{}, cfg.XDSServerCertFile, cfg.XDSServerKeyFile, cfg.XDSCACertFile)
+	if err != nil {
+		logger.Fatal("failed-to-create-xds-tls-config", err)
+	}
+	tlsWatcher.Add(tlsSource)
+	tlsConfig, err := tlsSource.ServerTLSConfig()
+	if err != nil {
+		logger.Fatal("failed-to-create-xds-tls-config", err)
+	}
+	return tlsConfig
+}
+
+// initializeLocketClient connects to locket like locket.NewClient, with the
+// client certificates read from a reloading source so that rotated
+// certificates are used without a restart.
+func initializeLocketClient(logger lager.Logger, cfg config.RouteEmitterConfig, tlsWatcher *tlsreload.Watcher) (locketmodels.LocketClient, error) {
+	tlsSource, err := tlsreload.NewSource("LocketClientCertificateExpiry", metrics.Labels{}, cfg.LocketClientCertFile, cfg.LocketClientKeyFile, cfg.LocketCACertFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsWatcher.Add(tlsSource)
+	tlsConfig, err := tlsSource.ClientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("creating-locket-client", lager.Data{"address": cfg.LocketAddress})
+	conn, err := grpc.Dial(
+		cfg.LocketAddress,
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: 10 * time.Second}).DialContext(ctx, "tcp", address)
+		}),
+		grpc.WithBlock(),
+		grpc.WithTimeout(10*time.Second),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: 10 * time.Second}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return locketmodels.NewLocketClient(conn), nil
 }
 
 func initializeBBSClient(
 	logger lager.Logger,
 	cfg config.RouteEmitterConfig,
-) bbs.Client {
-	bbsClient, err := bbs.NewClientWithConfig(bbs.ClientConfig{
-		URL:                    cfg.BBSAddress,
-		IsTLS:                  true,
-		CAFile:                 cfg.BBSCACertFile,
-		CertFile:               cfg.BBSClientCertFile,
-		KeyFile:                cfg.BBSClientKeyFile,
-		ClientSessionCacheSize: cfg.BBSClientSessionCacheSize,
-		MaxIdleConnsPerHost:    cfg.BBSMaxIdleConnsPerHost,
-		RequestTimeout:         time.Duration(cfg.CommunicationTimeout),
+	tlsWatcher *tlsreload.Watcher,
+) watcher.BBSClient {
+	tlsSource, err := tlsreload.NewSource("BBSClientCertificateExpiry", metrics.Labels{}, cfg.BBSClientCertFile, cfg.BBSClientKeyFile, cfg.BBSCACertFile)
+	if err != nil {
+		logger.Fatal("Failed to configure secure BBS client", err)
+	}
+	tlsWatcher.Add(tlsSource)
+
+	bbsClient, err := tlsreload.NewBBSClient(logger, tlsSource, func() (bbs.Client, error) {
+		return bbs.NewClientWithConfig(bbs.ClientConfig{
+			URL:                    cfg.BBSAddress,
+			IsTLS:                  true,
+			CAFile:                 cfg.BBSCACertFile,
+			CertFile:               cfg.BBSClientCertFile,
+			KeyFile:                cfg.BBSClientKeyFile,
+			ClientSessionCacheSize: cfg.BBSClientSessionCacheSize,
+			MaxIdleConnsPerHost:    cfg.BBSMaxIdleConnsPerHost,
+			RequestTimeout:         time.Duration(cfg.CommunicationTimeout),
+		})
 	})
 	if err != nil {
 		logger.Fatal("Failed to configure secure BBS client", err)
@@ -309,15 +935,30 @@ func initializeBBSClient(
 	return bbsClient
 }
 
-func initializeNATSClient(logger lager.Logger, tlsEnabled bool, caFile, certFile, keyFile string) (diegonats.NATSClient, error) {
+// natsClientCredentials returns the file based credentials of a nats
+// client, they cannot be combined with an inline password.
+func natsClientCredentials(username, password, passwordFile, nkeySeedFile, credsFile string) (diegonats.Credentials, error) {
+	credentials := diegonats.Credentials{
+		Username:     username,
+		PasswordFile: passwordFile,
+		NKeySeedFile: nkeySeedFile,
+		CredsFile:    credsFile,
+	}
+	err := credentials.Validate()
+	if err != nil {
+		return diegonats.Credentials{}, err
+	}
+	if password != "" && credentials.FromFiles() {
+		return diegonats.Credentials{}, errors.New("nats password cannot be combined with nats credentials files")
+	}
+	return credentials, nil
+}
+
+// initializeNATSClient returns a client without TLS when tlsSource is nil.
+func initializeNATSClient(logger lager.Logger, tlsSource *tlsreload.Source) (diegonats.NATSClient, error) {
 	var natsClient diegonats.NATSClient
-	if tlsEnabled {
-		tlsConfig, err := tlsconfig.Build(
-			tlsconfig.WithInternalServiceDefaults(),
-			tlsconfig.WithIdentityFromFile(certFile, keyFile),
-		).Client(
-			tlsconfig.WithAuthorityFromFile(caFile),
-		)
+	if tlsSource != nil {
+		tlsConfig, err := tlsSource.ClientTLSConfig()
 		if err != nil {
 			return nil, err
 		}
@@ -4,6 +4,7 @@ import (
 	"math"
 	"path"
 	"path/filepath"
+	"syscall"
 
 	"code.cloudfoundry.org/bbs"
 	"code.cloudfoundry.org/clock"
@@ -271,9 +272,10 @@ var _ = Describe("Route Emitter", func() {
 		natsClient.Subscribe("router.greet", func(msg *nats.Msg) {
 			defer GinkgoRecover()
 
+			registerInterval := int(atomic.LoadUint64(&emitInterval)) / int(time.Second)
 			greeting := routingtable.ExternalServiceGreetingMessage{
-				MinimumRegisterInterval: int(atomic.LoadUint64(&emitInterval)) / int(time.Second),
-				PruneThresholdInSeconds: 6,
+				MinimumRegisterInterval: registerInterval,
+				PruneThresholdInSeconds: 6 * registerInterval,
 			}
 
 			response, err := json.Marshal(greeting)
@@ -286,9 +288,10 @@ var _ = Describe("Route Emitter", func() {
 		natsClient.Subscribe("service-discovery.greet", func(msg *nats.Msg) {
 			defer GinkgoRecover()
 
+			registerInterval := int(atomic.LoadUint64(&emitInterval)) / int(time.Second)
 			greeting := routingtable.ExternalServiceGreetingMessage{
-				MinimumRegisterInterval: int(atomic.LoadUint64(&emitInterval)) / int(time.Second),
-				PruneThresholdInSeconds: 6,
+				MinimumRegisterInterval: registerInterval,
+				PruneThresholdInSeconds: 6 * registerInterval,
 			}
 
 			response, err := json.Marshal(greeting)
@@ -457,7 +460,7 @@ var _ = Describe("Route Emitter", func() {
 				var err error
 				Eventually(emitter.Wait()).Should(Receive(&err))
 				Expect(err).To(HaveOccurred())
-				Expect(runner.Buffer()).To(gbytes.Say("invalid-route-ttl"))
+				Expect(runner.Buffer()).To(gbytes.Say("invalid-config"))
 			})
 		})
 	})
@@ -1145,6 +1148,38 @@ var _ = Describe("Route Emitter", func() {
 					Eventually(runner.Buffer).Should(gbytes.Say("emitter1.started"))
 				})
 			})
+
+			Context("and hot standby is enabled", func() {
+				BeforeEach(func() {
+					cfgs = append(cfgs, func(cfg *config.RouteEmitterConfig) {
+						cfg.HotStandby = true
+					})
+
+					Expect(bbsClient.DesireLRP(logger, "", desiredLRP)).To(Succeed())
+					Expect(bbsClient.StartActualLRP(logger, "", &lrpKey, &instanceKey, &netInfo, []*models.ActualLRPInternalRoute{}, map[string]string{})).To(Succeed())
+				})
+
+				It("syncs the routing table but does not emit routes", func() {
+					Eventually(runner.Buffer).Should(gbytes.Say("emitter1.watcher.sync.complete"))
+					Consistently(registeredRoutes).ShouldNot(Receive())
+				})
+
+				Context("and the lock becomes available", func() {
+					JustBeforeEach(func() {
+						Eventually(runner.Buffer).Should(gbytes.Say("emitter1.watcher.sync.complete"))
+						ginkgomon.Interrupt(competingProcess)
+					})
+
+					It("emits the routes as soon as it acquires the lock", func() {
+						Eventually(runner.Buffer).Should(gbytes.Say("emitter1.standby-gate.activated"))
+						var msg routingtable.RegistryMessage
+						Eventually(registeredRoutes).Should(Receive(&msg))
+						Expect(msg.Host).To(Equal(netInfo.Address))
+						Expect(msg.Port).To(Equal(netInfo.Ports[0].HostPort))
+						Expect(msg.App).To(Equal(desiredLRP.LogGuid))
+					})
+				})
+			})
 		})
 
 		Context("and the UUID is not present", func() {
@@ -1192,6 +1227,16 @@ var _ = Describe("Route Emitter", func() {
 			ginkgomon.Kill(emitter, emitterInterruptTimeout)
 		})
 
+		Context("when it receives a SIGHUP", func() {
+			It("reloads the config file and keeps running", func() {
+				emitter.Signal(syscall.SIGHUP)
+
+				Eventually(runner).Should(gbytes.Say("emitter1.config-reloader.reload.finished"))
+				Expect(runner).NotTo(gbytes.Say("rejected-changes"))
+				Consistently(emitter.Wait()).ShouldNot(Receive())
+			})
+		})
+
 		Context("when configured to communicate with nats over TLS", func() {
 			var certDepot string
 
@@ -1323,6 +1368,22 @@ var _ = Describe("Route Emitter", func() {
 			}, 6*time.Second).ShouldNot(HaveOccurred(), "healthcheck server didn't start")
 		})
 
+		It("reports ready and healthy once synced and greeted by the routers", func() {
+			client := http.Client{
+				Timeout: time.Second,
+			}
+			for _, path := range []string{"/ready", "/health"} {
+				Eventually(func() (int, error) {
+					resp, err := client.Get("http://" + healthCheckAddress + path)
+					if err != nil {
+						return 0, err
+					}
+					resp.Body.Close()
+					return resp.StatusCode, nil
+				}, 10*time.Second).Should(Equal(http.StatusOK), path)
+			}
+		})
+
 		Context("and an lrp with routes is desired", func() {
 			BeforeEach(func() {
 				err := bbsClient.DesireLRP(logger, "", desiredLRP)
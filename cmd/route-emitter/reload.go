@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/lager/v3/lagerflags"
+	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/health"
+	"code.cloudfoundry.org/route-emitter/snapshot"
+	"code.cloudfoundry.org/route-emitter/syncer"
+	"code.cloudfoundry.org/route-emitter/unregistration"
+)
+
+// configReloader reads the config file again on SIGHUP and applies the
+// settings that can be changed without losing the lock, see
+// config.RouteEmitterConfig.Reload. Other changes are logged and ignored
+// until the next restart.
+type configReloader struct {
+	logger            lager.Logger
+	configPath        string
+	cfg               config.RouteEmitterConfig
+	sink              *lager.ReconfigurableSink
+	syncer            *syncer.NatsSyncer
+	sender            *unregistration.Sender
+	healthState       *health.State
+	snapshotWriter    *snapshot.Writer
+	resizableEmitters []emitter.ResizableNATSEmitter
+}
+
+func (r *configReloader) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := r.logger.Session("config-reloader")
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	logger.Info("started")
+	close(ready)
+	defer logger.Info("exiting")
+
+	for {
+		select {
+		case <-hangups:
+			r.reload(logger.Session("reload", lager.Data{"config": r.configPath}))
+		case <-signals:
+			logger.Info("stopping")
+			return nil
+		}
+	}
+}
+
+func (r *configReloader) reload(logger lager.Logger) {
+	logger.Info("starting")
+	defer logger.Info("finished")
+
+	next, err := config.NewRouteEmitterConfig(r.configPath)
+	if err != nil {
+		logger.Error("failed-to-parse-config", err)
+		return
+	}
+	err = next.Validate()
+	if err != nil {
+		logger.Error("invalid-config", err)
+		return
+	}
+
+	reloaded, rejected := r.cfg.Reload(next)
+	if len(rejected) > 0 {
+		logger.Info("rejected-changes", lager.Data{
+			"fields": rejected,
+			"reason": "these settings are only read on startup, restart the route-emitter to apply them",
+		})
+	}
+
+	if reloaded.LagerConfig.LogLevel != r.cfg.LagerConfig.LogLevel {
+		level, err := logLevel(reloaded.LagerConfig.LogLevel)
+		if err != nil {
+			logger.Error("failed-to-change-log-level", err)
+			reloaded.LagerConfig.LogLevel = r.cfg.LagerConfig.LogLevel
+		} else {
+			r.sink.SetMinLevel(level)
+			logger.Info("changed-log-level", lager.Data{"log-level": reloaded.LagerConfig.LogLevel})
+		}
+	}
+
+	if reloaded.SyncInterval != r.cfg.SyncInterval {
+		r.syncer.SetSyncInterval(time.Duration(reloaded.SyncInterval))
+
+		// the thresholds and the snapshot interval default to multiples of the
+		// sync interval
+		thresholds := healthThresholds(reloaded)
+		r.healthState.SetThresholds(thresholds)
+		data := lager.Data{
+			"readiness-sync-age":  thresholds.ReadinessSyncAge.String(),
+			"sync-age":            thresholds.SyncAge.String(),
+			"subscription-outage": thresholds.SubscriptionOutage.String(),
+		}
+		if r.snapshotWriter != nil {
+			interval := snapshotInterval(reloaded)
+			r.snapshotWriter.SetInterval(interval)
+			data["snapshot-interval"] = interval.String()
+		}
+		logger.Info("changed-sync-interval-settings", data)
+	}
+
+	if reloaded.UnregistrationInterval != r.cfg.UnregistrationInterval || reloaded.UnregistrationSendCount != r.cfg.UnregistrationSendCount {
+		r.sender.Reconfigure(time.Duration(reloaded.UnregistrationInterval), reloaded.UnregistrationSendCount)
+		logger.Info("changed-unregistration-settings", lager.Data{
+			"interval":   time.Duration(reloaded.UnregistrationInterval).String(),
+			"send-count": reloaded.UnregistrationSendCount,
+		})
+	}
+
+	if reloaded.RouteEmittingWorkers != r.cfg.RouteEmittingWorkers {
+		for _, resizableEmitter := range r.resizableEmitters {
+			err := resizableEmitter.ResizeLanes(reloaded.RouteEmittingWorkers)
+			if err != nil {
+				// should never happen, the config was validated
+				logger.Error("failed-to-resize-emitter-workpool", err)
+				reloaded.RouteEmittingWorkers = r.cfg.RouteEmittingWorkers
+				break
+			}
+		}
+	}
+
+	r.cfg = reloaded
+}
+
+// logLevel parses the log level the way lagerflags does, an empty level is
+// info.
+func logLevel(level string) (lager.LogLevel, error) {
+	if level == "" {
+		level = lagerflags.INFO
+	}
+	return lager.LogLevelFromString(level)
+}
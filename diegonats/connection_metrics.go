@@ -0,0 +1,75 @@
+package diegonats
+
+import (
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/metrics"
+)
+
+const (
+	natsDisconnectsCounter       = "NATSDisconnects"
+	natsReconnectsCounter        = "NATSReconnects"
+	natsTargetDisconnectsCounter = "NATSTargetDisconnects"
+	natsTargetReconnectsCounter  = "NATSTargetReconnects"
+	natsConnectedMetric          = "NATSConnected"
+)
+
+// NewConnectionMetrics returns a listener that logs connection changes,
+// counts disconnects and reconnects and reports whether the client is
+// currently connected.
+func NewConnectionMetrics(logger lager.Logger, metronClient loggingclient.IngressClient) ConnectionListener {
+	return newConnectionMetrics(logger.Session("nats-connection"), metronClient, "")
+}
+
+// NewTargetConnectionMetrics returns a listener like NewConnectionMetrics
+// for the client of an additional NATS target. Its metrics are labeled with
+// the target name and it counts NATSTargetDisconnects and
+// NATSTargetReconnects, so that the counters of the primary client keep
+// describing it.
+func NewTargetConnectionMetrics(logger lager.Logger, metronClient loggingclient.IngressClient, target string) ConnectionListener {
+	return newConnectionMetrics(logger.Session("nats-connection", lager.Data{"target": target}), metronClient, target)
+}
+
+func newConnectionMetrics(logger lager.Logger, metronClient loggingclient.IngressClient, target string) ConnectionListener {
+	labels := metrics.Labels{Target: target}
+	disconnects, reconnects := natsDisconnectsCounter, natsReconnectsCounter
+	increment := func(name string) {
+		incrementCounter(logger, metronClient, name)
+	}
+	if target != "" {
+		disconnects, reconnects = natsTargetDisconnectsCounter, natsTargetReconnectsCounter
+		increment = func(name string) {
+			err := metrics.IncrementCounterWithLabels(metronClient, name, 1, labels)
+			if err != nil {
+				logger.Error("failed-to-increment-counter", err, lager.Data{"counter": name})
+			}
+		}
+	}
+
+	return func(state ConnectionState) {
+		connected := 0
+		switch state {
+		case Disconnected:
+			logger.Info("disconnected")
+			increment(disconnects)
+		case Reconnected:
+			logger.Info("reconnected")
+			increment(reconnects)
+			connected = 1
+		case Closed:
+			logger.Info("closed")
+		}
+
+		err := metronClient.SendMetric(natsConnectedMetric, connected, metrics.WithLabels(labels)...)
+		if err != nil {
+			logger.Error("failed-to-send-connected-metric", err)
+		}
+	}
+}
+
+func incrementCounter(logger lager.Logger, metronClient loggingclient.IngressClient, name string) {
+	err := metronClient.IncrementCounter(name)
+	if err != nil {
+		logger.Error("failed-to-increment-counter", err, lager.Data{"counter": name})
+	}
+}
@@ -0,0 +1,98 @@
+package diegonats_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConnectionMetrics", func() {
+	var (
+		fakeMetronClient *mfakes.FakeIngressClient
+		listener         ConnectionListener
+	)
+
+	BeforeEach(func() {
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		listener = NewConnectionMetrics(lagertest.NewTestLogger("test"), fakeMetronClient)
+	})
+
+	It("counts disconnects and reports the client as disconnected", func() {
+		listener(Disconnected)
+
+		Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+		Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("NATSDisconnects"))
+		name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(name).To(Equal("NATSConnected"))
+		Expect(value).To(Equal(0))
+	})
+
+	It("counts reconnects and reports the client as connected", func() {
+		listener(Reconnected)
+
+		Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+		Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("NATSReconnects"))
+		name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(name).To(Equal("NATSConnected"))
+		Expect(value).To(Equal(1))
+	})
+
+	It("reports a closed client as disconnected", func() {
+		listener(Closed)
+
+		Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+		_, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(value).To(Equal(0))
+	})
+
+	Describe("an additional target", func() {
+		var prometheusClient *metrics.PrometheusClient
+
+		scrape := func() string {
+			recorder := httptest.NewRecorder()
+			prometheusClient.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			body, err := io.ReadAll(recorder.Body)
+			Expect(err).NotTo(HaveOccurred())
+			return string(body)
+		}
+
+		BeforeEach(func() {
+			prometheusClient = metrics.NewPrometheusClient(fakeMetronClient)
+			listener = NewTargetConnectionMetrics(lagertest.NewTestLogger("test"), prometheusClient, "new-cluster")
+		})
+
+		It("labels the metrics with the target and counts them apart from the primary client", func() {
+			listener(Disconnected)
+
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(1))
+			name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+			Expect(name).To(Equal("NATSTargetDisconnects"))
+			Expect(delta).To(BeEquivalentTo(1))
+			_, _, opts := fakeMetronClient.SendMetricArgsForCall(0)
+			Expect(opts).To(HaveLen(1))
+
+			body := scrape()
+			Expect(body).To(ContainSubstring(`route_emitter_nats_target_disconnects_total{isolation_segment="",nats_target="new-cluster",router_type="",subject=""} 1`))
+			Expect(body).To(ContainSubstring(`route_emitter_nats_connected{isolation_segment="",nats_target="new-cluster",router_type="",subject=""} 0`))
+		})
+
+		Context("without prometheus", func() {
+			It("sends the counters to the metron client", func() {
+				listener = NewTargetConnectionMetrics(lagertest.NewTestLogger("test"), fakeMetronClient, "new-cluster")
+				listener(Reconnected)
+
+				name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+				Expect(name).To(Equal("NATSTargetReconnects"))
+				Expect(delta).To(BeEquivalentTo(1))
+			})
+		})
+	})
+})
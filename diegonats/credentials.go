@@ -0,0 +1,71 @@
+package diegonats
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+var ErrConflictingCredentials = errors.New("only one of password file, nkey seed file and creds file can be set")
+
+// Credentials authenticate the client with a password file, an NKey seed or
+// a JWT credentials file instead of the user info embedded in the server
+// urls. The files are read again whenever the client reconnects, so rotated
+// credentials are picked up without a restart. The public key of an NKey
+// seed is only read when connecting for the first time.
+type Credentials struct {
+	Username     string
+	PasswordFile string
+	NKeySeedFile string
+	CredsFile    string
+}
+
+func (c Credentials) Validate() error {
+	set := 0
+	for _, file := range []string{c.PasswordFile, c.NKeySeedFile, c.CredsFile} {
+		if file != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return ErrConflictingCredentials
+	}
+	return nil
+}
+
+// FromFiles returns whether any credentials file is set.
+func (c Credentials) FromFiles() bool {
+	return c.PasswordFile != "" || c.NKeySeedFile != "" || c.CredsFile != ""
+}
+
+func (c Credentials) options() ([]nats.Option, error) {
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.PasswordFile != "":
+		username, passwordFile := c.Username, c.PasswordFile
+		return []nats.Option{nats.UserInfoHandler(func() (string, string) {
+			// an unreadable file fails the authentication, which the
+			// client reports as a connection error
+			password, _ := os.ReadFile(passwordFile)
+			return username, strings.TrimSpace(string(password))
+		})}, nil
+
+	case c.NKeySeedFile != "":
+		option, err := nats.NkeyOptionFromSeed(c.NKeySeedFile)
+		if err != nil {
+			return nil, err
+		}
+		return []nats.Option{option}, nil
+
+	case c.CredsFile != "":
+		return []nats.Option{nats.UserCredentials(c.CredsFile)}, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,89 @@
+package diegonats_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "code.cloudfoundry.org/route-emitter/diegonats"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Credentials", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "credentials")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Describe("Validate", func() {
+		It("accepts a single kind of credentials", func() {
+			Expect(Credentials{}.Validate()).To(Succeed())
+			Expect(Credentials{Username: "nats", PasswordFile: "password"}.Validate()).To(Succeed())
+			Expect(Credentials{CredsFile: "user.creds"}.Validate()).To(Succeed())
+		})
+
+		It("rejects more than one kind of credentials", func() {
+			err := Credentials{NKeySeedFile: "seed", CredsFile: "user.creds"}.Validate()
+			Expect(err).To(MatchError(ErrConflictingCredentials))
+		})
+	})
+
+	Describe("connecting", func() {
+		var (
+			natsClient   NATSClient
+			natsUrls     []string
+			passwordFile string
+		)
+
+		BeforeEach(func() {
+			startNATSWithAuth("nats", "secret")
+			natsUrls = []string{fmt.Sprintf("nats://127.0.0.1:%d", natsPort)}
+			passwordFile = filepath.Join(tmpDir, "password")
+			natsClient = NewClient()
+		})
+
+		AfterEach(func() {
+			natsClient.Close()
+			stopNATS()
+		})
+
+		It("authenticates with the password read from the file", func() {
+			Expect(os.WriteFile(passwordFile, []byte("secret\n"), 0600)).To(Succeed())
+			natsClient.SetCredentials(Credentials{Username: "nats", PasswordFile: passwordFile})
+
+			_, err := natsClient.Connect(natsUrls)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(natsClient.Ping()).To(BeTrue())
+		})
+
+		It("fails when the password in the file is wrong", func() {
+			Expect(os.WriteFile(passwordFile, []byte("wrong"), 0600)).To(Succeed())
+			natsClient.SetCredentials(Credentials{Username: "nats", PasswordFile: passwordFile})
+
+			_, err := natsClient.Connect(natsUrls)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails when the nkey seed cannot be read", func() {
+			natsClient.SetCredentials(Credentials{NKeySeedFile: filepath.Join(tmpDir, "missing")})
+
+			_, err := natsClient.Connect(natsUrls)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails when the credentials conflict", func() {
+			natsClient.SetCredentials(Credentials{PasswordFile: passwordFile, CredsFile: "user.creds"})
+
+			_, err := natsClient.Connect(natsUrls)
+			Expect(err).To(MatchError(ErrConflictingCredentials))
+		})
+	})
+})
@@ -47,6 +47,10 @@ func startNATSWithTLS(caFile, certFile, keyFile string) {
 	natsServerProcess = ginkgomon.Invoke(natsserverrunner.NewNatsServerWithTLSTestRunner(int(natsPort), caFile, certFile, keyFile))
 }
 
+func startNATSWithAuth(username, password string) {
+	natsServerProcess = ginkgomon.Invoke(natsserverrunner.NewNatsServerWithAuthTestRunner(int(natsPort), username, password))
+}
+
 func stopNATS() {
 	ginkgomon.Kill(natsServerProcess)
 }
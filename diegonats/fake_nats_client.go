@@ -23,6 +23,10 @@ type FakeNATSClient struct {
 	onPing       func() bool
 	pingResponse bool
 	pingInterval time.Duration
+	credentials  Credentials
+	retryConnect bool
+
+	connectionListeners []ConnectionListener
 
 	sync.RWMutex
 }
@@ -50,6 +54,7 @@ func (f *FakeNATSClient) Reset() {
 	f.whenPublishing = map[string]func(*nats.Msg) error{}
 
 	f.pingResponse = true
+	f.connectionListeners = nil
 }
 
 func (f *FakeNATSClient) Connect(urls []string) (chan struct{}, error) {
@@ -66,6 +71,53 @@ func (f *FakeNATSClient) SetPingInterval(interval time.Duration) {
 	f.pingInterval = interval
 }
 
+func (f *FakeNATSClient) SetCredentials(credentials Credentials) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.credentials = credentials
+}
+
+func (f *FakeNATSClient) Credentials() Credentials {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.credentials
+}
+
+func (f *FakeNATSClient) SetRetryOnFailedConnect(retry bool) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.retryConnect = retry
+}
+
+func (f *FakeNATSClient) RetryOnFailedConnect() bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.retryConnect
+}
+
+func (f *FakeNATSClient) AddConnectionListener(listener ConnectionListener) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.connectionListeners = append(f.connectionListeners, listener)
+}
+
+// NotifyConnectionState calls the connection listeners as the real client
+// does when its connection changes.
+func (f *FakeNATSClient) NotifyConnectionState(state ConnectionState) {
+	f.RLock()
+	listeners := append([]ConnectionListener{}, f.connectionListeners...)
+	f.RUnlock()
+
+	for _, listener := range listeners {
+		listener(state)
+	}
+}
+
 func (f *FakeNATSClient) Close() {
 	f.Lock()
 	defer f.Unlock()
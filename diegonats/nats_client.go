@@ -2,14 +2,30 @@ package diegonats
 
 import (
 	"crypto/tls"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
 )
 
+// ConnectionState is reported to the connection listeners whenever the
+// connection to NATS is lost, re-established or closed for good.
+type ConnectionState string
+
+const (
+	Disconnected ConnectionState = "disconnected"
+	Reconnected  ConnectionState = "reconnected"
+	Closed       ConnectionState = "closed"
+)
+
+type ConnectionListener func(ConnectionState)
+
 type NATSClient interface {
 	Connect(urls []string) (chan struct{}, error)
 	SetPingInterval(interval time.Duration)
+	SetCredentials(credentials Credentials)
+	SetRetryOnFailedConnect(retry bool)
+	AddConnectionListener(listener ConnectionListener)
 	Close()
 	Ping() bool
 	Unsubscribe(sub *nats.Subscription) error
@@ -26,6 +42,11 @@ type natsClient struct {
 	*nats.Conn
 	pingInterval time.Duration
 	tlsConfig    *tls.Config
+	credentials  Credentials
+	retryConnect bool
+
+	listenersLock sync.Mutex
+	listeners     []ConnectionListener
 }
 
 func NewClient() NATSClient {
@@ -45,6 +66,17 @@ func (nc *natsClient) SetPingInterval(interval time.Duration) {
 	nc.pingInterval = interval
 }
 
+func (nc *natsClient) SetCredentials(credentials Credentials) {
+	nc.credentials = credentials
+}
+
+// SetRetryOnFailedConnect makes Connect succeed even when no server is
+// reachable, the client keeps connecting in the background and notifies the
+// listeners with Reconnected once it is connected.
+func (nc *natsClient) SetRetryOnFailedConnect(retry bool) {
+	nc.retryConnect = retry
+}
+
 func (nc *natsClient) Connect(urls []string) (chan struct{}, error) {
 	options := nats.DefaultOptions
 	options.Servers = urls
@@ -53,9 +85,33 @@ func (nc *natsClient) Connect(urls []string) (chan struct{}, error) {
 	options.PingInterval = nc.pingInterval
 	options.TLSConfig = nc.tlsConfig
 
+	credentialOptions, err := nc.credentials.options()
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range credentialOptions {
+		err = option(&options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	closedChan := make(chan struct{})
 	options.ClosedCB = func(*nats.Conn) {
 		close(closedChan)
+		nc.notify(Closed)
+	}
+	options.DisconnectedErrCB = func(*nats.Conn, error) {
+		nc.notify(Disconnected)
+	}
+	options.ReconnectedCB = func(*nats.Conn) {
+		nc.notify(Reconnected)
+	}
+	if nc.retryConnect {
+		options.RetryOnFailedConnect = true
+		options.ConnectedCB = func(*nats.Conn) {
+			nc.notify(Reconnected)
+		}
 	}
 
 	natsConnection, err := options.Connect()
@@ -67,6 +123,24 @@ func (nc *natsClient) Connect(urls []string) (chan struct{}, error) {
 	return closedChan, nil
 }
 
+// AddConnectionListener registers a listener that is called from the NATS
+// connection goroutine, listeners must not block.
+func (nc *natsClient) AddConnectionListener(listener ConnectionListener) {
+	nc.listenersLock.Lock()
+	defer nc.listenersLock.Unlock()
+	nc.listeners = append(nc.listeners, listener)
+}
+
+func (nc *natsClient) notify(state ConnectionState) {
+	nc.listenersLock.Lock()
+	listeners := append([]ConnectionListener{}, nc.listeners...)
+	nc.listenersLock.Unlock()
+
+	for _, listener := range listeners {
+		listener(state)
+	}
+}
+
 func (nc *natsClient) Close() {
 	if nc.Conn != nil {
 		nc.Conn.Close()
@@ -32,9 +32,12 @@ func (runner NATSClientRunner) Run(signals <-chan os.Signal, ready chan<- struct
 	for _, addr := range strings.Split(runner.addresses, ",") {
 		uri := url.URL{
 			Scheme: "nats",
-			User:   url.UserPassword(runner.username, runner.password),
 			Host:   addr,
 		}
+		// user info in the url takes precedence over the client credentials
+		if runner.username != "" || runner.password != "" {
+			uri.User = url.UserPassword(runner.username, runner.password)
+		}
 		natsMembers = append(natsMembers, uri.String())
 	}
 
@@ -117,6 +117,63 @@ var _ = Describe("NatsClient", func() {
 		verifyConnect()
 
 		verifySubscription()
+
+		Describe("connection listeners", func() {
+			var states chan ConnectionState
+
+			BeforeEach(func() {
+				states = make(chan ConnectionState, 10)
+				natsClient.AddConnectionListener(func(state ConnectionState) {
+					states <- state
+				})
+				_, err := natsClient.Connect(natsUrls)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				natsClient.Close()
+			})
+
+			It("notifies the listeners when the connection is lost and re-established", func() {
+				stopNATS()
+				Eventually(states).Should(Receive(Equal(Disconnected)))
+
+				startNATS()
+				Eventually(states, 5).Should(Receive(Equal(Reconnected)))
+			})
+
+			It("notifies the listeners when the connection is closed", func() {
+				natsClient.Close()
+				Eventually(states).Should(Receive(Equal(Closed)))
+			})
+		})
+
+		Describe("retrying a failed connect", func() {
+			var states chan ConnectionState
+
+			BeforeEach(func() {
+				stopNATS()
+				states = make(chan ConnectionState, 10)
+				natsClient.AddConnectionListener(func(state ConnectionState) {
+					states <- state
+				})
+				natsClient.SetRetryOnFailedConnect(true)
+			})
+
+			AfterEach(func() {
+				natsClient.Close()
+			})
+
+			It("connects once the server is available", func() {
+				_, err := natsClient.Connect(natsUrls)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(natsClient.Ping()).To(BeFalse())
+
+				startNATS()
+				Eventually(states, 5).Should(Receive(Equal(Reconnected)))
+				Expect(natsClient.Ping()).To(BeTrue())
+			})
+		})
 	})
 
 	Context("when configured with TLS", func() {
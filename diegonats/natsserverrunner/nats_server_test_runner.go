@@ -81,3 +81,21 @@ func NewNatsServerWithTLSTestRunner(natsPort int, caFile, certFile, keyFile stri
 		),
 	})
 }
+
+func NewNatsServerWithAuthTestRunner(natsPort int, username, password string) *ginkgomon.Runner {
+	natsServerPath, err := exec.LookPath("nats-server")
+	Expect(err).NotTo(HaveOccurred(), "You need nats-server installed!")
+
+	return ginkgomon.New(ginkgomon.Config{
+		Name:              "nats-server",
+		AnsiColorCode:     "99m",
+		StartCheck:        "Server is ready",
+		StartCheckTimeout: 5 * time.Second,
+		Command: exec.Command(
+			natsServerPath,
+			"-p", strconv.Itoa(natsPort),
+			"--user", username,
+			"--pass", password,
+		),
+	})
+}
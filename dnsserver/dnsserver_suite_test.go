@@ -0,0 +1,13 @@
+package dnsserver_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDNSServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DNSServer Suite")
+}
@@ -0,0 +1,202 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/miekg/dns"
+)
+
+// recordTTL is zero so that resolvers never cache an instance that has moved.
+const recordTTL = 0
+
+// SyncTracker reports when the table was last synced with the BBS.
+type SyncTracker interface {
+	LastSync() time.Time
+}
+
+// Server answers A and SRV queries for internal routes from the routing
+// table. Both the hostname and the <index>.hostname forms are resolved.
+// Until the table was first synced with the BBS every query fails with
+// SERVFAIL, so that resolvers do not cache a negative answer from a table
+// that may be empty or stale.
+type Server struct {
+	logger        lager.Logger
+	table         routingtable.RoutingTable
+	syncs         SyncTracker
+	listenAddress string
+
+	mutex sync.Mutex
+	addr  net.Addr
+}
+
+func NewServer(logger lager.Logger, table routingtable.RoutingTable, syncs SyncTracker, listenAddress string) *Server {
+	return &Server{
+		logger:        logger.Session("dns-server"),
+		table:         table,
+		syncs:         syncs,
+		listenAddress: listenAddress,
+	}
+}
+
+// Addr returns the address the server is listening on once it is ready. The
+// server listens on the same port for udp and tcp.
+func (s *Server) Addr() net.Addr {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.addr
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := s.logger.Session("run")
+
+	packetConn, err := net.ListenPacket("udp", s.listenAddress)
+	if err != nil {
+		logger.Error("failed-to-listen-udp", err)
+		return err
+	}
+
+	listener, err := net.Listen("tcp", packetConn.LocalAddr().String())
+	if err != nil {
+		packetConn.Close()
+		logger.Error("failed-to-listen-tcp", err)
+		return err
+	}
+
+	s.mutex.Lock()
+	s.addr = packetConn.LocalAddr()
+	s.mutex.Unlock()
+
+	udpServer := &dns.Server{PacketConn: packetConn, Handler: s}
+	tcpServer := &dns.Server{Listener: listener, Handler: s}
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- udpServer.ActivateAndServe()
+	}()
+	go func() {
+		errCh <- tcpServer.ActivateAndServe()
+	}()
+
+	logger.Info("started", lager.Data{"address": packetConn.LocalAddr().String()})
+	close(ready)
+
+	select {
+	case err = <-errCh:
+		logger.Error("failed-serving", err)
+	case <-signals:
+		logger.Info("stopping")
+	}
+
+	udpServer.Shutdown()
+	tcpServer.Shutdown()
+	return err
+}
+
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+
+	switch {
+	case len(req.Question) != 1:
+		resp.Rcode = dns.RcodeFormatError
+	case s.syncs.LastSync().IsZero():
+		resp.Rcode = dns.RcodeServerFailure
+	default:
+		resp.Authoritative = true
+		s.answer(resp, req.Question[0])
+	}
+
+	err := w.WriteMsg(resp)
+	if err != nil {
+		s.logger.Error("failed-to-write-response", err)
+	}
+}
+
+func (s *Server) answer(resp *dns.Msg, question dns.Question) {
+	name := strings.ToLower(dns.Fqdn(question.Name))
+	hostname := strings.TrimSuffix(name, ".")
+
+	endpoints, indexed := s.lookup(hostname)
+	if len(endpoints) == 0 {
+		resp.Rcode = dns.RcodeNameError
+		return
+	}
+
+	switch question.Qtype {
+	case dns.TypeA:
+		for _, endpoint := range endpoints {
+			if record := aRecord(name, endpoint); record != nil {
+				resp.Answer = append(resp.Answer, record)
+			}
+		}
+	case dns.TypeSRV:
+		for _, endpoint := range endpoints {
+			target := name
+			if !indexed {
+				target = dns.Fqdn(fmt.Sprintf("%d.%s", endpoint.Index, hostname))
+			}
+
+			for _, port := range endpoint.Ports {
+				resp.Answer = append(resp.Answer, &dns.SRV{
+					Hdr:      dns.RR_Header{Name: name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: recordTTL},
+					Priority: 0,
+					Weight:   1,
+					Port:     uint16(port),
+					Target:   target,
+				})
+			}
+
+			if len(endpoint.Ports) > 0 {
+				if record := aRecord(target, endpoint); record != nil {
+					resp.Extra = append(resp.Extra, record)
+				}
+			}
+		}
+	}
+}
+
+// lookup resolves the hostname first as an internal route, then as an
+// <index>.hostname form.
+func (s *Server) lookup(hostname string) ([]routingtable.InternalEndpoint, bool) {
+	endpoints := s.table.LookupInternalRoute(hostname)
+	if len(endpoints) > 0 {
+		return endpoints, false
+	}
+
+	labels := strings.SplitN(hostname, ".", 2)
+	if len(labels) != 2 {
+		return nil, false
+	}
+
+	index, err := strconv.ParseInt(labels[0], 10, 32)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, endpoint := range s.table.LookupInternalRoute(labels[1]) {
+		if endpoint.Index == int32(index) {
+			return []routingtable.InternalEndpoint{endpoint}, true
+		}
+	}
+	return nil, false
+}
+
+func aRecord(name string, endpoint routingtable.InternalEndpoint) dns.RR {
+	ip := net.ParseIP(endpoint.ContainerIP).To4()
+	if ip == nil {
+		return nil
+	}
+
+	return &dns.A{
+		Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: recordTTL},
+		A:   ip,
+	}
+}
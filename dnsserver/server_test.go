@@ -0,0 +1,154 @@
+package dnsserver_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/dnsserver"
+	"code.cloudfoundry.org/route-emitter/health"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		fakeTable   *fakeroutingtable.FakeRoutingTable
+		healthState *health.State
+		server      *dnsserver.Server
+		process     ifrit.Process
+		client      *dns.Client
+	)
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(dns.Fqdn(name), qtype)
+		resp, _, err := client.Exchange(req, server.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	aRecords := func(resp *dns.Msg) []string {
+		ips := []string{}
+		for _, rr := range resp.Answer {
+			a, ok := rr.(*dns.A)
+			Expect(ok).To(BeTrue())
+			ips = append(ips, a.A.String())
+		}
+		return ips
+	}
+
+	BeforeEach(func() {
+		fakeTable = &fakeroutingtable.FakeRoutingTable{}
+		fakeTable.LookupInternalRouteStub = func(hostname string) []routingtable.InternalEndpoint {
+			switch hostname {
+			case "app.apps.internal":
+				return []routingtable.InternalEndpoint{
+					{Index: 0, ContainerIP: "10.255.0.1", Ports: []uint32{8080}},
+					{Index: 1, ContainerIP: "10.255.0.2", Ports: []uint32{8080}},
+				}
+			case "worker.apps.internal":
+				return []routingtable.InternalEndpoint{
+					{Index: 0, ContainerIP: "10.255.1.1"},
+				}
+			}
+			return nil
+		}
+
+		healthState = health.NewState(fakeclock.NewFakeClock(time.Now()), nil, health.Thresholds{})
+		healthState.SyncSucceeded()
+	})
+
+	JustBeforeEach(func() {
+		server = dnsserver.NewServer(lagertest.NewTestLogger("test"), fakeTable, healthState, "127.0.0.1:0")
+		process = ifrit.Invoke(server)
+		client = &dns.Client{}
+	})
+
+	AfterEach(func() {
+		ifrit.Interrupt(process)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	Context("before the table was first synced", func() {
+		BeforeEach(func() {
+			healthState = health.NewState(fakeclock.NewFakeClock(time.Now()), nil, health.Thresholds{})
+		})
+
+		It("fails every query without an authoritative answer", func() {
+			for _, name := range []string{"app.apps.internal", "unknown.apps.internal"} {
+				resp := query(name, dns.TypeA)
+				Expect(resp.Rcode).To(Equal(dns.RcodeServerFailure))
+				Expect(resp.Authoritative).To(BeFalse())
+				Expect(resp.Answer).To(BeEmpty())
+			}
+		})
+
+		It("answers once the table was synced", func() {
+			healthState.SyncSucceeded()
+			resp := query("app.apps.internal", dns.TypeA)
+			Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+		})
+	})
+
+	Describe("A queries", func() {
+		It("returns every instance of the hostname", func() {
+			resp := query("app.apps.internal", dns.TypeA)
+			Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(resp.Authoritative).To(BeTrue())
+			Expect(aRecords(resp)).To(Equal([]string{"10.255.0.1", "10.255.0.2"}))
+		})
+
+		It("returns a single instance for the index form", func() {
+			resp := query("1.app.apps.internal", dns.TypeA)
+			Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(aRecords(resp)).To(Equal([]string{"10.255.0.2"}))
+		})
+
+		It("is case insensitive", func() {
+			resp := query("App.Apps.Internal", dns.TypeA)
+			Expect(aRecords(resp)).To(HaveLen(2))
+		})
+
+		It("answers over tcp", func() {
+			client.Net = "tcp"
+			resp := query("app.apps.internal", dns.TypeA)
+			Expect(aRecords(resp)).To(HaveLen(2))
+		})
+
+		Context("when the hostname is unknown", func() {
+			It("returns NXDOMAIN", func() {
+				Expect(query("unknown.apps.internal", dns.TypeA).Rcode).To(Equal(dns.RcodeNameError))
+				Expect(query("5.app.apps.internal", dns.TypeA).Rcode).To(Equal(dns.RcodeNameError))
+			})
+		})
+	})
+
+	Describe("SRV queries", func() {
+		It("returns a record per instance and port pointing at the index form", func() {
+			resp := query("app.apps.internal", dns.TypeSRV)
+			Expect(resp.Answer).To(HaveLen(2))
+
+			srv := resp.Answer[0].(*dns.SRV)
+			Expect(srv.Port).To(BeEquivalentTo(8080))
+			Expect(srv.Target).To(Equal("0.app.apps.internal."))
+			Expect(resp.Answer[1].(*dns.SRV).Target).To(Equal("1.app.apps.internal."))
+
+			Expect(resp.Extra).To(HaveLen(2))
+			Expect(resp.Extra[0].Header().Name).To(Equal("0.app.apps.internal."))
+			Expect(resp.Extra[0].(*dns.A).A.String()).To(Equal("10.255.0.1"))
+		})
+
+		Context("when the ports are not known", func() {
+			It("returns no records", func() {
+				resp := query("worker.apps.internal", dns.TypeSRV)
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(resp.Answer).To(BeEmpty())
+			})
+		})
+	})
+})
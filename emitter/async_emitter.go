@@ -0,0 +1,427 @@
+package emitter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-api/models"
+)
+
+const (
+	emitQueueDepthMetric      = "EmitQueueDepth"
+	emitQueueCoalescedCounter = "EmitQueueCoalesced"
+	emitQueueDroppedCounter   = "EmitQueueDropped"
+)
+
+var ErrEmitQueueOverflow = errors.New("emit queue overflow")
+
+// emitBatch collects the queued messages of a sink in the order they were
+// last enqueued.
+type emitBatch struct {
+	messages routingtable.MessagesToEmit
+	mappings routingtable.TCPRouteMappings
+}
+
+type queueEntry struct {
+	seq uint64
+	add func(batch *emitBatch)
+}
+
+// emitQueue holds the messages that are waiting to be emitted to a sink,
+// keyed by the route they are about. A message replaces a queued message for
+// the same route, so only the latest state of each route is emitted.
+type emitQueue struct {
+	logger       lager.Logger
+	metronClient loggingclient.IngressClient
+	sink         string
+	capacity     int
+	onOverflow   func()
+	flush        func(batch emitBatch) error
+
+	lock          sync.Mutex
+	seq           uint64
+	pending       map[string]queueEntry
+	limit         int
+	resyncPending bool
+	notify        chan struct{}
+}
+
+func newEmitQueue(
+	logger lager.Logger,
+	metronClient loggingclient.IngressClient,
+	sink string,
+	capacity int,
+	onOverflow func(),
+	flush func(batch emitBatch) error,
+) *emitQueue {
+	return &emitQueue{
+		logger:       logger.Session("emit-queue", lager.Data{"sink": sink}),
+		metronClient: metronClient,
+		sink:         sink,
+		capacity:     capacity,
+		onOverflow:   onOverflow,
+		flush:        flush,
+		pending:      map[string]queueEntry{},
+		limit:        capacity,
+		notify:       make(chan struct{}, 1),
+	}
+}
+
+// enqueue adds the entries to the queue. When they do not fit, the queue and
+// the entries are dropped and a full emit is requested to make up for them,
+// unless one is already pending.
+//
+// Entries that exceed the capacity on their own are a full emit of a table
+// larger than the queue. Dropping them would only request the same full emit
+// again, so they are queued whole and the queue keeps room for capacity more
+// entries until it is emitted.
+func (q *emitQueue) enqueue(entries map[string]func(batch *emitBatch)) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	q.lock.Lock()
+	newKeys := 0
+	for key := range entries {
+		if _, ok := q.pending[key]; !ok {
+			newKeys++
+		}
+	}
+
+	oversized := len(entries) > q.capacity
+	if !oversized && len(q.pending)+newKeys > q.limit {
+		dropped := len(q.pending) + len(entries)
+		q.pending = map[string]queueEntry{}
+		q.limit = q.capacity
+		triggerResync := !q.resyncPending
+		q.resyncPending = true
+		q.lock.Unlock()
+
+		q.logger.Error("dropping-queued-messages", ErrEmitQueueOverflow, lager.Data{"dropped": dropped, "capacity": q.capacity})
+		q.incrementCounter(emitQueueDroppedCounter, dropped)
+		q.sendDepth(0)
+		if triggerResync && q.onOverflow != nil {
+			q.onOverflow()
+		}
+		return ErrEmitQueueOverflow
+	}
+
+	// entries of a single emit are unordered with respect to each other,
+	// sort the keys so that repeated emits queue them in the same order
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		q.seq++
+		q.pending[key] = queueEntry{seq: q.seq, add: entries[key]}
+	}
+	if oversized {
+		q.limit = len(q.pending) + q.capacity
+	}
+	depth := len(q.pending)
+	q.lock.Unlock()
+
+	if oversized {
+		q.logger.Info("queued-emit-larger-than-capacity", lager.Data{"count": len(entries), "capacity": q.capacity})
+	}
+
+	q.incrementCounter(emitQueueCoalescedCounter, len(entries)-newKeys)
+	q.sendDepth(depth)
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *emitQueue) take() (emitBatch, int) {
+	q.lock.Lock()
+	entries := make([]queueEntry, 0, len(q.pending))
+	for _, entry := range q.pending {
+		entries = append(entries, entry)
+	}
+	q.pending = map[string]queueEntry{}
+	q.limit = q.capacity
+	q.lock.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+
+	batch := emitBatch{}
+	for _, entry := range entries {
+		entry.add(&batch)
+	}
+	return batch, len(entries)
+}
+
+// Run emits the queued messages until it is signaled. Messages still queued
+// when it exits are dropped.
+func (q *emitQueue) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	q.logger.Info("starting")
+	close(ready)
+	defer q.logger.Info("exiting")
+
+	for {
+		select {
+		case <-signals:
+			return nil
+
+		case <-q.notify:
+			batch, count := q.take()
+			if count == 0 {
+				continue
+			}
+			q.sendDepth(0)
+
+			err := q.flush(batch)
+			if err != nil {
+				q.logger.Error("failed-to-emit", err, lager.Data{"count": count})
+				continue
+			}
+
+			q.lock.Lock()
+			q.resyncPending = false
+			q.lock.Unlock()
+		}
+	}
+}
+
+func (q *emitQueue) sendDepth(depth int) {
+	err := q.metronClient.SendMetric(q.sink+emitQueueDepthMetric, depth)
+	if err != nil {
+		q.logger.Error("failed-to-send-queue-depth-metric", err)
+	}
+}
+
+func (q *emitQueue) incrementCounter(name string, delta int) {
+	if delta <= 0 {
+		return
+	}
+	err := q.metronClient.IncrementCounterWithDelta(q.sink+name, uint64(delta))
+	if err != nil {
+		q.logger.Error("failed-to-increment-counter", err, lager.Data{"counter": q.sink + name})
+	}
+}
+
+// AsyncNATSEmitter queues the messages it is asked to emit and emits them to
+// the delegate from its own goroutine, so that a slow sink does not block the
+// caller. It must be run as an ifrit runner.
+type AsyncNATSEmitter struct {
+	*emitQueue
+}
+
+// NewAsyncNATSEmitter returns an emitter that queues up to capacity routes
+// for the delegate. Registering and unregistering the same route coalesce
+// into the latest of the two. When the queue overflows, the queued messages
+// are dropped and onOverflow is called to request a full emit. A single emit
+// of more than capacity routes, such as a full emit, is always queued.
+func NewAsyncNATSEmitter(
+	logger lager.Logger,
+	delegate NATSEmitter,
+	metronClient loggingclient.IngressClient,
+	capacity int,
+	onOverflow func(),
+) *AsyncNATSEmitter {
+	return newAsyncNATSEmitter(logger, delegate, metronClient, "NATS", capacity, onOverflow)
+}
+
+// NewAsyncRoutingAPIHTTPEmitter returns an AsyncNATSEmitter for the emitter
+// of the http routes in the routing api, whose queue reports its metrics
+// separately from the queue of the NATS emitter.
+func NewAsyncRoutingAPIHTTPEmitter(
+	logger lager.Logger,
+	delegate NATSEmitter,
+	metronClient loggingclient.IngressClient,
+	capacity int,
+	onOverflow func(),
+) *AsyncNATSEmitter {
+	return newAsyncNATSEmitter(logger, delegate, metronClient, "RoutingAPIHTTP", capacity, onOverflow)
+}
+
+func newAsyncNATSEmitter(
+	logger lager.Logger,
+	delegate NATSEmitter,
+	metronClient loggingclient.IngressClient,
+	sink string,
+	capacity int,
+	onOverflow func(),
+) *AsyncNATSEmitter {
+	flush := func(batch emitBatch) error {
+		return delegate.Emit(batch.messages)
+	}
+	return &AsyncNATSEmitter{newEmitQueue(logger, metronClient, sink, capacity, onOverflow, flush)}
+}
+
+func (e *AsyncNATSEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	entries := map[string]func(batch *emitBatch){}
+	for _, message := range messagesToEmit.RegistrationMessages {
+		message := message
+		entries[registryMessageKey("external", message)] = func(batch *emitBatch) {
+			batch.messages.RegistrationMessages = append(batch.messages.RegistrationMessages, message)
+		}
+	}
+	for _, message := range messagesToEmit.UnregistrationMessages {
+		message := message
+		entries[registryMessageKey("external", message)] = func(batch *emitBatch) {
+			batch.messages.UnregistrationMessages = append(batch.messages.UnregistrationMessages, message)
+		}
+	}
+	for _, message := range messagesToEmit.InternalRegistrationMessages {
+		message := message
+		entries[registryMessageKey("internal", message)] = func(batch *emitBatch) {
+			batch.messages.InternalRegistrationMessages = append(batch.messages.InternalRegistrationMessages, message)
+		}
+	}
+	for _, message := range messagesToEmit.InternalUnregistrationMessages {
+		message := message
+		entries[registryMessageKey("internal", message)] = func(batch *emitBatch) {
+			batch.messages.InternalUnregistrationMessages = append(batch.messages.InternalUnregistrationMessages, message)
+		}
+	}
+	return e.enqueue(entries)
+}
+
+func registryMessageKey(routeType string, message routingtable.RegistryMessage) string {
+	uris := append([]string{}, message.URIs...)
+	sort.Strings(uris)
+	return fmt.Sprintf("%s/%s:%d:%d/%s", routeType, message.Host, message.Port, message.TlsPort, strings.Join(uris, ","))
+}
+
+// AsyncRoutingAPIEmitter is the AsyncNATSEmitter counterpart for tcp route
+// mappings.
+type AsyncRoutingAPIEmitter struct {
+	*emitQueue
+}
+
+// NewAsyncRoutingAPIEmitter returns an emitter that queues up to capacity
+// tcp route mappings for the delegate, see NewAsyncNATSEmitter.
+func NewAsyncRoutingAPIEmitter(
+	logger lager.Logger,
+	delegate RoutingAPIEmitter,
+	metronClient loggingclient.IngressClient,
+	capacity int,
+	onOverflow func(),
+) *AsyncRoutingAPIEmitter {
+	flush := func(batch emitBatch) error {
+		return delegate.Emit(batch.mappings)
+	}
+	return &AsyncRoutingAPIEmitter{newEmitQueue(logger, metronClient, "RoutingAPI", capacity, onOverflow, flush)}
+}
+
+func (e *AsyncRoutingAPIEmitter) Emit(routingEvents routingtable.TCPRouteMappings) error {
+	entries := map[string]func(batch *emitBatch){}
+	for _, mapping := range routingEvents.Registrations {
+		mapping := mapping
+		entries[tcpRouteMappingKey(mapping)] = func(batch *emitBatch) {
+			batch.mappings.Registrations = append(batch.mappings.Registrations, mapping)
+		}
+	}
+	for _, mapping := range routingEvents.Unregistrations {
+		mapping := mapping
+		entries[tcpRouteMappingKey(mapping)] = func(batch *emitBatch) {
+			batch.mappings.Unregistrations = append(batch.mappings.Unregistrations, mapping)
+		}
+	}
+	return e.enqueue(entries)
+}
+
+func tcpRouteMappingKey(mapping models.TcpRouteMapping) string {
+	return fmt.Sprintf("%s/%d/%s:%d", mapping.RouterGroupGuid, mapping.ExternalPort, mapping.HostIP, mapping.HostPort)
+}
+
+// AsyncTCPReconciler reconciles the tcp route mappings from its own
+// goroutine, so that reading and deleting mappings in the routing api does
+// not block the caller. Every reconcile covers the complete desired state,
+// so only the latest one that is pending is run. It must be run as an ifrit
+// runner.
+type AsyncTCPReconciler struct {
+	logger   lager.Logger
+	delegate TCPReconciler
+
+	lock    sync.Mutex
+	desired *routingtable.TCPRouteMappings
+	notify  chan struct{}
+}
+
+func NewAsyncTCPReconciler(logger lager.Logger, delegate TCPReconciler) *AsyncTCPReconciler {
+	return &AsyncTCPReconciler{
+		logger:   logger.Session("tcp-reconcile-queue"),
+		delegate: delegate,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// Reconcile replaces the pending reconcile, if any, with one of the desired
+// mappings.
+func (r *AsyncTCPReconciler) Reconcile(desired routingtable.TCPRouteMappings) error {
+	r.lock.Lock()
+	r.desired = &desired
+	r.lock.Unlock()
+
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run reconciles the pending mappings until it is signaled.
+func (r *AsyncTCPReconciler) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	r.logger.Info("starting")
+	close(ready)
+	defer r.logger.Info("exiting")
+
+	for {
+		select {
+		case <-signals:
+			return nil
+
+		case <-r.notify:
+			r.lock.Lock()
+			desired := r.desired
+			r.desired = nil
+			r.lock.Unlock()
+			if desired == nil {
+				continue
+			}
+
+			err := r.delegate.Reconcile(*desired)
+			if err != nil {
+				r.logger.Error("failed-to-reconcile", err)
+			}
+		}
+	}
+}
+
+// TriggerEmit returns a function that requests an emit on each of the given
+// channels, without blocking when one is already pending.
+func TriggerEmit(emitChans ...chan<- struct{}) func() {
+	return func() {
+		for _, emitCh := range emitChans {
+			select {
+			case emitCh <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// TriggerFullEmit returns a function like TriggerEmit that calls
+// requestFullEmit before the channels are triggered, so that a sharded
+// emission covers the whole table instead of a single shard.
+func TriggerFullEmit(requestFullEmit func(), emitChans ...chan<- struct{}) func() {
+	triggerEmit := TriggerEmit(emitChans...)
+	return func() {
+		requestFullEmit()
+		triggerEmit()
+	}
+}
@@ -0,0 +1,323 @@
+package emitter_test
+
+import (
+	"errors"
+
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("AsyncNATSEmitter", func() {
+	var (
+		delegate         *fakes.FakeNATSEmitter
+		fakeMetronClient *mfakes.FakeIngressClient
+		overflows        chan struct{}
+		asyncEmitter     *emitter.AsyncNATSEmitter
+		process          ifrit.Process
+
+		first, second routingtable.RegistryMessage
+	)
+
+	BeforeEach(func() {
+		delegate = &fakes.FakeNATSEmitter{}
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		overflows = make(chan struct{}, 10)
+		asyncEmitter = emitter.NewAsyncNATSEmitter(lagertest.NewTestLogger("test"), delegate, fakeMetronClient, 3, func() {
+			overflows <- struct{}{}
+		})
+		process = nil
+
+		first = routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 61000}
+		second = routingtable.RegistryMessage{URIs: []string{"bar.com"}, Host: "2.2.2.2", Port: 61001}
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			ifrit.Interrupt(process)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		}
+	})
+
+	counterDelta := func(name string) uint64 {
+		total := uint64(0)
+		for i := 0; i < fakeMetronClient.IncrementCounterWithDeltaCallCount(); i++ {
+			counter, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(i)
+			if counter == name {
+				total += delta
+			}
+		}
+		return total
+	}
+
+	It("does not emit from the caller", func() {
+		Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{first}})).To(Succeed())
+		Consistently(delegate.EmitCallCount).Should(Equal(0))
+	})
+
+	It("emits the queued messages once running", func() {
+		messages := routingtable.MessagesToEmit{
+			RegistrationMessages:         []routingtable.RegistryMessage{first},
+			InternalRegistrationMessages: []routingtable.RegistryMessage{second},
+		}
+		Expect(asyncEmitter.Emit(messages)).To(Succeed())
+
+		process = ifrit.Invoke(asyncEmitter)
+		Eventually(delegate.EmitCallCount).Should(Equal(1))
+		Expect(delegate.EmitArgsForCall(0)).To(Equal(messages))
+	})
+
+	It("coalesces messages for the same route into the latest one", func() {
+		Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{first, second}})).To(Succeed())
+		Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{UnregistrationMessages: []routingtable.RegistryMessage{first}})).To(Succeed())
+
+		process = ifrit.Invoke(asyncEmitter)
+		Eventually(delegate.EmitCallCount).Should(Equal(1))
+		Expect(delegate.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+			RegistrationMessages:   []routingtable.RegistryMessage{second},
+			UnregistrationMessages: []routingtable.RegistryMessage{first},
+		}))
+		Expect(counterDelta("NATSEmitQueueCoalesced")).To(BeEquivalentTo(1))
+	})
+
+	It("reports the queue depth", func() {
+		Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{first, second}})).To(Succeed())
+		Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(1))
+		name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(name).To(Equal("NATSEmitQueueDepth"))
+		Expect(value).To(Equal(2))
+	})
+
+	Context("when the queue overflows", func() {
+		var overflowing routingtable.MessagesToEmit
+
+		BeforeEach(func() {
+			Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{first, second}})).To(Succeed())
+			overflowing = routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{
+					{URIs: []string{"baz.com"}, Host: "3.3.3.3", Port: 61002},
+					{URIs: []string{"qux.com"}, Host: "4.4.4.4", Port: 61003},
+				},
+			}
+		})
+
+		It("drops the queued and the new messages", func() {
+			Expect(asyncEmitter.Emit(overflowing)).To(MatchError(emitter.ErrEmitQueueOverflow))
+			Expect(counterDelta("NATSEmitQueueDropped")).To(BeEquivalentTo(4))
+
+			process = ifrit.Invoke(asyncEmitter)
+			Consistently(delegate.EmitCallCount).Should(Equal(0))
+		})
+
+		It("requests a resync once until the queue is emitted again", func() {
+			Expect(asyncEmitter.Emit(overflowing)).To(HaveOccurred())
+			Expect(overflows).To(Receive())
+
+			Expect(asyncEmitter.Emit(overflowing)).To(Succeed())
+			Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{first, second}})).To(HaveOccurred())
+			Expect(overflows).NotTo(Receive())
+
+			process = ifrit.Invoke(asyncEmitter)
+			Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{first}})).To(Succeed())
+			Eventually(delegate.EmitCallCount).Should(Equal(1))
+
+			Eventually(func() bool {
+				asyncEmitter.Emit(overflowing)
+				asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{first, second}})
+				select {
+				case <-overflows:
+					return true
+				default:
+					return false
+				}
+			}).Should(BeTrue())
+		})
+	})
+
+	Context("when a single emit is larger than the queue", func() {
+		var full routingtable.MessagesToEmit
+
+		BeforeEach(func() {
+			full = routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{
+					first,
+					second,
+					{URIs: []string{"baz.com"}, Host: "3.3.3.3", Port: 61002},
+					{URIs: []string{"qux.com"}, Host: "4.4.4.4", Port: 61003},
+				},
+			}
+		})
+
+		It("queues it whole instead of requesting the same full emit again", func() {
+			Expect(asyncEmitter.Emit(full)).To(Succeed())
+			Expect(overflows).NotTo(Receive())
+
+			process = ifrit.Invoke(asyncEmitter)
+			Eventually(delegate.EmitCallCount).Should(Equal(1))
+			Expect(delegate.EmitArgsForCall(0)).To(Equal(full))
+		})
+
+		It("keeps room for capacity more routes until it is emitted", func() {
+			Expect(asyncEmitter.Emit(full)).To(Succeed())
+			Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{
+				{URIs: []string{"quux.com"}, Host: "5.5.5.5", Port: 61004},
+				{URIs: []string{"corge.com"}, Host: "6.6.6.6", Port: 61005},
+			}})).To(Succeed())
+			Expect(overflows).NotTo(Receive())
+
+			Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{
+				{URIs: []string{"grault.com"}, Host: "7.7.7.7", Port: 61006},
+				{URIs: []string{"garply.com"}, Host: "8.8.8.8", Port: 61007},
+			}})).To(MatchError(emitter.ErrEmitQueueOverflow))
+			Expect(overflows).To(Receive())
+		})
+	})
+
+	Context("when the delegate fails", func() {
+		BeforeEach(func() {
+			delegate.EmitReturns(errors.New("boom"))
+		})
+
+		It("keeps emitting", func() {
+			process = ifrit.Invoke(asyncEmitter)
+			Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{first}})).To(Succeed())
+			Eventually(delegate.EmitCallCount).Should(Equal(1))
+			Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{second}})).To(Succeed())
+			Eventually(delegate.EmitCallCount).Should(Equal(2))
+		})
+	})
+})
+
+var _ = Describe("AsyncRoutingAPIEmitter", func() {
+	var (
+		delegate     *fakes.FakeRoutingAPIEmitter
+		asyncEmitter *emitter.AsyncRoutingAPIEmitter
+		process      ifrit.Process
+	)
+
+	BeforeEach(func() {
+		delegate = &fakes.FakeRoutingAPIEmitter{}
+		asyncEmitter = emitter.NewAsyncRoutingAPIEmitter(lagertest.NewTestLogger("test"), delegate, &mfakes.FakeIngressClient{}, 10, nil)
+	})
+
+	AfterEach(func() {
+		ifrit.Interrupt(process)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("coalesces mappings for the same route into the latest one", func() {
+		mapping := apimodels.NewTcpRouteMapping("rg", 61000, "1.1.1.1", 62000, 0)
+		other := apimodels.NewTcpRouteMapping("rg", 61001, "1.1.1.1", 62001, 0)
+		Expect(asyncEmitter.Emit(routingtable.TCPRouteMappings{Registrations: []apimodels.TcpRouteMapping{mapping, other}})).To(Succeed())
+		Expect(asyncEmitter.Emit(routingtable.TCPRouteMappings{Unregistrations: []apimodels.TcpRouteMapping{mapping}})).To(Succeed())
+
+		process = ifrit.Invoke(asyncEmitter)
+		Eventually(delegate.EmitCallCount).Should(Equal(1))
+		Expect(delegate.EmitArgsForCall(0)).To(Equal(routingtable.TCPRouteMappings{
+			Registrations:   []apimodels.TcpRouteMapping{other},
+			Unregistrations: []apimodels.TcpRouteMapping{mapping},
+		}))
+	})
+})
+
+var _ = Describe("AsyncRoutingAPIHTTPEmitter", func() {
+	It("reports the queue depth of its own sink", func() {
+		fakeMetronClient := &mfakes.FakeIngressClient{}
+		asyncEmitter := emitter.NewAsyncRoutingAPIHTTPEmitter(lagertest.NewTestLogger("test"), &fakes.FakeNATSEmitter{}, fakeMetronClient, 10, nil)
+		message := routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 61000}
+		Expect(asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{message}})).To(Succeed())
+
+		Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(1))
+		name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(name).To(Equal("RoutingAPIHTTPEmitQueueDepth"))
+		Expect(value).To(Equal(1))
+	})
+})
+
+var _ = Describe("AsyncTCPReconciler", func() {
+	var (
+		delegate        *fakes.FakeTCPReconciler
+		asyncReconciler *emitter.AsyncTCPReconciler
+		process         ifrit.Process
+
+		first, second routingtable.TCPRouteMappings
+	)
+
+	BeforeEach(func() {
+		delegate = &fakes.FakeTCPReconciler{}
+		asyncReconciler = emitter.NewAsyncTCPReconciler(lagertest.NewTestLogger("test"), delegate)
+		process = nil
+
+		first = routingtable.TCPRouteMappings{Registrations: []apimodels.TcpRouteMapping{apimodels.NewTcpRouteMapping("rg", 61000, "1.1.1.1", 62000, 0)}}
+		second = routingtable.TCPRouteMappings{Registrations: []apimodels.TcpRouteMapping{apimodels.NewTcpRouteMapping("rg", 61001, "1.1.1.1", 62001, 0)}}
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			ifrit.Interrupt(process)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		}
+	})
+
+	It("does not reconcile from the caller", func() {
+		Expect(asyncReconciler.Reconcile(first)).To(Succeed())
+		Consistently(delegate.ReconcileCallCount).Should(Equal(0))
+	})
+
+	It("only reconciles the latest desired mappings", func() {
+		Expect(asyncReconciler.Reconcile(first)).To(Succeed())
+		Expect(asyncReconciler.Reconcile(second)).To(Succeed())
+
+		process = ifrit.Invoke(asyncReconciler)
+		Eventually(delegate.ReconcileCallCount).Should(Equal(1))
+		Expect(delegate.ReconcileArgsForCall(0)).To(Equal(second))
+		Consistently(delegate.ReconcileCallCount).Should(Equal(1))
+	})
+
+	Context("when the delegate fails", func() {
+		BeforeEach(func() {
+			delegate.ReconcileReturns(errors.New("boom"))
+		})
+
+		It("keeps reconciling", func() {
+			process = ifrit.Invoke(asyncReconciler)
+			Expect(asyncReconciler.Reconcile(first)).To(Succeed())
+			Eventually(delegate.ReconcileCallCount).Should(Equal(1))
+			Expect(asyncReconciler.Reconcile(second)).To(Succeed())
+			Eventually(delegate.ReconcileCallCount).Should(Equal(2))
+		})
+	})
+})
+
+var _ = Describe("TriggerEmit", func() {
+	It("requests an emit on every channel without blocking", func() {
+		externalChan := make(chan struct{}, 1)
+		internalChan := make(chan struct{}, 1)
+		externalChan <- struct{}{}
+
+		emitter.TriggerEmit(externalChan, internalChan)()
+		Expect(externalChan).To(Receive())
+		Expect(externalChan).NotTo(Receive())
+		Expect(internalChan).To(Receive())
+	})
+})
+
+var _ = Describe("TriggerFullEmit", func() {
+	It("requests a full emit before it requests an emit on every channel", func() {
+		emitChan := make(chan struct{}, 1)
+		requested := false
+		emitter.TriggerFullEmit(func() {
+			Expect(emitChan).NotTo(Receive())
+			requested = true
+		}, emitChan)()
+
+		Expect(requested).To(BeTrue())
+		Expect(emitChan).To(Receive())
+	})
+})
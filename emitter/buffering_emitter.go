@@ -0,0 +1,118 @@
+package emitter
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// maxBufferedUnregistrations is the upper bound of the unregistrations
+// buffered while a nats connection is down, the oldest ones are dropped
+// first.
+const maxBufferedUnregistrations = 10000
+
+// BufferingNATSEmitter holds back the unregistrations for a single nats
+// connection while it is disconnected, so that they are not lost, and
+// replays them with the first emit after it reconnects. Registrations are
+// passed on right away, the next full emit makes up for the failed ones.
+type BufferingNATSEmitter struct {
+	logger   lager.Logger
+	delegate NATSEmitter
+
+	lock         sync.Mutex
+	disconnected bool
+	buffered     routingtable.MessagesToEmit
+}
+
+// NewBufferingNATSEmitter returns an emitter that buffers the unregistrations
+// for the delegate. Its ConnectionStateChanged must be added as a connection
+// listener of the nats client the delegate publishes with.
+func NewBufferingNATSEmitter(logger lager.Logger, delegate NATSEmitter) *BufferingNATSEmitter {
+	return &BufferingNATSEmitter{
+		logger:   logger.Session("buffering-nats-emitter"),
+		delegate: delegate,
+	}
+}
+
+// ConnectionStateChanged is a diegonats.ConnectionListener.
+func (e *BufferingNATSEmitter) ConnectionStateChanged(state diegonats.ConnectionState) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	switch state {
+	case diegonats.Disconnected, diegonats.Closed:
+		e.disconnected = true
+	case diegonats.Reconnected:
+		e.disconnected = false
+	}
+}
+
+func (e *BufferingNATSEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	e.lock.Lock()
+	if e.disconnected {
+		e.buffer(messagesToEmit)
+		e.lock.Unlock()
+
+		messagesToEmit.UnregistrationMessages = nil
+		messagesToEmit.InternalUnregistrationMessages = nil
+		return e.delegate.Emit(messagesToEmit)
+	}
+	buffered := e.buffered
+	e.buffered = routingtable.MessagesToEmit{}
+	e.lock.Unlock()
+
+	if len(buffered.UnregistrationMessages) > 0 || len(buffered.InternalUnregistrationMessages) > 0 {
+		// a route that is registered again must not be unregistered by a
+		// replayed message
+		buffered.UnregistrationMessages = withoutRegistered("external", buffered.UnregistrationMessages, messagesToEmit.RegistrationMessages)
+		buffered.InternalUnregistrationMessages = withoutRegistered("internal", buffered.InternalUnregistrationMessages, messagesToEmit.InternalRegistrationMessages)
+
+		e.logger.Info("replaying-buffered-unregistrations", lager.Data{
+			"num-unregistration-messages":          len(buffered.UnregistrationMessages),
+			"num-internal-unregistration-messages": len(buffered.InternalUnregistrationMessages),
+		})
+		messagesToEmit.UnregistrationMessages = append(buffered.UnregistrationMessages, messagesToEmit.UnregistrationMessages...)
+		messagesToEmit.InternalUnregistrationMessages = append(buffered.InternalUnregistrationMessages, messagesToEmit.InternalUnregistrationMessages...)
+	}
+
+	return e.delegate.Emit(messagesToEmit)
+}
+
+func (e *BufferingNATSEmitter) buffer(messagesToEmit routingtable.MessagesToEmit) {
+	e.buffered.UnregistrationMessages = append(e.buffered.UnregistrationMessages, messagesToEmit.UnregistrationMessages...)
+	e.buffered.InternalUnregistrationMessages = append(e.buffered.InternalUnregistrationMessages, messagesToEmit.InternalUnregistrationMessages...)
+	dropped := trimOldest(&e.buffered.UnregistrationMessages) + trimOldest(&e.buffered.InternalUnregistrationMessages)
+	if dropped > 0 {
+		e.logger.Info("dropped-buffered-unregistrations", lager.Data{"count": dropped})
+	}
+}
+
+func withoutRegistered(routeType string, unregistrations, registrations []routingtable.RegistryMessage) []routingtable.RegistryMessage {
+	if len(registrations) == 0 {
+		return unregistrations
+	}
+
+	registered := map[string]bool{}
+	for _, message := range registrations {
+		registered[registryMessageKey(routeType, message)] = true
+	}
+
+	var remaining []routingtable.RegistryMessage
+	for _, message := range unregistrations {
+		if !registered[registryMessageKey(routeType, message)] {
+			remaining = append(remaining, message)
+		}
+	}
+	return remaining
+}
+
+func trimOldest(messages *[]routingtable.RegistryMessage) int {
+	overflow := len(*messages) - maxBufferedUnregistrations
+	if overflow <= 0 {
+		return 0
+	}
+	*messages = (*messages)[overflow:]
+	return overflow
+}
@@ -0,0 +1,86 @@
+package emitter_test
+
+import (
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BufferingNATSEmitter", func() {
+	var (
+		delegate         *fakes.FakeNATSEmitter
+		bufferingEmitter *emitter.BufferingNATSEmitter
+
+		foo, bar, baz routingtable.RegistryMessage
+	)
+
+	BeforeEach(func() {
+		delegate = &fakes.FakeNATSEmitter{}
+		bufferingEmitter = emitter.NewBufferingNATSEmitter(lagertest.NewTestLogger("test"), delegate)
+
+		foo = routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 61000}
+		bar = routingtable.RegistryMessage{URIs: []string{"bar.com"}, Host: "2.2.2.2", Port: 61001}
+		baz = routingtable.RegistryMessage{URIs: []string{"baz.com"}, Host: "3.3.3.3", Port: 61002}
+	})
+
+	It("passes the messages on while connected", func() {
+		messages := routingtable.MessagesToEmit{
+			RegistrationMessages:   []routingtable.RegistryMessage{foo},
+			UnregistrationMessages: []routingtable.RegistryMessage{bar},
+		}
+		Expect(bufferingEmitter.Emit(messages)).To(Succeed())
+		Expect(delegate.EmitArgsForCall(0)).To(Equal(messages))
+	})
+
+	Context("while disconnected", func() {
+		BeforeEach(func() {
+			bufferingEmitter.ConnectionStateChanged(diegonats.Disconnected)
+			Expect(bufferingEmitter.Emit(routingtable.MessagesToEmit{
+				RegistrationMessages:           []routingtable.RegistryMessage{baz},
+				UnregistrationMessages:         []routingtable.RegistryMessage{foo, bar},
+				InternalUnregistrationMessages: []routingtable.RegistryMessage{bar},
+			})).To(Succeed())
+		})
+
+		It("buffers the unregistrations", func() {
+			Expect(delegate.EmitCallCount()).To(Equal(1))
+			Expect(delegate.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{baz},
+			}))
+		})
+
+		Context("when nats reconnects", func() {
+			BeforeEach(func() {
+				bufferingEmitter.ConnectionStateChanged(diegonats.Reconnected)
+			})
+
+			It("replays the buffered unregistrations with the next emit", func() {
+				Expect(bufferingEmitter.Emit(routingtable.MessagesToEmit{
+					UnregistrationMessages: []routingtable.RegistryMessage{baz},
+				})).To(Succeed())
+				Expect(delegate.EmitArgsForCall(1)).To(Equal(routingtable.MessagesToEmit{
+					UnregistrationMessages:         []routingtable.RegistryMessage{foo, bar, baz},
+					InternalUnregistrationMessages: []routingtable.RegistryMessage{bar},
+				}))
+
+				Expect(bufferingEmitter.Emit(routingtable.MessagesToEmit{})).To(Succeed())
+				Expect(delegate.EmitArgsForCall(2)).To(Equal(routingtable.MessagesToEmit{}))
+			})
+
+			It("does not replay the unregistrations of routes that are registered again", func() {
+				Expect(bufferingEmitter.Emit(routingtable.MessagesToEmit{
+					RegistrationMessages: []routingtable.RegistryMessage{foo},
+				})).To(Succeed())
+				Expect(delegate.EmitArgsForCall(1)).To(Equal(routingtable.MessagesToEmit{
+					RegistrationMessages:           []routingtable.RegistryMessage{foo},
+					UnregistrationMessages:         []routingtable.RegistryMessage{bar},
+					InternalUnregistrationMessages: []routingtable.RegistryMessage{bar},
+				}))
+			})
+		})
+	})
+})
@@ -0,0 +1,55 @@
+package emitter
+
+import "code.cloudfoundry.org/route-emitter/routingtable"
+
+type fanOutNATSEmitter struct {
+	emitters []NATSEmitter
+}
+
+// NewFanOutNATSEmitter returns an emitter that emits the messages to every
+// given emitter, nil emitters are skipped. Every emitter is called even when
+// an earlier one fails, the first error is returned.
+func NewFanOutNATSEmitter(emitters ...NATSEmitter) NATSEmitter {
+	nonNil := []NATSEmitter{}
+	for _, emitter := range emitters {
+		if emitter != nil {
+			nonNil = append(nonNil, emitter)
+		}
+	}
+	return &fanOutNATSEmitter{emitters: nonNil}
+}
+
+func (f *fanOutNATSEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	var firstErr error
+	for _, emitter := range f.emitters {
+		err := emitter.Emit(messagesToEmit)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+type internalOnlyNATSEmitter struct {
+	delegate NATSEmitter
+}
+
+// NewInternalOnlyNATSEmitter returns an emitter that only passes the internal
+// route messages on to the given emitter, for when the external routes are
+// emitted to a different sink. A nil emitter is returned as is.
+func NewInternalOnlyNATSEmitter(delegate NATSEmitter) NATSEmitter {
+	if delegate == nil {
+		return nil
+	}
+	return &internalOnlyNATSEmitter{delegate: delegate}
+}
+
+func (e *internalOnlyNATSEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	if len(messagesToEmit.InternalRegistrationMessages) == 0 && len(messagesToEmit.InternalUnregistrationMessages) == 0 {
+		return nil
+	}
+	return e.delegate.Emit(routingtable.MessagesToEmit{
+		InternalRegistrationMessages:   messagesToEmit.InternalRegistrationMessages,
+		InternalUnregistrationMessages: messagesToEmit.InternalUnregistrationMessages,
+	})
+}
@@ -0,0 +1,87 @@
+package emitter_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FanOutNATSEmitter", func() {
+	var (
+		first, second *fakes.FakeNATSEmitter
+		fanOut        emitter.NATSEmitter
+		messages      routingtable.MessagesToEmit
+	)
+
+	BeforeEach(func() {
+		first = &fakes.FakeNATSEmitter{}
+		second = &fakes.FakeNATSEmitter{}
+		fanOut = emitter.NewFanOutNATSEmitter(first, nil, second)
+		messages = routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.1", Port: 61000}},
+		}
+	})
+
+	It("emits the messages to every emitter", func() {
+		Expect(fanOut.Emit(messages)).To(Succeed())
+		Expect(first.EmitCallCount()).To(Equal(1))
+		Expect(first.EmitArgsForCall(0)).To(Equal(messages))
+		Expect(second.EmitCallCount()).To(Equal(1))
+		Expect(second.EmitArgsForCall(0)).To(Equal(messages))
+	})
+
+	Context("when an emitter fails", func() {
+		BeforeEach(func() {
+			first.EmitReturns(errors.New("boom"))
+			second.EmitReturns(errors.New("bang"))
+		})
+
+		It("still emits to the other emitters and returns the first error", func() {
+			Expect(fanOut.Emit(messages)).To(MatchError("boom"))
+			Expect(second.EmitCallCount()).To(Equal(1))
+		})
+	})
+})
+
+var _ = Describe("InternalOnlyNATSEmitter", func() {
+	var (
+		delegate     *fakes.FakeNATSEmitter
+		internalOnly emitter.NATSEmitter
+	)
+
+	BeforeEach(func() {
+		delegate = &fakes.FakeNATSEmitter{}
+		internalOnly = emitter.NewInternalOnlyNATSEmitter(delegate)
+	})
+
+	It("only passes on the internal messages", func() {
+		messages := routingtable.MessagesToEmit{
+			RegistrationMessages:           []routingtable.RegistryMessage{{Host: "1.1.1.1", Port: 61000}},
+			UnregistrationMessages:         []routingtable.RegistryMessage{{Host: "1.1.1.1", Port: 61001}},
+			InternalRegistrationMessages:   []routingtable.RegistryMessage{{Host: "2.2.2.2", Port: 8080}},
+			InternalUnregistrationMessages: []routingtable.RegistryMessage{{Host: "2.2.2.2", Port: 8081}},
+		}
+		Expect(internalOnly.Emit(messages)).To(Succeed())
+		Expect(delegate.EmitCallCount()).To(Equal(1))
+		Expect(delegate.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+			InternalRegistrationMessages:   messages.InternalRegistrationMessages,
+			InternalUnregistrationMessages: messages.InternalUnregistrationMessages,
+		}))
+	})
+
+	It("does not emit when there are no internal messages", func() {
+		messages := routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.1", Port: 61000}},
+		}
+		Expect(internalOnly.Emit(messages)).To(Succeed())
+		Expect(delegate.EmitCallCount()).To(Equal(0))
+	})
+
+	It("returns nil for a nil emitter", func() {
+		Expect(emitter.NewInternalOnlyNATSEmitter(nil)).To(BeNil())
+	})
+})
@@ -2,11 +2,14 @@ package emitter
 
 import (
 	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"sync"
 
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/workpool"
 )
@@ -14,6 +17,7 @@ import (
 const (
 	httpRouteNATSMessagesEmittedCounter     = "HTTPRouteNATSMessagesEmitted"
 	internalRouteNATSMessagesEmittedCounter = "InternalRouteNATSMessagesEmitted"
+	natsMessagesEmittedCounter              = "NATSMessagesEmitted"
 )
 
 //go:generate counterfeiter -o fakes/fake_nats_emitter.go . NATSEmitter
@@ -21,27 +25,87 @@ type NATSEmitter interface {
 	Emit(messagesToEmit routingtable.MessagesToEmit) error
 }
 
+// ResizableNATSEmitter is a NATSEmitter whose number of lanes can be changed
+// while it is emitting.
+type ResizableNATSEmitter interface {
+	NATSEmitter
+	ResizeLanes(count int) error
+}
+
 type natsEmitter struct {
 	natsClient         diegonats.NATSClient
-	workPool           *workpool.WorkPool
+	lanesLock          sync.RWMutex
+	lanes              []*workpool.WorkPool
 	logger             lager.Logger
 	metronClient       loggingclient.IngressClient
 	emitInternalRoutes bool
+	retrier            *PublishRetrier
 }
 
-func NewNATSEmitter(natsClient diegonats.NATSClient, workPool *workpool.WorkPool, logger lager.Logger, metronClient loggingclient.IngressClient, emitInternalRoutes bool) NATSEmitter {
-	return &natsEmitter{
+// NewEmitLanes creates the given number of single worker pools. Messages are
+// published in order within a lane, see NewNATSEmitter.
+func NewEmitLanes(count int) ([]*workpool.WorkPool, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("invalid number of emit lanes: %d", count)
+	}
+
+	lanes := make([]*workpool.WorkPool, count)
+	for i := range lanes {
+		lane, err := workpool.NewWorkPool(1)
+		if err != nil {
+			return nil, err
+		}
+		lanes[i] = lane
+	}
+	return lanes, nil
+}
+
+// NewNATSEmitter returns an emitter that publishes messages for the same
+// host and port on the same lane, so that a register and an unregister for
+// an endpoint are never reordered. Lanes must have a single worker each.
+// Failed publishes are handed to the retrier, when one is given.
+func NewNATSEmitter(natsClient diegonats.NATSClient, lanes []*workpool.WorkPool, logger lager.Logger, metronClient loggingclient.IngressClient, emitInternalRoutes bool, retrier *PublishRetrier) ResizableNATSEmitter {
+	n := &natsEmitter{
 		natsClient:         natsClient,
-		workPool:           workPool,
+		lanes:              lanes,
 		logger:             logger.Session("nats-emitter"),
 		metronClient:       metronClient,
 		emitInternalRoutes: emitInternalRoutes,
+		retrier:            retrier,
 	}
+	if retrier != nil {
+		retrier.setLanes(n.runOnLanes)
+	}
+	return n
+}
+
+// ResizeLanes replaces the lanes with count new ones. It waits for the
+// messages that are being emitted, so that a message for an endpoint is never
+// published on a new lane before an earlier one on the old lane.
+func (n *natsEmitter) ResizeLanes(count int) error {
+	lanes, err := NewEmitLanes(count)
+	if err != nil {
+		return err
+	}
+
+	n.lanesLock.Lock()
+	oldLanes := n.lanes
+	n.lanes = lanes
+	n.lanesLock.Unlock()
+
+	for _, lane := range oldLanes {
+		lane.Stop()
+	}
+	n.logger.Info("resized-lanes", lager.Data{"lanes": count})
+	return nil
 }
 
 func (n *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
 	errors := make(chan error, 1)
 	var wg sync.WaitGroup
+
+	// hold the lanes until all messages are published, see ResizeLanes
+	n.lanesLock.RLock()
 	wg.Add(len(messagesToEmit.RegistrationMessages))
 	for _, message := range messagesToEmit.RegistrationMessages {
 		n.emit("router.register", message, &wg, errors)
@@ -71,6 +135,7 @@ func (n *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
 	}
 
 	wg.Wait()
+	n.lanesLock.RUnlock()
 
 	select {
 	case finalError := <-errors:
@@ -90,11 +155,62 @@ func (n *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
 		}
 	}
 
+	n.reportLabeledCounts("http", "router.register", messagesToEmit.RegistrationMessages)
+	n.reportLabeledCounts("http", "router.unregister", messagesToEmit.UnregistrationMessages)
+	if n.emitInternalRoutes {
+		n.reportLabeledCounts("internal", "service-discovery.register", messagesToEmit.InternalRegistrationMessages)
+		n.reportLabeledCounts("internal", "service-discovery.unregister", messagesToEmit.InternalUnregistrationMessages)
+	}
+
 	return nil
 }
 
+// reportLabeledCounts breaks the emitted messages down by subject and
+// isolation segment for clients that support labels.
+func (n *natsEmitter) reportLabeledCounts(routerType, subject string, messages []routingtable.RegistryMessage) {
+	countsBySegment := map[string]uint64{}
+	for _, message := range messages {
+		countsBySegment[message.IsolationSegment]++
+	}
+
+	for isolationSegment, count := range countsBySegment {
+		metrics.IncrementLabeledCounter(n.metronClient, natsMessagesEmittedCounter, count, metrics.Labels{
+			RouterType:       routerType,
+			Subject:          subject,
+			IsolationSegment: isolationSegment,
+		})
+	}
+}
+
+// runOnLanes runs work for each of the messages on the lane of its route and
+// waits for it, so that a retry is published in order with the other
+// messages for the route.
+func (n *natsEmitter) runOnLanes(messages []routingtable.RegistryMessage, work func(i int)) {
+	var wg sync.WaitGroup
+
+	// hold the lanes until the work is done, see ResizeLanes
+	n.lanesLock.RLock()
+	defer n.lanesLock.RUnlock()
+
+	wg.Add(len(messages))
+	for i, message := range messages {
+		i := i
+		n.lane(message).Submit(func() {
+			defer wg.Done()
+			work(i)
+		})
+	}
+	wg.Wait()
+}
+
+func (n *natsEmitter) lane(message routingtable.RegistryMessage) *workpool.WorkPool {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%s:%d", message.Host, message.Port)
+	return n.lanes[hash.Sum32()%uint32(len(n.lanes))]
+}
+
 func (n *natsEmitter) emit(subject string, message routingtable.RegistryMessage, wg *sync.WaitGroup, errors chan error) {
-	n.workPool.Submit(func() {
+	n.lane(message).Submit(func() {
 		var err error
 		defer func() {
 			if err != nil {
@@ -125,6 +241,14 @@ func (n *natsEmitter) emit(subject string, message routingtable.RegistryMessage,
 				"message": message,
 				"subject": subject,
 			})
+			if n.retrier != nil {
+				n.retrier.Failed(subject, message, err)
+			}
+			return
+		}
+
+		if n.retrier != nil {
+			n.retrier.Published(subject, message)
 		}
 	})
 }
@@ -1,19 +1,27 @@
 package emitter_test
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/lager/v3/lagertest"
 	"code.cloudfoundry.org/route-emitter/diegonats"
 	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
-	"code.cloudfoundry.org/workpool"
 	"github.com/nats-io/nats.go"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
 )
 
 var _ = Describe("NatsEmitter", func() {
@@ -44,10 +52,23 @@ var _ = Describe("NatsEmitter", func() {
 	BeforeEach(func() {
 		natsClient = diegonats.NewFakeClient()
 		logger = lagertest.NewTestLogger("test")
-		workPool, err := workpool.NewWorkPool(1)
+		lanes, err := emitter.NewEmitLanes(1)
 		Expect(err).NotTo(HaveOccurred())
 		fakeMetronClient = &mfakes.FakeIngressClient{}
-		natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetronClient, true)
+		natsEmitter = emitter.NewNATSEmitter(natsClient, lanes, logger, fakeMetronClient, true, nil)
+	})
+
+	Describe("NewEmitLanes", func() {
+		It("creates the requested number of lanes", func() {
+			lanes, err := emitter.NewEmitLanes(3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lanes).To(HaveLen(3))
+		})
+
+		It("errors when no lanes are requested", func() {
+			_, err := emitter.NewEmitLanes(0)
+			Expect(err).To(HaveOccurred())
+		})
 	})
 
 	Describe("Emitting", func() {
@@ -158,9 +179,9 @@ var _ = Describe("NatsEmitter", func() {
 		Context("when the nats emitter is configured to not emit internal routes", func() {
 			BeforeEach(func() {
 				logger := lagertest.NewTestLogger("test")
-				workPool, err := workpool.NewWorkPool(1)
+				lanes, err := emitter.NewEmitLanes(1)
 				Expect(err).NotTo(HaveOccurred())
-				natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetronClient, false)
+				natsEmitter = emitter.NewNATSEmitter(natsClient, lanes, logger, fakeMetronClient, false, nil)
 			})
 
 			It("only emits http routes", func() {
@@ -232,6 +253,296 @@ var _ = Describe("NatsEmitter", func() {
 			It("should error", func() {
 				Expect(natsEmitter.Emit(messagesToEmit)).To(MatchError(errors.New("bam")))
 			})
+
+			Context("when a retrier is configured", func() {
+				var retrier *emitter.PublishRetrier
+
+				BeforeEach(func() {
+					policy := emitter.RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Second, MaxAttempts: 1}
+					retrier = emitter.NewPublishRetrier(logger, fakeclock.NewFakeClock(time.Now()), natsClient, fakeMetronClient, policy, 10)
+					lanes, err := emitter.NewEmitLanes(1)
+					Expect(err).NotTo(HaveOccurred())
+					natsEmitter = emitter.NewNATSEmitter(natsClient, lanes, logger, fakeMetronClient, true, retrier)
+				})
+
+				It("hands the failed messages to the retrier", func() {
+					Expect(natsEmitter.Emit(messagesToEmit)).To(HaveOccurred())
+
+					subjects := []string{}
+					for _, deadLetter := range retrier.DeadLetters() {
+						subjects = append(subjects, deadLetter.Subject)
+					}
+					Expect(subjects).To(Equal([]string{"router.register", "router.register"}))
+				})
+			})
+		})
+
+		Context("when a failed publish is retried", func() {
+			var (
+				clock       *fakeclock.FakeClock
+				retrier     *emitter.PublishRetrier
+				process     ifrit.Process
+				registered  chan struct{}
+				unblockLane chan struct{}
+			)
+
+			BeforeEach(func() {
+				clock = fakeclock.NewFakeClock(time.Now())
+				policy := emitter.RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Second, MaxAttempts: 3}
+				retrier = emitter.NewPublishRetrier(logger, clock, natsClient, fakeMetronClient, policy, 10)
+				lanes, err := emitter.NewEmitLanes(1)
+				Expect(err).NotTo(HaveOccurred())
+				natsEmitter = emitter.NewNATSEmitter(natsClient, lanes, logger, fakeMetronClient, true, retrier)
+				process = ifrit.Invoke(retrier)
+
+				registered = make(chan struct{}, 10)
+				failed := false
+				natsClient.WhenPublishing("router.register", func(*nats.Msg) error {
+					if !failed {
+						failed = true
+						return errors.New("bam")
+					}
+					registered <- struct{}{}
+					return nil
+				})
+
+				unblockLane = make(chan struct{})
+				natsClient.WhenPublishing("router.unregister", func(*nats.Msg) error {
+					<-unblockLane
+					return nil
+				})
+			})
+
+			AfterEach(func() {
+				ifrit.Interrupt(process)
+				Eventually(process.Wait()).Should(Receive(BeNil()))
+			})
+
+			It("publishes the retry on the lane of its route", func() {
+				message := routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 11}
+				Expect(natsEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{message}})).To(HaveOccurred())
+
+				go natsEmitter.Emit(routingtable.MessagesToEmit{UnregistrationMessages: []routingtable.RegistryMessage{
+					{URIs: []string{"bar.com"}, Host: "2.2.2.2", Port: 22},
+				}})
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				clock.Increment(time.Second)
+				Consistently(registered).ShouldNot(Receive())
+
+				close(unblockLane)
+				Eventually(registered).Should(Receive())
+			})
+		})
+
+		Context("when emitting on multiple lanes", func() {
+			type publishedMessage struct {
+				subject string
+				message routingtable.RegistryMessage
+			}
+
+			var (
+				mutex     sync.Mutex
+				published []publishedMessage
+			)
+
+			recordPublish := func(msg *nats.Msg) error {
+				defer GinkgoRecover()
+
+				var message routingtable.RegistryMessage
+				Expect(json.Unmarshal(msg.Data, &message)).To(Succeed())
+
+				// stagger the publishes so that messages on different lanes
+				// overtake each other
+				time.Sleep(time.Duration(message.Port%3) * time.Millisecond)
+
+				mutex.Lock()
+				published = append(published, publishedMessage{subject: msg.Subject, message: message})
+				mutex.Unlock()
+				return nil
+			}
+
+			publishedFor := func(host string, port uint32) []string {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				subjects := []string{}
+				for _, p := range published {
+					if p.message.Host == host && p.message.Port == port {
+						subjects = append(subjects, p.subject)
+					}
+				}
+				return subjects
+			}
+
+			BeforeEach(func() {
+				published = nil
+				natsClient.WhenPublishing("router.register", recordPublish)
+				natsClient.WhenPublishing("router.unregister", recordPublish)
+
+				lanes, err := emitter.NewEmitLanes(8)
+				Expect(err).NotTo(HaveOccurred())
+				natsEmitter = emitter.NewNATSEmitter(natsClient, lanes, logger, fakeMetronClient, false, nil)
+			})
+
+			It("publishes the messages for an endpoint in order", func() {
+				messages := routingtable.MessagesToEmit{}
+				for i := 0; i < 50; i++ {
+					message := routingtable.RegistryMessage{
+						URIs: []string{fmt.Sprintf("app-%d.com", i)},
+						Host: fmt.Sprintf("10.0.0.%d", i),
+						Port: uint32(61000 + i),
+					}
+					messages.RegistrationMessages = append(messages.RegistrationMessages, message)
+					messages.UnregistrationMessages = append(messages.UnregistrationMessages, message)
+				}
+
+				Expect(natsEmitter.Emit(messages)).To(Succeed())
+				Expect(natsClient.PublishedMessages("router.register")).To(HaveLen(50))
+				Expect(natsClient.PublishedMessages("router.unregister")).To(HaveLen(50))
+
+				for i := 0; i < 50; i++ {
+					Expect(publishedFor(fmt.Sprintf("10.0.0.%d", i), uint32(61000+i))).To(Equal([]string{"router.register", "router.unregister"}))
+				}
+			})
+
+			It("does not let a concurrent emit overtake a pending message for the same endpoint", func() {
+				unregistration := routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 11}
+				registration := routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 11}
+
+				publishing := make(chan struct{})
+				unblock := make(chan struct{})
+				natsClient.WhenPublishing("router.unregister", func(msg *nats.Msg) error {
+					close(publishing)
+					<-unblock
+					return recordPublish(msg)
+				})
+
+				unregistered := make(chan error)
+				go func() {
+					unregistered <- natsEmitter.Emit(routingtable.MessagesToEmit{UnregistrationMessages: []routingtable.RegistryMessage{unregistration}})
+				}()
+				Eventually(publishing).Should(BeClosed())
+
+				registered := make(chan error)
+				go func() {
+					registered <- natsEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{registration}})
+				}()
+
+				Consistently(registered).ShouldNot(Receive())
+				close(unblock)
+
+				Eventually(unregistered).Should(Receive(BeNil()))
+				Eventually(registered).Should(Receive(BeNil()))
+				Expect(publishedFor("1.1.1.1", 11)).To(Equal([]string{"router.unregister", "router.register"}))
+			})
+
+			It("keeps emitting for other endpoints while a lane is busy", func() {
+				unblock := make(chan struct{})
+				defer close(unblock)
+				natsClient.WhenPublishing("router.unregister", func(msg *nats.Msg) error {
+					<-unblock
+					return nil
+				})
+
+				go natsEmitter.Emit(routingtable.MessagesToEmit{
+					UnregistrationMessages: []routingtable.RegistryMessage{{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 11}},
+				})
+
+				emitted := make(chan error, 20)
+				for i := 0; i < 20; i++ {
+					message := routingtable.RegistryMessage{URIs: []string{"bar.com"}, Host: fmt.Sprintf("10.0.1.%d", i), Port: 11}
+					go func() {
+						emitted <- natsEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{message}})
+					}()
+				}
+
+				Eventually(emitted).Should(Receive(BeNil()))
+			})
+
+			Context("when the lanes are resized", func() {
+				var resizableEmitter emitter.ResizableNATSEmitter
+
+				BeforeEach(func() {
+					lanes, err := emitter.NewEmitLanes(8)
+					Expect(err).NotTo(HaveOccurred())
+					resizableEmitter = emitter.NewNATSEmitter(natsClient, lanes, logger, fakeMetronClient, false, nil)
+					natsEmitter = resizableEmitter
+				})
+
+				It("keeps publishing on the new lanes", func() {
+					Expect(resizableEmitter.ResizeLanes(2)).To(Succeed())
+					Expect(logger).To(gbytes.Say(`resized-lanes.*"lanes":2`))
+
+					Expect(natsEmitter.Emit(messagesToEmit)).To(Succeed())
+					Expect(natsClient.PublishedMessages("router.register")).To(HaveLen(2))
+					Expect(natsClient.PublishedMessages("router.unregister")).To(HaveLen(2))
+				})
+
+				It("waits for the messages on the old lanes to be published", func() {
+					publishing := make(chan struct{})
+					unblock := make(chan struct{})
+					natsClient.WhenPublishing("router.unregister", func(msg *nats.Msg) error {
+						close(publishing)
+						<-unblock
+						return recordPublish(msg)
+					})
+
+					unregistration := routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 11}
+					unregistered := make(chan error)
+					go func() {
+						unregistered <- natsEmitter.Emit(routingtable.MessagesToEmit{UnregistrationMessages: []routingtable.RegistryMessage{unregistration}})
+					}()
+					Eventually(publishing).Should(BeClosed())
+
+					resized := make(chan error)
+					go func() {
+						resized <- resizableEmitter.ResizeLanes(3)
+					}()
+					Consistently(resized).ShouldNot(Receive())
+
+					close(unblock)
+					Eventually(unregistered).Should(Receive(BeNil()))
+					Eventually(resized).Should(Receive(BeNil()))
+
+					Expect(natsEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{unregistration}})).To(Succeed())
+					Expect(publishedFor("1.1.1.1", 11)).To(Equal([]string{"router.unregister", "router.register"}))
+				})
+
+				It("errors when no lanes are requested", func() {
+					Expect(resizableEmitter.ResizeLanes(0)).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("when the metron client reports labeled metrics", func() {
+			var prometheusClient *metrics.PrometheusClient
+
+			BeforeEach(func() {
+				prometheusClient = metrics.NewPrometheusClient(fakeMetronClient)
+				lanes, err := emitter.NewEmitLanes(1)
+				Expect(err).NotTo(HaveOccurred())
+				natsEmitter = emitter.NewNATSEmitter(natsClient, lanes, logger, prometheusClient, true, nil)
+			})
+
+			It("breaks the emitted messages down by subject and isolation segment", func() {
+				err := natsEmitter.Emit(routingtable.MessagesToEmit{
+					RegistrationMessages: []routingtable.RegistryMessage{
+						{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 11, IsolationSegment: "iso-seg"},
+						{URIs: []string{"bar.com"}, Host: "2.2.2.2", Port: 22},
+					},
+					InternalUnregistrationMessages: []routingtable.RegistryMessage{
+						{URIs: []string{"internal-foo.com"}, Host: "1.2.1.1", Port: 11},
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				recorder := httptest.NewRecorder()
+				prometheusClient.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+				body := recorder.Body.String()
+				Expect(body).To(ContainSubstring(`route_emitter_nats_messages_emitted_total{isolation_segment="iso-seg",router_type="http",subject="router.register"} 1`))
+				Expect(body).To(ContainSubstring(`route_emitter_nats_messages_emitted_total{isolation_segment="",router_type="http",subject="router.register"} 1`))
+				Expect(body).To(ContainSubstring(`route_emitter_nats_messages_emitted_total{isolation_segment="",router_type="internal",subject="service-discovery.unregister"} 1`))
+			})
 		})
 
 		Context("when the metron client errors", func() {
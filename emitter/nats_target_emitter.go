@@ -0,0 +1,126 @@
+package emitter
+
+import (
+	"strings"
+
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+const (
+	natsTargetMessagesEmittedCounter = "NATSTargetMessagesEmitted"
+	natsTargetEmitFailuresCounter    = "NATSTargetEmitFailures"
+)
+
+// NATSTargetFilter selects the routes that are emitted to a NATS target.
+type NATSTargetFilter struct {
+	HTTP     bool
+	Internal bool
+	// Domains limits the http routes to the hostnames in these domains, every
+	// hostname passes when it is empty.
+	Domains []string
+}
+
+func (f NATSTargetFilter) apply(messagesToEmit routingtable.MessagesToEmit) routingtable.MessagesToEmit {
+	filtered := routingtable.MessagesToEmit{}
+	if f.HTTP {
+		filtered.RegistrationMessages = f.filterDomains(messagesToEmit.RegistrationMessages)
+		filtered.UnregistrationMessages = f.filterDomains(messagesToEmit.UnregistrationMessages)
+	}
+	if f.Internal {
+		filtered.InternalRegistrationMessages = messagesToEmit.InternalRegistrationMessages
+		filtered.InternalUnregistrationMessages = messagesToEmit.InternalUnregistrationMessages
+	}
+	return filtered
+}
+
+func (f NATSTargetFilter) filterDomains(messages []routingtable.RegistryMessage) []routingtable.RegistryMessage {
+	if len(f.Domains) == 0 {
+		return messages
+	}
+
+	var filtered []routingtable.RegistryMessage
+	for _, message := range messages {
+		uris := []string{}
+		for _, uri := range message.URIs {
+			if f.inDomains(uri) {
+				uris = append(uris, uri)
+			}
+		}
+		if len(uris) == 0 {
+			continue
+		}
+		message.URIs = uris
+		filtered = append(filtered, message)
+	}
+	return filtered
+}
+
+func (f NATSTargetFilter) inDomains(uri string) bool {
+	hostname := strings.SplitN(uri, "/", 2)[0]
+	for _, domain := range f.Domains {
+		if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+type natsTargetEmitter struct {
+	logger       lager.Logger
+	name         string
+	delegate     NATSEmitter
+	metronClient loggingclient.IngressClient
+	filter       NATSTargetFilter
+}
+
+// NewNATSTargetEmitter returns an emitter for an additional NATS target. It
+// only passes the routes selected by the filter on to the delegate and
+// reports the emitted messages and failures, labeled with the target name
+// where the metron client supports labels.
+// Failures are not returned, so that a failing target does not affect the
+// emission to the other targets.
+func NewNATSTargetEmitter(
+	logger lager.Logger,
+	name string,
+	delegate NATSEmitter,
+	metronClient loggingclient.IngressClient,
+	filter NATSTargetFilter,
+) NATSEmitter {
+	return &natsTargetEmitter{
+		logger:       logger.Session("nats-target-emitter", lager.Data{"target": name}),
+		name:         name,
+		delegate:     delegate,
+		metronClient: metronClient,
+		filter:       filter,
+	}
+}
+
+func (e *natsTargetEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	filtered := e.filter.apply(messagesToEmit)
+	count := len(filtered.RegistrationMessages) + len(filtered.UnregistrationMessages) +
+		len(filtered.InternalRegistrationMessages) + len(filtered.InternalUnregistrationMessages)
+	if count == 0 {
+		return nil
+	}
+
+	labels := metrics.Labels{Target: e.name}
+	err := e.delegate.Emit(filtered)
+	if err != nil {
+		e.logger.Error("failed-to-emit", err)
+		e.incrementCounter(natsTargetEmitFailuresCounter, 1, labels)
+		return nil
+	}
+
+	e.incrementCounter(natsTargetMessagesEmittedCounter, uint64(count), labels)
+	return nil
+}
+
+func (e *natsTargetEmitter) incrementCounter(name string, delta uint64, labels metrics.Labels) {
+	err := metrics.IncrementCounterWithLabels(e.metronClient, name, delta, labels)
+	if err != nil {
+		e.logger.Error("failed-to-increment-counter", err, lager.Data{"counter": name})
+	}
+}
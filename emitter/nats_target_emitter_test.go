@@ -0,0 +1,121 @@
+package emitter_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NATSTargetEmitter", func() {
+	var (
+		delegate         *fakes.FakeNATSEmitter
+		fakeMetronClient *mfakes.FakeIngressClient
+		prometheusClient *metrics.PrometheusClient
+		filter           emitter.NATSTargetFilter
+		targetEmitter    emitter.NATSEmitter
+		messages         routingtable.MessagesToEmit
+	)
+
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		prometheusClient.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		body, err := io.ReadAll(recorder.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		delegate = &fakes.FakeNATSEmitter{}
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		prometheusClient = metrics.NewPrometheusClient(fakeMetronClient)
+		filter = emitter.NATSTargetFilter{HTTP: true, Internal: true}
+		messages = routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.1", Port: 61000, URIs: []string{"foo.apps.example.com", "foo.example.org/path"}},
+				{Host: "1.1.1.1", Port: 61001, URIs: []string{"bar.example.org"}},
+			},
+			UnregistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.2", Port: 61000, URIs: []string{"apps.example.com"}},
+			},
+			InternalRegistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.1", URIs: []string{"foo.apps.internal"}},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		targetEmitter = emitter.NewNATSTargetEmitter(lagertest.NewTestLogger("test"), "new-cluster", delegate, prometheusClient, filter)
+	})
+
+	It("emits every message to the delegate", func() {
+		Expect(targetEmitter.Emit(messages)).To(Succeed())
+		Expect(delegate.EmitCallCount()).To(Equal(1))
+		Expect(delegate.EmitArgsForCall(0)).To(Equal(messages))
+		Expect(scrape()).To(ContainSubstring(`route_emitter_nats_target_messages_emitted_total{isolation_segment="",nats_target="new-cluster",router_type="",subject=""} 4`))
+	})
+
+	Context("when the target only receives http routes", func() {
+		BeforeEach(func() {
+			filter = emitter.NATSTargetFilter{HTTP: true}
+		})
+
+		It("drops the internal routes", func() {
+			Expect(targetEmitter.Emit(messages)).To(Succeed())
+			emitted := delegate.EmitArgsForCall(0)
+			Expect(emitted.RegistrationMessages).To(Equal(messages.RegistrationMessages))
+			Expect(emitted.InternalRegistrationMessages).To(BeEmpty())
+		})
+
+		It("does not call the delegate without http routes", func() {
+			Expect(targetEmitter.Emit(routingtable.MessagesToEmit{
+				InternalRegistrationMessages: messages.InternalRegistrationMessages,
+			})).To(Succeed())
+			Expect(delegate.EmitCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the target is limited to domains", func() {
+		BeforeEach(func() {
+			filter = emitter.NATSTargetFilter{HTTP: true, Domains: []string{"apps.example.com"}}
+		})
+
+		It("only emits the hostnames in the domains", func() {
+			Expect(targetEmitter.Emit(messages)).To(Succeed())
+			emitted := delegate.EmitArgsForCall(0)
+			Expect(emitted.RegistrationMessages).To(Equal([]routingtable.RegistryMessage{
+				{Host: "1.1.1.1", Port: 61000, URIs: []string{"foo.apps.example.com"}},
+			}))
+			Expect(emitted.UnregistrationMessages).To(Equal(messages.UnregistrationMessages))
+		})
+
+		It("does not change the messages of the other targets", func() {
+			Expect(targetEmitter.Emit(messages)).To(Succeed())
+			Expect(messages.RegistrationMessages[0].URIs).To(HaveLen(2))
+		})
+	})
+
+	Context("when the delegate fails", func() {
+		BeforeEach(func() {
+			delegate.EmitReturns(errors.New("boom"))
+		})
+
+		It("counts the failure without returning it", func() {
+			Expect(targetEmitter.Emit(messages)).To(Succeed())
+			Expect(scrape()).To(ContainSubstring(`route_emitter_nats_target_emit_failures_total{isolation_segment="",nats_target="new-cluster",router_type="",subject=""} 1`))
+
+			name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+			Expect(name).To(Equal("NATSTargetEmitFailures"))
+			Expect(delta).To(BeEquivalentTo(1))
+		})
+	})
+})
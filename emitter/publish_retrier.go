@@ -0,0 +1,331 @@
+package emitter
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+const (
+	natsPublishRetriesCounter      = "NATSPublishRetries"
+	natsPublishDeadLetteredCounter = "NATSPublishDeadLettered"
+	natsPublishRetryQueueMetric    = "NATSPublishRetryQueueDepth"
+)
+
+// RetryPolicy configures how often a failed publish is retried. The backoff
+// doubles with every attempt up to MaxBackoff, MaxAttempts includes the
+// publish that failed first. At most MaxPending retries wait at once, further
+// failures are dead lettered right away. Zero means no limit.
+type RetryPolicy struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxAttempts    int
+	MaxPending     int
+}
+
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempts && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// DeadLetter is a message that could not be published within the configured
+// number of attempts.
+type DeadLetter struct {
+	Subject   string                       `json:"subject"`
+	Message   routingtable.RegistryMessage `json:"message"`
+	Attempts  int                          `json:"attempts"`
+	LastError string                       `json:"last_error"`
+	FailedAt  time.Time                    `json:"failed_at"`
+}
+
+type pendingPublish struct {
+	subject   string
+	message   routingtable.RegistryMessage
+	attempts  int
+	nextRetry time.Time
+}
+
+// laneRunner runs work for each of the messages on the lane the nats emitter
+// publishes the route of the message on, and waits for all of it.
+type laneRunner func(messages []routingtable.RegistryMessage, work func(i int))
+
+// PublishRetrier republishes the messages the nats emitter failed to publish
+// with exponential backoff. A pending retry is dropped as soon as a newer
+// message for the same route is published, so that a retried registration
+// never overtakes a later unregistration. Retries are published on the lane
+// of their route once the retrier is given to NewNATSEmitter.
+type PublishRetrier struct {
+	logger             lager.Logger
+	clock              clock.Clock
+	natsClient         diegonats.NATSClient
+	metronClient       loggingclient.IngressClient
+	policy             RetryPolicy
+	deadLetterCapacity int
+
+	lock        sync.Mutex
+	lanes       laneRunner
+	pending     map[string]*pendingPublish
+	deadLetters []DeadLetter
+	notify      chan struct{}
+}
+
+func NewPublishRetrier(
+	logger lager.Logger,
+	clock clock.Clock,
+	natsClient diegonats.NATSClient,
+	metronClient loggingclient.IngressClient,
+	policy RetryPolicy,
+	deadLetterCapacity int,
+) *PublishRetrier {
+	return &PublishRetrier{
+		logger:             logger.Session("publish-retrier"),
+		clock:              clock,
+		natsClient:         natsClient,
+		metronClient:       metronClient,
+		policy:             policy,
+		deadLetterCapacity: deadLetterCapacity,
+		pending:            map[string]*pendingPublish{},
+		notify:             make(chan struct{}, 1),
+	}
+}
+
+// Failed schedules a retry of a message whose first publish failed. It
+// replaces any pending retry for the same route. When MaxPending retries are
+// already waiting, the message is dead lettered instead.
+func (r *PublishRetrier) Failed(subject string, message routingtable.RegistryMessage, err error) {
+	publish := &pendingPublish{subject: subject, message: message, attempts: 1}
+	if r.policy.MaxAttempts <= 1 {
+		r.deadLetter(publish, err)
+		return
+	}
+
+	publish.nextRetry = r.clock.Now().Add(r.policy.backoff(1))
+	key := publishKey(subject, message)
+	r.lock.Lock()
+	_, replacing := r.pending[key]
+	if !replacing && r.policy.MaxPending > 0 && len(r.pending) >= r.policy.MaxPending {
+		r.lock.Unlock()
+		r.deadLetter(publish, err)
+		return
+	}
+	r.pending[key] = publish
+	depth := len(r.pending)
+	r.lock.Unlock()
+
+	r.sendDepth(depth)
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Published drops the pending retry for the route of a message that was
+// published successfully.
+func (r *PublishRetrier) Published(subject string, message routingtable.RegistryMessage) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.pending, publishKey(subject, message))
+}
+
+func (r *PublishRetrier) setLanes(lanes laneRunner) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.lanes = lanes
+}
+
+// DeadLetters returns the most recent messages that were given up on, oldest
+// first.
+func (r *PublishRetrier) DeadLetters() []DeadLetter {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]DeadLetter{}, r.deadLetters...)
+}
+
+func (r *PublishRetrier) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	r.logger.Info("starting")
+	close(ready)
+	defer r.logger.Info("exiting")
+
+	for {
+		var timer clock.Timer
+		var timerCh <-chan time.Time
+		if wait, ok := r.nextWait(); ok {
+			timer = r.clock.NewTimer(wait)
+			timerCh = timer.C()
+		}
+
+		select {
+		case <-signals:
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+
+		case <-r.notify:
+
+		case <-timerCh:
+			r.retryDue()
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (r *PublishRetrier) nextWait() (time.Duration, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.pending) == 0 {
+		return 0, false
+	}
+
+	var next time.Time
+	for _, publish := range r.pending {
+		if next.IsZero() || publish.nextRetry.Before(next) {
+			next = publish.nextRetry
+		}
+	}
+
+	wait := next.Sub(r.clock.Now())
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+func (r *PublishRetrier) retryDue() {
+	now := r.clock.Now()
+
+	r.lock.Lock()
+	keys := []string{}
+	due := []*pendingPublish{}
+	messages := []routingtable.RegistryMessage{}
+	for key, publish := range r.pending {
+		if !publish.nextRetry.After(now) {
+			keys = append(keys, key)
+			due = append(due, publish)
+			messages = append(messages, publish.message)
+		}
+	}
+	lanes := r.lanes
+	r.lock.Unlock()
+
+	retry := func(i int) {
+		r.retry(keys[i], due[i])
+	}
+	if lanes != nil {
+		lanes(messages, retry)
+	} else {
+		for i := range due {
+			retry(i)
+		}
+	}
+
+	r.lock.Lock()
+	depth := len(r.pending)
+	r.lock.Unlock()
+	r.sendDepth(depth)
+}
+
+// retry claims the pending retry and publishes it, unless a newer message for
+// the route was published or failed since it was found due.
+func (r *PublishRetrier) retry(key string, publish *pendingPublish) {
+	r.lock.Lock()
+	if r.pending[key] != publish {
+		r.lock.Unlock()
+		return
+	}
+	delete(r.pending, key)
+	r.lock.Unlock()
+
+	r.incrementCounter(natsPublishRetriesCounter)
+	err := r.publish(publish)
+	publish.attempts++
+	if err == nil {
+		r.logger.Info("retried-publish", lager.Data{"subject": publish.subject, "attempts": publish.attempts})
+		return
+	}
+	if publish.attempts >= r.policy.MaxAttempts {
+		r.deadLetter(publish, err)
+		return
+	}
+
+	r.lock.Lock()
+	// a newer message for the route failed meanwhile, its retry replaces this one
+	if _, ok := r.pending[key]; !ok {
+		publish.nextRetry = r.clock.Now().Add(r.policy.backoff(publish.attempts))
+		r.pending[key] = publish
+	}
+	r.lock.Unlock()
+}
+
+func (r *PublishRetrier) publish(publish *pendingPublish) error {
+	payload, err := json.Marshal(publish.message)
+	if err != nil {
+		return err
+	}
+	return r.natsClient.Publish(publish.subject, payload)
+}
+
+func (r *PublishRetrier) deadLetter(publish *pendingPublish, err error) {
+	r.logger.Error("giving-up-on-publish", err, lager.Data{
+		"subject":  publish.subject,
+		"message":  publish.message,
+		"attempts": publish.attempts,
+	})
+
+	r.lock.Lock()
+	r.deadLetters = append(r.deadLetters, DeadLetter{
+		Subject:   publish.subject,
+		Message:   publish.message,
+		Attempts:  publish.attempts,
+		LastError: err.Error(),
+		FailedAt:  r.clock.Now(),
+	})
+	if overflow := len(r.deadLetters) - r.deadLetterCapacity; overflow > 0 {
+		r.deadLetters = r.deadLetters[overflow:]
+	}
+	r.lock.Unlock()
+
+	r.incrementCounter(natsPublishDeadLetteredCounter)
+}
+
+func (r *PublishRetrier) sendDepth(depth int) {
+	err := r.metronClient.SendMetric(natsPublishRetryQueueMetric, depth)
+	if err != nil {
+		r.logger.Error("failed-to-send-retry-queue-depth-metric", err)
+	}
+}
+
+func (r *PublishRetrier) incrementCounter(name string) {
+	err := r.metronClient.IncrementCounter(name)
+	if err != nil {
+		r.logger.Error("failed-to-increment-counter", err, lager.Data{"counter": name})
+	}
+}
+
+// publishKey identifies the route a message is about, registrations and
+// unregistrations of the same route share a key.
+func publishKey(subject string, message routingtable.RegistryMessage) string {
+	routeType := "external"
+	if strings.HasPrefix(subject, "service-discovery.") {
+		routeType = "internal"
+	}
+	return registryMessageKey(routeType, message)
+}
@@ -0,0 +1,175 @@
+package emitter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("PublishRetrier", func() {
+	var (
+		clock            *fakeclock.FakeClock
+		natsClient       *diegonats.FakeNATSClient
+		fakeMetronClient *mfakes.FakeIngressClient
+		policy           emitter.RetryPolicy
+		retrier          *emitter.PublishRetrier
+		process          ifrit.Process
+
+		attempts     int32
+		failures     int32
+		message      routingtable.RegistryMessage
+		publishError error
+	)
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		natsClient = diegonats.NewFakeClient()
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		policy = emitter.RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 4 * time.Second, MaxAttempts: 3}
+
+		atomic.StoreInt32(&attempts, 0)
+		atomic.StoreInt32(&failures, 100)
+		publishError = errors.New("nats down")
+		natsClient.WhenPublishing("router.register", func(*nats.Msg) error {
+			if atomic.AddInt32(&attempts, 1) <= atomic.LoadInt32(&failures) {
+				return publishError
+			}
+			return nil
+		})
+
+		message = routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 61000}
+	})
+
+	JustBeforeEach(func() {
+		retrier = emitter.NewPublishRetrier(lagertest.NewTestLogger("test"), clock, natsClient, fakeMetronClient, policy, 2)
+		process = ifrit.Invoke(retrier)
+	})
+
+	AfterEach(func() {
+		ifrit.Interrupt(process)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	retryAttempts := func() int32 {
+		return atomic.LoadInt32(&attempts)
+	}
+
+	Context("when the retry succeeds", func() {
+		BeforeEach(func() {
+			atomic.StoreInt32(&failures, 0)
+		})
+
+		It("republishes the message after the initial backoff", func() {
+			retrier.Failed("router.register", message, publishError)
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			clock.Increment(999 * time.Millisecond)
+			Consistently(retryAttempts).Should(BeEquivalentTo(0))
+
+			clock.Increment(time.Millisecond)
+			Eventually(retryAttempts).Should(BeEquivalentTo(1))
+
+			published := natsClient.PublishedMessages("router.register")
+			Expect(published).To(HaveLen(1))
+			var republished routingtable.RegistryMessage
+			Expect(json.Unmarshal(published[0].Data, &republished)).To(Succeed())
+			Expect(republished).To(Equal(message))
+
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("NATSPublishRetries"))
+			Expect(retrier.DeadLetters()).To(BeEmpty())
+		})
+	})
+
+	Context("when the retries keep failing", func() {
+		It("backs off exponentially and dead letters the message", func() {
+			retrier.Failed("router.register", message, publishError)
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(time.Second)
+			Eventually(retryAttempts).Should(BeEquivalentTo(1))
+
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(time.Second)
+			Consistently(retryAttempts).Should(BeEquivalentTo(1))
+			clock.Increment(time.Second)
+			Eventually(retryAttempts).Should(BeEquivalentTo(2))
+
+			Eventually(retrier.DeadLetters).Should(HaveLen(1))
+			deadLetter := retrier.DeadLetters()[0]
+			Expect(deadLetter.Subject).To(Equal("router.register"))
+			Expect(deadLetter.Message).To(Equal(message))
+			Expect(deadLetter.Attempts).To(Equal(3))
+			Expect(deadLetter.LastError).To(Equal("nats down"))
+
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(fakeMetronClient.IncrementCounterCallCount() - 1)).To(Equal("NATSPublishDeadLettered"))
+
+			clock.Increment(time.Minute)
+			Consistently(retryAttempts).Should(BeEquivalentTo(2))
+		})
+
+		It("only keeps the most recent dead letters", func() {
+			for i := 0; i < 3; i++ {
+				message.Port = uint32(61000 + i)
+				retrier.Failed("router.register", message, publishError)
+			}
+
+			Eventually(func() int {
+				clock.Increment(time.Second)
+				return len(retrier.DeadLetters())
+			}).Should(Equal(2))
+			Consistently(retrier.DeadLetters).Should(HaveLen(2))
+		})
+	})
+
+	Context("when a newer message for the route is published", func() {
+		It("drops the pending retry", func() {
+			retrier.Failed("router.register", message, publishError)
+			retrier.Published("router.unregister", message)
+
+			clock.Increment(time.Minute)
+			Consistently(retryAttempts).Should(BeEquivalentTo(0))
+		})
+	})
+
+	Context("when too many retries are pending", func() {
+		BeforeEach(func() {
+			policy.MaxPending = 1
+		})
+
+		It("dead letters the overflow", func() {
+			retrier.Failed("router.register", message, publishError)
+			retrier.Failed("router.register", message, publishError)
+			Expect(retrier.DeadLetters()).To(BeEmpty())
+
+			overflow := message
+			overflow.Port = 61001
+			retrier.Failed("router.register", overflow, publishError)
+			Expect(retrier.DeadLetters()).To(HaveLen(1))
+			Expect(retrier.DeadLetters()[0].Message).To(Equal(overflow))
+		})
+	})
+
+	Context("when retries are disabled", func() {
+		BeforeEach(func() {
+			policy.MaxAttempts = 1
+		})
+
+		It("dead letters the message right away", func() {
+			retrier.Failed("router.register", message, publishError)
+			Expect(retrier.DeadLetters()).To(HaveLen(1))
+			Expect(retrier.DeadLetters()[0].Attempts).To(Equal(1))
+		})
+	})
+})
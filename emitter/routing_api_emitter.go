@@ -47,26 +47,34 @@ func (t *routingAPIEmitter) Emit(tcpEvents routingtable.TCPRouteMappings) error
 }
 
 func (t *routingAPIEmitter) emit(registrationMappingRequests, unregistrationMappingRequests []models.TcpRouteMapping) error {
-	var forceUpdate bool
+	err := withToken(t.uaaTokenFetcher, t.routingAPIClient, func() error {
+		return t.emitRoutingAPI(registrationMappingRequests, unregistrationMappingRequests)
+	})
+	if err != nil {
+		return err
+	}
 
+	t.logger.Debug("successfully-emitted-events")
+	return nil
+}
+
+// withToken authorizes the client with a token from uaa before making the
+// request, and retries the request once with a fresh token if it fails.
+func withToken(uaaTokenFetcher uaaclient.TokenFetcher, routingAPIClient routing_api.Client, request func() error) error {
 	for count := 0; count < 2; count++ {
-		forceUpdate = count > 0
-		token, err := t.uaaTokenFetcher.FetchToken(context.Background(), forceUpdate)
+		forceUpdate := count > 0
+		token, err := uaaTokenFetcher.FetchToken(context.Background(), forceUpdate)
 		if err != nil {
 			return err
 		}
 
-		t.routingAPIClient.SetToken(token.AccessToken)
+		routingAPIClient.SetToken(token.AccessToken)
 
-		err = t.emitRoutingAPI(registrationMappingRequests, unregistrationMappingRequests)
-		if err != nil && count > 0 {
+		err = request()
+		if err == nil || count > 0 {
 			return err
-		} else if err == nil {
-			break
 		}
 	}
-
-	t.logger.Debug("successfully-emitted-events")
 	return nil
 }
 
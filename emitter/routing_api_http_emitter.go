@@ -0,0 +1,99 @@
+package emitter
+
+import (
+	"math"
+
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	routing_api "code.cloudfoundry.org/routing-api"
+	"code.cloudfoundry.org/routing-api/models"
+	"code.cloudfoundry.org/routing-api/uaaclient"
+)
+
+const httpRouteRoutingAPIMessagesEmittedCounter = "HTTPRouteRoutingAPIMessagesEmitted"
+
+type routingAPIHTTPEmitter struct {
+	logger           lager.Logger
+	routingAPIClient routing_api.Client
+	uaaTokenFetcher  uaaclient.TokenFetcher
+	metronClient     loggingclient.IngressClient
+	ttl              int
+}
+
+// NewRoutingAPIHTTPEmitter returns an emitter that writes the external http
+// route registrations and unregistrations to the routing api instead of
+// publishing them over nats. Routes expire after the given ttl in seconds
+// unless they are emitted again. Internal routes are ignored, the routing api
+// does not serve service discovery.
+func NewRoutingAPIHTTPEmitter(
+	logger lager.Logger,
+	routingAPIClient routing_api.Client,
+	uaaTokenFetcher uaaclient.TokenFetcher,
+	metronClient loggingclient.IngressClient,
+	routeTTL int,
+) NATSEmitter {
+	return &routingAPIHTTPEmitter{
+		logger:           logger.Session("routing-api-http-emitter"),
+		routingAPIClient: routingAPIClient,
+		uaaTokenFetcher:  uaaTokenFetcher,
+		metronClient:     metronClient,
+		ttl:              routeTTL,
+	}
+}
+
+func (e *routingAPIHTTPEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	registrations := e.routesFor(messagesToEmit.RegistrationMessages)
+	unregistrations := e.routesFor(messagesToEmit.UnregistrationMessages)
+	if len(registrations) == 0 && len(unregistrations) == 0 {
+		return nil
+	}
+
+	err := withToken(e.uaaTokenFetcher, e.routingAPIClient, func() error {
+		if len(registrations) > 0 {
+			if err := e.routingAPIClient.UpsertRoutes(registrations); err != nil {
+				e.logger.Error("unable-to-upsert", err)
+				return err
+			}
+		}
+		if len(unregistrations) > 0 {
+			if err := e.routingAPIClient.DeleteRoutes(unregistrations); err != nil {
+				e.logger.Error("unable-to-delete", err)
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	e.logger.Debug("successfully-emitted-routes", lager.Data{
+		"number-of-registrations":   len(registrations),
+		"number-of-unregistrations": len(unregistrations),
+	})
+
+	err = e.metronClient.IncrementCounterWithDelta(httpRouteRoutingAPIMessagesEmittedCounter, uint64(len(registrations)+len(unregistrations)))
+	if err != nil {
+		e.logger.Error("cannot-emit-number-of-http-routes", err)
+	}
+	return nil
+}
+
+// routesFor converts the messages to routing api routes, one per uri. The
+// routing api has no notion of tls ports, endpoints without a plain port are
+// skipped.
+func (e *routingAPIHTTPEmitter) routesFor(messages []routingtable.RegistryMessage) []models.Route {
+	routes := []models.Route{}
+	for _, message := range messages {
+		if message.Port == 0 || message.Port > math.MaxUint16 {
+			e.logger.Debug("skipping-endpoint-without-http-port", lager.Data{"message": message})
+			continue
+		}
+
+		for _, uri := range message.URIs {
+			routes = append(routes, models.NewRoute(uri, uint16(message.Port), message.Host, message.App, message.RouteServiceUrl, e.ttl))
+		}
+	}
+	return routes
+}
@@ -0,0 +1,132 @@
+package emitter_test
+
+import (
+	"errors"
+
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-api/fake_routing_api"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+	fakeuaa "code.cloudfoundry.org/routing-api/uaaclient/fakes"
+	"golang.org/x/oauth2"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RoutingAPIHTTPEmitter", func() {
+	var (
+		routingAPIClient *fake_routing_api.FakeClient
+		uaaTokenFetcher  *fakeuaa.FakeTokenFetcher
+		fakeMetronClient *mfakes.FakeIngressClient
+		httpEmitter      emitter.NATSEmitter
+		messages         routingtable.MessagesToEmit
+	)
+
+	BeforeEach(func() {
+		routingAPIClient = new(fake_routing_api.FakeClient)
+		uaaTokenFetcher = &fakeuaa.FakeTokenFetcher{}
+		uaaTokenFetcher.FetchTokenReturns(&oauth2.Token{AccessToken: "accesstoken"}, nil)
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		httpEmitter = emitter.NewRoutingAPIHTTPEmitter(lagertest.NewTestLogger("test"), routingAPIClient, uaaTokenFetcher, fakeMetronClient, 120)
+
+		messages = routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{
+				{URIs: []string{"foo.com", "bar.com"}, Host: "1.1.1.1", Port: 61000, App: "log-guid", RouteServiceUrl: "https://rs.example.com"},
+			},
+			UnregistrationMessages: []routingtable.RegistryMessage{
+				{URIs: []string{"baz.com"}, Host: "2.2.2.2", Port: 61001, App: "other-log-guid"},
+			},
+			InternalRegistrationMessages: []routingtable.RegistryMessage{
+				{URIs: []string{"internal.apps.internal"}, Host: "10.0.0.1", Port: 8080},
+			},
+		}
+	})
+
+	It("upserts the registrations with the route ttl", func() {
+		Expect(httpEmitter.Emit(messages)).To(Succeed())
+		Expect(routingAPIClient.SetTokenCallCount()).To(Equal(1))
+		Expect(routingAPIClient.SetTokenArgsForCall(0)).To(Equal("accesstoken"))
+
+		Expect(routingAPIClient.UpsertRoutesCallCount()).To(Equal(1))
+		Expect(routingAPIClient.UpsertRoutesArgsForCall(0)).To(ConsistOf(
+			apimodels.NewRoute("foo.com", 61000, "1.1.1.1", "log-guid", "https://rs.example.com", 120),
+			apimodels.NewRoute("bar.com", 61000, "1.1.1.1", "log-guid", "https://rs.example.com", 120),
+		))
+	})
+
+	It("deletes the unregistrations", func() {
+		Expect(httpEmitter.Emit(messages)).To(Succeed())
+		Expect(routingAPIClient.DeleteRoutesCallCount()).To(Equal(1))
+		Expect(routingAPIClient.DeleteRoutesArgsForCall(0)).To(ConsistOf(
+			apimodels.NewRoute("baz.com", 61001, "2.2.2.2", "other-log-guid", "", 120),
+		))
+	})
+
+	It("counts the emitted routes", func() {
+		Expect(httpEmitter.Emit(messages)).To(Succeed())
+		Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(1))
+		name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+		Expect(name).To(Equal("HTTPRouteRoutingAPIMessagesEmitted"))
+		Expect(delta).To(BeEquivalentTo(3))
+	})
+
+	Context("when there are only internal routes", func() {
+		BeforeEach(func() {
+			messages.RegistrationMessages = nil
+			messages.UnregistrationMessages = nil
+		})
+
+		It("does not call the routing api", func() {
+			Expect(httpEmitter.Emit(messages)).To(Succeed())
+			Expect(uaaTokenFetcher.FetchTokenCallCount()).To(Equal(0))
+			Expect(routingAPIClient.UpsertRoutesCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when an endpoint only has a tls port", func() {
+		BeforeEach(func() {
+			messages.RegistrationMessages = []routingtable.RegistryMessage{
+				{URIs: []string{"foo.com"}, Host: "1.1.1.1", TlsPort: 61443},
+			}
+		})
+
+		It("skips it", func() {
+			Expect(httpEmitter.Emit(messages)).To(Succeed())
+			Expect(routingAPIClient.UpsertRoutesCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the routing api fails once", func() {
+		BeforeEach(func() {
+			calls := 0
+			routingAPIClient.UpsertRoutesStub = func([]apimodels.Route) error {
+				calls++
+				if calls == 1 {
+					return errors.New("unauthorized")
+				}
+				return nil
+			}
+		})
+
+		It("retries with a fresh token", func() {
+			Expect(httpEmitter.Emit(messages)).To(Succeed())
+			Expect(uaaTokenFetcher.FetchTokenCallCount()).To(Equal(2))
+			_, forceUpdate := uaaTokenFetcher.FetchTokenArgsForCall(1)
+			Expect(forceUpdate).To(BeTrue())
+		})
+	})
+
+	Context("when the routing api keeps failing", func() {
+		BeforeEach(func() {
+			routingAPIClient.UpsertRoutesReturns(errors.New("boom"))
+		})
+
+		It("returns the error", func() {
+			Expect(httpEmitter.Emit(messages)).To(MatchError("boom"))
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(0))
+		})
+	})
+})
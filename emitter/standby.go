@@ -0,0 +1,108 @@
+package emitter
+
+import (
+	"os"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/tedsuo/ifrit"
+)
+
+// StandbyGate drops all emitted messages until it is activated. It allows a
+// route emitter that does not hold the lock to keep its routing table up to
+// date without publishing any routes.
+type StandbyGate struct {
+	active int32
+}
+
+func NewStandbyGate() *StandbyGate {
+	return &StandbyGate{}
+}
+
+func (g *StandbyGate) Activate() {
+	atomic.StoreInt32(&g.active, 1)
+}
+
+func (g *StandbyGate) Active() bool {
+	return atomic.LoadInt32(&g.active) == 1
+}
+
+// NATSEmitter wraps the emitter so that it only emits once the gate is
+// active. A nil emitter is returned as is.
+func (g *StandbyGate) NATSEmitter(delegate NATSEmitter) NATSEmitter {
+	if delegate == nil {
+		return nil
+	}
+	return &standbyNATSEmitter{gate: g, delegate: delegate}
+}
+
+// RoutingAPIEmitter wraps the emitter so that it only emits once the gate is
+// active. A nil emitter is returned as is.
+func (g *StandbyGate) RoutingAPIEmitter(delegate RoutingAPIEmitter) RoutingAPIEmitter {
+	if delegate == nil {
+		return nil
+	}
+	return &standbyRoutingAPIEmitter{gate: g, delegate: delegate}
+}
+
+// TCPReconciler wraps the reconciler so that it only deletes mappings once
+// the gate is active. A nil reconciler is returned as is.
+func (g *StandbyGate) TCPReconciler(delegate TCPReconciler) TCPReconciler {
+	if delegate == nil {
+		return nil
+	}
+	return &standbyTCPReconciler{gate: g, delegate: delegate}
+}
+
+// Activator returns a runner that activates the gate and then triggers a full
+// emit on each of the given channels, see TriggerFullEmit. It is meant to run
+// after the lock has been acquired.
+func (g *StandbyGate) Activator(logger lager.Logger, requestFullEmit func(), emitChans ...chan<- struct{}) ifrit.Runner {
+	logger = logger.Session("standby-gate")
+	return ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+		g.Activate()
+		logger.Info("activated")
+		TriggerFullEmit(requestFullEmit, emitChans...)()
+
+		close(ready)
+		<-signals
+		return nil
+	})
+}
+
+type standbyNATSEmitter struct {
+	gate     *StandbyGate
+	delegate NATSEmitter
+}
+
+func (e *standbyNATSEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	if !e.gate.Active() {
+		return nil
+	}
+	return e.delegate.Emit(messagesToEmit)
+}
+
+type standbyRoutingAPIEmitter struct {
+	gate     *StandbyGate
+	delegate RoutingAPIEmitter
+}
+
+func (e *standbyRoutingAPIEmitter) Emit(routingEvents routingtable.TCPRouteMappings) error {
+	if !e.gate.Active() {
+		return nil
+	}
+	return e.delegate.Emit(routingEvents)
+}
+
+type standbyTCPReconciler struct {
+	gate     *StandbyGate
+	delegate TCPReconciler
+}
+
+func (r *standbyTCPReconciler) Reconcile(desired routingtable.TCPRouteMappings) error {
+	if !r.gate.Active() {
+		return nil
+	}
+	return r.delegate.Reconcile(desired)
+}
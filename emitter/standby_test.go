@@ -0,0 +1,143 @@
+package emitter_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("StandbyGate", func() {
+	var (
+		gate                  *emitter.StandbyGate
+		fakeNATSEmitter       *fakes.FakeNATSEmitter
+		fakeRoutingAPIEmitter *fakes.FakeRoutingAPIEmitter
+		natsEmitter           emitter.NATSEmitter
+		routingAPIEmitter     emitter.RoutingAPIEmitter
+		fakeTCPReconciler     *fakes.FakeTCPReconciler
+		tcpReconciler         emitter.TCPReconciler
+		messages              routingtable.MessagesToEmit
+		mappings              routingtable.TCPRouteMappings
+	)
+
+	BeforeEach(func() {
+		gate = emitter.NewStandbyGate()
+		fakeNATSEmitter = &fakes.FakeNATSEmitter{}
+		fakeRoutingAPIEmitter = &fakes.FakeRoutingAPIEmitter{}
+		natsEmitter = gate.NATSEmitter(fakeNATSEmitter)
+		routingAPIEmitter = gate.RoutingAPIEmitter(fakeRoutingAPIEmitter)
+		fakeTCPReconciler = &fakes.FakeTCPReconciler{}
+		tcpReconciler = gate.TCPReconciler(fakeTCPReconciler)
+
+		messages = routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.1", Port: 61000}},
+		}
+	})
+
+	Context("when the gate is not active", func() {
+		It("drops the messages", func() {
+			Expect(gate.Active()).To(BeFalse())
+			Expect(natsEmitter.Emit(messages)).To(Succeed())
+			Expect(routingAPIEmitter.Emit(mappings)).To(Succeed())
+			Expect(fakeNATSEmitter.EmitCallCount()).To(Equal(0))
+			Expect(fakeRoutingAPIEmitter.EmitCallCount()).To(Equal(0))
+		})
+
+		It("does not reconcile", func() {
+			Expect(tcpReconciler.Reconcile(mappings)).To(Succeed())
+			Expect(fakeTCPReconciler.ReconcileCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the gate is active", func() {
+		BeforeEach(func() {
+			gate.Activate()
+		})
+
+		It("emits the messages", func() {
+			Expect(natsEmitter.Emit(messages)).To(Succeed())
+			Expect(fakeNATSEmitter.EmitCallCount()).To(Equal(1))
+			Expect(fakeNATSEmitter.EmitArgsForCall(0)).To(Equal(messages))
+
+			Expect(routingAPIEmitter.Emit(mappings)).To(Succeed())
+			Expect(fakeRoutingAPIEmitter.EmitCallCount()).To(Equal(1))
+
+			Expect(tcpReconciler.Reconcile(mappings)).To(Succeed())
+			Expect(fakeTCPReconciler.ReconcileCallCount()).To(Equal(1))
+		})
+
+		It("returns the emitter errors", func() {
+			fakeNATSEmitter.EmitReturns(errors.New("boom"))
+			Expect(natsEmitter.Emit(messages)).To(MatchError("boom"))
+		})
+	})
+
+	Context("when the emitter is nil", func() {
+		It("returns nil", func() {
+			Expect(gate.NATSEmitter(nil)).To(BeNil())
+			Expect(gate.RoutingAPIEmitter(nil)).To(BeNil())
+			Expect(gate.TCPReconciler(nil)).To(BeNil())
+		})
+	})
+
+	Describe("Activator", func() {
+		var (
+			process              ifrit.Process
+			externalChan         chan struct{}
+			internalChan         chan struct{}
+			fullEmitRequests     int
+			pendingWhenRequested int
+		)
+
+		BeforeEach(func() {
+			externalChan = make(chan struct{}, 1)
+			internalChan = make(chan struct{}, 1)
+			fullEmitRequests = 0
+			pendingWhenRequested = 0
+		})
+
+		JustBeforeEach(func() {
+			requestFullEmit := func() {
+				fullEmitRequests++
+				pendingWhenRequested = len(externalChan) + len(internalChan)
+			}
+			process = ifrit.Invoke(gate.Activator(lagertest.NewTestLogger("test"), requestFullEmit, externalChan, internalChan))
+		})
+
+		AfterEach(func() {
+			ifrit.Interrupt(process)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+
+		It("activates the gate", func() {
+			Expect(gate.Active()).To(BeTrue())
+		})
+
+		It("triggers a full emit", func() {
+			Expect(externalChan).To(Receive())
+			Expect(internalChan).To(Receive())
+		})
+
+		It("requests a full emit before triggering it, so that it is not sharded", func() {
+			Expect(fullEmitRequests).To(Equal(1))
+			Expect(pendingWhenRequested).To(Equal(0))
+		})
+
+		Context("when an emit is already pending", func() {
+			BeforeEach(func() {
+				externalChan <- struct{}{}
+			})
+
+			It("does not block", func() {
+				Expect(externalChan).To(Receive())
+				Expect(externalChan).NotTo(Receive())
+				Expect(internalChan).To(Receive())
+			})
+		})
+	})
+})
@@ -0,0 +1,168 @@
+package emitter
+
+import (
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	routing_api "code.cloudfoundry.org/routing-api"
+	"code.cloudfoundry.org/routing-api/models"
+	"code.cloudfoundry.org/routing-api/uaaclient"
+)
+
+const (
+	staleTCPRouteMappingsMetric   = "TCPRouteMappingsStale"
+	missingTCPRouteMappingsMetric = "TCPRouteMappingsMissing"
+	deletedTCPRouteMappingsCount  = "TCPRouteMappingsDeleted"
+)
+
+//go:generate counterfeiter -o fakes/fake_tcp_reconciler.go . TCPReconciler
+type TCPReconciler interface {
+	Reconcile(desired routingtable.TCPRouteMappings) error
+}
+
+type tcpMappingKey struct {
+	routerGroupGUID string
+	externalPort    uint16
+	hostIP          string
+	hostPort        uint16
+}
+
+func keyFor(mapping models.TcpRouteMapping) tcpMappingKey {
+	return tcpMappingKey{
+		routerGroupGUID: mapping.RouterGroupGuid,
+		externalPort:    mapping.ExternalPort,
+		hostIP:          mapping.HostIP,
+		hostPort:        mapping.HostPort,
+	}
+}
+
+type tcpReconciler struct {
+	logger           lager.Logger
+	routingAPIClient routing_api.Client
+	uaaTokenFetcher  uaaclient.TokenFetcher
+	metronClient     loggingclient.IngressClient
+
+	// the emitter owns the mappings of these router groups, or of all
+	// router groups when empty
+	routerGroups map[string]struct{}
+	// in local mode the emitter only owns the mappings to the hosts of its
+	// cell, which are the hosts it has desired mappings for
+	localMode  bool
+	knownHosts map[string]struct{}
+}
+
+// NewTCPReconciler returns a reconciler that deletes the mappings in the
+// routing api that the emitter owns but that are no longer in its routing
+// table, instead of waiting for them to expire.
+func NewTCPReconciler(
+	logger lager.Logger,
+	routingAPIClient routing_api.Client,
+	uaaTokenFetcher uaaclient.TokenFetcher,
+	metronClient loggingclient.IngressClient,
+	routerGroups []string,
+	localMode bool,
+) TCPReconciler {
+	routerGroupSet := map[string]struct{}{}
+	for _, guid := range routerGroups {
+		routerGroupSet[guid] = struct{}{}
+	}
+
+	return &tcpReconciler{
+		logger:           logger.Session("tcp-reconciler"),
+		routingAPIClient: routingAPIClient,
+		uaaTokenFetcher:  uaaTokenFetcher,
+		metronClient:     metronClient,
+		routerGroups:     routerGroupSet,
+		localMode:        localMode,
+		knownHosts:       map[string]struct{}{},
+	}
+}
+
+// Reconcile compares the mappings in the routing api with the desired
+// registrations, deletes the stale ones and reports the drift. Desired
+// mappings missing from the routing api are only reported, the next refresh
+// upserts them.
+func (r *tcpReconciler) Reconcile(desired routingtable.TCPRouteMappings) error {
+	logger := r.logger.Session("reconcile")
+
+	desiredKeys := map[tcpMappingKey]struct{}{}
+	for _, mapping := range desired.Registrations {
+		desiredKeys[keyFor(mapping)] = struct{}{}
+		r.knownHosts[mapping.HostIP] = struct{}{}
+	}
+
+	var actual []models.TcpRouteMapping
+	err := withToken(r.uaaTokenFetcher, r.routingAPIClient, func() error {
+		var err error
+		actual, err = r.routingAPIClient.TcpRouteMappings()
+		return err
+	})
+	if err != nil {
+		logger.Error("failed-to-list-tcp-route-mappings", err)
+		return err
+	}
+
+	actualKeys := map[tcpMappingKey]struct{}{}
+	stale := []models.TcpRouteMapping{}
+	for _, mapping := range actual {
+		key := keyFor(mapping)
+		actualKeys[key] = struct{}{}
+		if !r.owns(mapping) {
+			continue
+		}
+		if _, ok := desiredKeys[key]; !ok {
+			stale = append(stale, mapping)
+		}
+	}
+
+	missing := 0
+	for key := range desiredKeys {
+		if _, ok := actualKeys[key]; !ok {
+			missing++
+		}
+	}
+
+	r.sendMetric(logger, staleTCPRouteMappingsMetric, len(stale))
+	r.sendMetric(logger, missingTCPRouteMappingsMetric, missing)
+
+	if len(stale) == 0 {
+		logger.Debug("no-stale-mappings", lager.Data{"missing": missing})
+		return nil
+	}
+
+	logger.Info("deleting-stale-mappings", lager.Data{"stale": len(stale), "missing": missing})
+	err = withToken(r.uaaTokenFetcher, r.routingAPIClient, func() error {
+		return r.routingAPIClient.DeleteTcpRouteMappings(stale)
+	})
+	if err != nil {
+		logger.Error("failed-to-delete-stale-mappings", err)
+		return err
+	}
+
+	err = r.metronClient.IncrementCounterWithDelta(deletedTCPRouteMappingsCount, uint64(len(stale)))
+	if err != nil {
+		logger.Error("failed-to-send-deleted-mappings-metric", err)
+	}
+	return nil
+}
+
+func (r *tcpReconciler) owns(mapping models.TcpRouteMapping) bool {
+	if len(r.routerGroups) > 0 {
+		if _, ok := r.routerGroups[mapping.RouterGroupGuid]; !ok {
+			return false
+		}
+	}
+
+	if r.localMode {
+		_, ok := r.knownHosts[mapping.HostIP]
+		return ok
+	}
+	return true
+}
+
+func (r *tcpReconciler) sendMetric(logger lager.Logger, name string, value int) {
+	err := r.metronClient.SendMetric(name, value)
+	if err != nil {
+		logger.Error("failed-to-send-metric", err, lager.Data{"metric": name})
+	}
+}
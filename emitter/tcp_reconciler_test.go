@@ -0,0 +1,159 @@
+package emitter_test
+
+import (
+	"errors"
+
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-api/fake_routing_api"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+	fakeuaa "code.cloudfoundry.org/routing-api/uaaclient/fakes"
+	"golang.org/x/oauth2"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TCPReconciler", func() {
+	var (
+		routingAPIClient *fake_routing_api.FakeClient
+		uaaTokenFetcher  *fakeuaa.FakeTokenFetcher
+		fakeMetronClient *mfakes.FakeIngressClient
+		routerGroups     []string
+		localMode        bool
+		reconciler       emitter.TCPReconciler
+
+		desiredMapping apimodels.TcpRouteMapping
+		staleMapping   apimodels.TcpRouteMapping
+		foreignMapping apimodels.TcpRouteMapping
+		desired        routingtable.TCPRouteMappings
+	)
+
+	BeforeEach(func() {
+		routingAPIClient = new(fake_routing_api.FakeClient)
+		uaaTokenFetcher = &fakeuaa.FakeTokenFetcher{}
+		uaaTokenFetcher.FetchTokenReturns(&oauth2.Token{AccessToken: "accesstoken"}, nil)
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		routerGroups = nil
+		localMode = false
+
+		desiredMapping = apimodels.NewTcpRouteMapping("rg-1", 61000, "1.1.1.1", 62000, 0)
+		staleMapping = apimodels.NewTcpRouteMapping("rg-1", 61001, "1.1.1.1", 62001, 120)
+		foreignMapping = apimodels.NewTcpRouteMapping("rg-2", 61002, "2.2.2.2", 62002, 120)
+
+		desired = routingtable.TCPRouteMappings{
+			Registrations: []apimodels.TcpRouteMapping{
+				desiredMapping,
+				apimodels.NewTcpRouteMapping("rg-1", 61003, "1.1.1.1", 62003, 0),
+			},
+		}
+
+		routingAPIClient.TcpRouteMappingsReturns([]apimodels.TcpRouteMapping{
+			apimodels.NewTcpRouteMapping("rg-1", 61000, "1.1.1.1", 62000, 120),
+			staleMapping,
+			foreignMapping,
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		logger := lagertest.NewTestLogger("test")
+		reconciler = emitter.NewTCPReconciler(logger, routingAPIClient, uaaTokenFetcher, fakeMetronClient, routerGroups, localMode)
+	})
+
+	It("authorizes the routing api calls", func() {
+		Expect(reconciler.Reconcile(desired)).To(Succeed())
+		Expect(uaaTokenFetcher.FetchTokenCallCount()).To(BeNumerically(">=", 1))
+		Expect(routingAPIClient.SetTokenCallCount()).To(BeNumerically(">=", 1))
+	})
+
+	It("deletes the mappings that are not desired", func() {
+		Expect(reconciler.Reconcile(desired)).To(Succeed())
+		Expect(routingAPIClient.DeleteTcpRouteMappingsCallCount()).To(Equal(1))
+		Expect(routingAPIClient.DeleteTcpRouteMappingsArgsForCall(0)).To(ConsistOf(staleMapping, foreignMapping))
+	})
+
+	It("reports the drift", func() {
+		Expect(reconciler.Reconcile(desired)).To(Succeed())
+
+		Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(2))
+		name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(name).To(Equal("TCPRouteMappingsStale"))
+		Expect(value).To(Equal(2))
+		name, value, _ = fakeMetronClient.SendMetricArgsForCall(1)
+		Expect(name).To(Equal("TCPRouteMappingsMissing"))
+		Expect(value).To(Equal(1))
+
+		Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(1))
+		name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+		Expect(name).To(Equal("TCPRouteMappingsDeleted"))
+		Expect(delta).To(BeEquivalentTo(2))
+	})
+
+	Context("when router groups are configured", func() {
+		BeforeEach(func() {
+			routerGroups = []string{"rg-1"}
+		})
+
+		It("only deletes the mappings of those router groups", func() {
+			Expect(reconciler.Reconcile(desired)).To(Succeed())
+			Expect(routingAPIClient.DeleteTcpRouteMappingsCallCount()).To(Equal(1))
+			Expect(routingAPIClient.DeleteTcpRouteMappingsArgsForCall(0)).To(ConsistOf(staleMapping))
+		})
+	})
+
+	Context("when in local mode", func() {
+		BeforeEach(func() {
+			localMode = true
+		})
+
+		It("only deletes the mappings to hosts it has desired mappings for", func() {
+			Expect(reconciler.Reconcile(desired)).To(Succeed())
+			Expect(routingAPIClient.DeleteTcpRouteMappingsCallCount()).To(Equal(1))
+			Expect(routingAPIClient.DeleteTcpRouteMappingsArgsForCall(0)).To(ConsistOf(staleMapping))
+		})
+
+		It("keeps owning hosts whose mappings were all removed", func() {
+			Expect(reconciler.Reconcile(desired)).To(Succeed())
+
+			Expect(reconciler.Reconcile(routingtable.TCPRouteMappings{})).To(Succeed())
+			Expect(routingAPIClient.DeleteTcpRouteMappingsCallCount()).To(Equal(2))
+			Expect(routingAPIClient.DeleteTcpRouteMappingsArgsForCall(1)).To(HaveLen(2))
+		})
+	})
+
+	Context("when nothing is stale", func() {
+		BeforeEach(func() {
+			routingAPIClient.TcpRouteMappingsReturns([]apimodels.TcpRouteMapping{desiredMapping}, nil)
+		})
+
+		It("does not delete anything", func() {
+			Expect(reconciler.Reconcile(desired)).To(Succeed())
+			Expect(routingAPIClient.DeleteTcpRouteMappingsCallCount()).To(Equal(0))
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when listing the mappings fails", func() {
+		BeforeEach(func() {
+			routingAPIClient.TcpRouteMappingsReturns(nil, errors.New("boom"))
+		})
+
+		It("returns the error and deletes nothing", func() {
+			Expect(reconciler.Reconcile(desired)).To(MatchError("boom"))
+			Expect(routingAPIClient.DeleteTcpRouteMappingsCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when deleting the mappings fails", func() {
+		BeforeEach(func() {
+			routingAPIClient.DeleteTcpRouteMappingsReturns(errors.New("boom"))
+		})
+
+		It("returns the error", func() {
+			Expect(reconciler.Reconcile(desired)).To(MatchError("boom"))
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(0))
+		})
+	})
+})
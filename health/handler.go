@@ -0,0 +1,57 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const (
+	ReadyPath  = "/ready"
+	HealthPath = "/health"
+)
+
+type handler struct {
+	logger lager.Logger
+	report func() Report
+}
+
+// NewHandler serves the readiness and liveness reports of the state as JSON,
+// with a 503 status code when a check fails. Any other path always returns
+// 200 for existing health checks.
+func NewHandler(logger lager.Logger, state *State) http.Handler {
+	logger = logger.Session("health")
+
+	mux := http.NewServeMux()
+	mux.Handle(ReadyPath, &handler{logger: logger.Session("ready"), report: state.Readiness})
+	mux.Handle(HealthPath, &handler{logger: logger.Session("health"), report: state.Liveness})
+	mux.HandleFunc("/", func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+func (h *handler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	report := h.report()
+
+	payload, err := json.Marshal(report)
+	if err != nil {
+		h.logger.Error("failed-to-marshal-report", err)
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if !report.Healthy {
+		h.logger.Debug("unhealthy", lager.Data{"checks": report.Checks})
+		status = http.StatusServiceUnavailable
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+	_, err = resp.Write(payload)
+	if err != nil {
+		h.logger.Error("failed-to-write-response", err)
+	}
+}
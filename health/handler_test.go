@@ -0,0 +1,73 @@
+package health_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/health"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		state   *health.State
+		handler http.Handler
+	)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	BeforeEach(func() {
+		state = health.NewState(fakeclock.NewFakeClock(time.Now()), diegonats.NewFakeClient(), health.Thresholds{
+			ReadinessSyncAge:   time.Minute,
+			SyncAge:            time.Minute,
+			SubscriptionOutage: time.Minute,
+		}, "router")
+		handler = health.NewHandler(lagertest.NewTestLogger("test"), state)
+	})
+
+	It("always returns 200 on the root path", func() {
+		Expect(get("/").Code).To(Equal(http.StatusOK))
+	})
+
+	It("returns 503 and the report when not ready", func() {
+		resp := get(health.ReadyPath)
+		Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var report health.Report
+		Expect(json.Unmarshal(resp.Body.Bytes(), &report)).To(Succeed())
+		Expect(report.Healthy).To(BeFalse())
+		Expect(report.Checks).To(ContainElement(health.Check{Name: health.NATSCheck, Healthy: true}))
+	})
+
+	It("returns 200 when ready", func() {
+		state.WatcherStarted()
+		state.EventSourceSubscribed()
+		state.SyncSucceeded()
+		state.GreetingReceived("router")
+
+		Expect(get(health.ReadyPath).Code).To(Equal(http.StatusOK))
+	})
+
+	It("returns 200 when healthy", func() {
+		resp := get(health.HealthPath)
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(MatchJSON(`{
+			"healthy": true,
+			"checks": [
+				{"name": "sync", "healthy": true},
+				{"name": "event-subscription", "healthy": true}
+			]
+		}`))
+	})
+})
@@ -0,0 +1 @@
+package health // import "code.cloudfoundry.org/route-emitter/health"
@@ -0,0 +1,299 @@
+package health
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+)
+
+const (
+	SyncCheck         = "sync"
+	SubscriptionCheck = "event-subscription"
+	NATSCheck         = "nats"
+	GreetingCheck     = "router-greeting"
+	// NATSTargetCheck prefixes the checks of the additional NATS targets,
+	// e.g. "nats-target:new-cluster".
+	NATSTargetCheck = "nats-target"
+)
+
+type Thresholds struct {
+	// ReadinessSyncAge is the maximum age of the last successful sync for
+	// the emitter to be ready.
+	ReadinessSyncAge time.Duration
+	// SyncAge is the maximum time without a successful sync for the emitter
+	// to be healthy.
+	SyncAge time.Duration
+	// SubscriptionOutage is the maximum time the BBS event subscription can
+	// be down for the emitter to be healthy.
+	SubscriptionOutage time.Duration
+}
+
+type Check struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+	// Optional checks are reported but do not affect the health of the
+	// report.
+	Optional bool `json:"optional,omitempty"`
+}
+
+type Report struct {
+	Healthy bool    `json:"healthy"`
+	Checks  []Check `json:"checks"`
+}
+
+func newReport(checks ...Check) Report {
+	report := Report{Healthy: true, Checks: checks}
+	for _, check := range checks {
+		report.Healthy = report.Healthy && (check.Healthy || check.Optional)
+	}
+	return report
+}
+
+// State records the sync, event subscription and router greeting state
+// reported by the watcher and the route broadcast schedulers. The watcher
+// only runs once the lock is held (or in hot standby), until then the
+// emitter is healthy but not ready.
+type State struct {
+	clock      clock.Clock
+	natsClient diegonats.NATSClient
+
+	mutex             sync.Mutex
+	thresholds        Thresholds
+	watchStarted      time.Time
+	lastSync          time.Time
+	subscribed        bool
+	unsubscribedSince time.Time
+	greetings         map[string]bool
+	natsTargets       []*NATSTarget
+}
+
+// NATSTarget records the router greetings received from an additional NATS
+// target.
+type NATSTarget struct {
+	state      *State
+	name       string
+	natsClient diegonats.NATSClient
+	greetings  map[string]bool
+}
+
+// NewState returns a State that expects a greeting from each of the external
+// services, e.g. "router" and "service-discovery".
+func NewState(clock clock.Clock, natsClient diegonats.NATSClient, thresholds Thresholds, externalServiceNames ...string) *State {
+	greetings := map[string]bool{}
+	for _, name := range externalServiceNames {
+		greetings[name] = false
+	}
+
+	return &State{
+		clock:      clock,
+		natsClient: natsClient,
+		thresholds: thresholds,
+		greetings:  greetings,
+	}
+}
+
+// SetThresholds changes the thresholds at runtime, e.g. after the sync
+// interval they are derived from was reloaded.
+func (s *State) SetThresholds(thresholds Thresholds) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.thresholds = thresholds
+}
+
+func (s *State) WatcherStarted() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.clock.Now()
+	s.watchStarted = now
+	s.subscribed = false
+	s.unsubscribedSince = now
+}
+
+func (s *State) EventSourceSubscribed() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.subscribed = true
+	s.unsubscribedSince = time.Time{}
+}
+
+func (s *State) EventSourceFailed() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.subscribed || s.unsubscribedSince.IsZero() {
+		s.unsubscribedSince = s.clock.Now()
+	}
+	s.subscribed = false
+}
+
+func (s *State) SyncSucceeded() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastSync = s.clock.Now()
+}
+
+// LastSync returns when the watcher last synced with the BBS, it is zero
+// until the first sync succeeded.
+func (s *State) LastSync() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.lastSync
+}
+
+func (s *State) GreetingReceived(externalServiceName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.greetings[externalServiceName]; ok {
+		s.greetings[externalServiceName] = true
+	}
+}
+
+// AddNATSTarget adds an optional readiness check for an additional NATS
+// target, an unavailable target is reported without taking the emitter out
+// of service. The returned NATSTarget is the greeting reporter of the
+// target's route broadcast schedulers.
+func (s *State) AddNATSTarget(name string, natsClient diegonats.NATSClient, externalServiceNames ...string) *NATSTarget {
+	greetings := map[string]bool{}
+	for _, serviceName := range externalServiceNames {
+		greetings[serviceName] = false
+	}
+
+	target := &NATSTarget{
+		state:      s,
+		name:       name,
+		natsClient: natsClient,
+		greetings:  greetings,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.natsTargets = append(s.natsTargets, target)
+	return target
+}
+
+func (t *NATSTarget) GreetingReceived(externalServiceName string) {
+	t.state.mutex.Lock()
+	defer t.state.mutex.Unlock()
+
+	if _, ok := t.greetings[externalServiceName]; ok {
+		t.greetings[externalServiceName] = true
+	}
+}
+
+// Readiness reports whether the emitter has a recent view of the BBS, is
+// subscribed to its events, is connected to NATS and has heard from the
+// routers.
+func (s *State) Readiness() Report {
+	natsConnected := s.natsClient.Ping()
+
+	s.mutex.Lock()
+	natsTargets := append([]*NATSTarget{}, s.natsTargets...)
+	s.mutex.Unlock()
+	natsTargetsConnected := make([]bool, len(natsTargets))
+	for i, target := range natsTargets {
+		natsTargetsConnected[i] = target.natsClient.Ping()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.clock.Now()
+
+	syncCheck := Check{Name: SyncCheck, Healthy: true}
+	switch {
+	case s.watchStarted.IsZero():
+		syncCheck = Check{Name: SyncCheck, Message: "not watching the bbs"}
+	case s.lastSync.IsZero():
+		syncCheck = Check{Name: SyncCheck, Message: "no successful sync"}
+	case now.Sub(s.lastSync) > s.thresholds.ReadinessSyncAge:
+		syncCheck = Check{Name: SyncCheck, Message: fmt.Sprintf("last successful sync %s ago", now.Sub(s.lastSync))}
+	}
+
+	subscriptionCheck := Check{Name: SubscriptionCheck, Healthy: s.subscribed}
+	if !s.subscribed {
+		subscriptionCheck.Message = "not subscribed to bbs events"
+	}
+
+	natsCheck := Check{Name: NATSCheck, Healthy: natsConnected}
+	if !natsConnected {
+		natsCheck.Message = "not connected"
+	}
+
+	checks := []Check{syncCheck, subscriptionCheck, natsCheck, greetingCheck(GreetingCheck, s.greetings)}
+	for i, target := range natsTargets {
+		checks = append(checks, target.check(natsTargetsConnected[i]))
+	}
+	return newReport(checks...)
+}
+
+// Liveness reports whether the emitter has been unable to sync or to
+// subscribe to BBS events for longer than the thresholds. NATS outages do
+// not fail the check since the client reconnects on its own.
+func (s *State) Liveness() Report {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.watchStarted.IsZero() {
+		return newReport(
+			Check{Name: SyncCheck, Healthy: true},
+			Check{Name: SubscriptionCheck, Healthy: true},
+		)
+	}
+
+	now := s.clock.Now()
+
+	syncCheck := Check{Name: SyncCheck, Healthy: true}
+	lastSync := s.lastSync
+	if lastSync.IsZero() {
+		lastSync = s.watchStarted
+	}
+	if age := now.Sub(lastSync); age > s.thresholds.SyncAge {
+		syncCheck = Check{Name: SyncCheck, Message: fmt.Sprintf("no successful sync for %s", age)}
+	}
+
+	subscriptionCheck := Check{Name: SubscriptionCheck, Healthy: true}
+	if !s.subscribed {
+		if outage := now.Sub(s.unsubscribedSince); outage > s.thresholds.SubscriptionOutage {
+			subscriptionCheck = Check{Name: SubscriptionCheck, Message: fmt.Sprintf("not subscribed to bbs events for %s", outage)}
+		}
+	}
+
+	return newReport(syncCheck, subscriptionCheck)
+}
+
+func (t *NATSTarget) check(connected bool) Check {
+	name := NATSTargetCheck + ":" + t.name
+	if !connected {
+		return Check{Name: name, Message: "not connected", Optional: true}
+	}
+
+	check := greetingCheck(name, t.greetings)
+	check.Optional = true
+	return check
+}
+
+func greetingCheck(checkName string, greetings map[string]bool) Check {
+	waiting := []string{}
+	for name, greeted := range greetings {
+		if !greeted {
+			waiting = append(waiting, name)
+		}
+	}
+
+	if len(waiting) == 0 {
+		return Check{Name: checkName, Healthy: true}
+	}
+
+	sort.Strings(waiting)
+	return Check{Name: checkName, Message: fmt.Sprintf("waiting for a greeting from %v", waiting)}
+}
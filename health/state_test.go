@@ -0,0 +1,192 @@
+package health_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/health"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("State", func() {
+	var (
+		clock      *fakeclock.FakeClock
+		natsClient *diegonats.FakeNATSClient
+		state      *health.State
+	)
+
+	failing := func(report health.Report) []string {
+		names := []string{}
+		for _, check := range report.Checks {
+			if !check.Healthy {
+				names = append(names, check.Name)
+			}
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		natsClient = diegonats.NewFakeClient()
+		state = health.NewState(clock, natsClient, health.Thresholds{
+			ReadinessSyncAge:   2 * time.Minute,
+			SyncAge:            5 * time.Minute,
+			SubscriptionOutage: time.Minute,
+		}, "router", "service-discovery")
+	})
+
+	Describe("Readiness", func() {
+		Context("before the watcher starts", func() {
+			It("is not ready", func() {
+				report := state.Readiness()
+				Expect(report.Healthy).To(BeFalse())
+				Expect(failing(report)).To(ConsistOf(health.SyncCheck, health.SubscriptionCheck, health.GreetingCheck))
+			})
+		})
+
+		Context("when everything has been reported", func() {
+			BeforeEach(func() {
+				state.WatcherStarted()
+				state.EventSourceSubscribed()
+				state.SyncSucceeded()
+				state.GreetingReceived("router")
+				state.GreetingReceived("service-discovery")
+			})
+
+			It("is ready", func() {
+				Expect(state.Readiness().Healthy).To(BeTrue())
+			})
+
+			It("is not ready once the last sync is too old", func() {
+				clock.Increment(2*time.Minute + time.Second)
+				Expect(failing(state.Readiness())).To(ConsistOf(health.SyncCheck))
+			})
+
+			It("is not ready when the event source fails", func() {
+				state.EventSourceFailed()
+				Expect(failing(state.Readiness())).To(ConsistOf(health.SubscriptionCheck))
+			})
+
+			It("is not ready when nats is disconnected", func() {
+				natsClient.OnPing(func() bool { return false })
+				Expect(failing(state.Readiness())).To(ConsistOf(health.NATSCheck))
+			})
+		})
+
+		Context("when only some of the routers have greeted", func() {
+			BeforeEach(func() {
+				state.GreetingReceived("router")
+			})
+
+			It("reports the missing greeting", func() {
+				var check health.Check
+				for _, c := range state.Readiness().Checks {
+					if c.Name == health.GreetingCheck {
+						check = c
+					}
+				}
+				Expect(check.Healthy).To(BeFalse())
+				Expect(check.Message).To(ContainSubstring("service-discovery"))
+			})
+		})
+
+		Context("with an additional nats target", func() {
+			var (
+				targetClient *diegonats.FakeNATSClient
+				target       *health.NATSTarget
+			)
+
+			BeforeEach(func() {
+				targetClient = diegonats.NewFakeClient()
+				target = state.AddNATSTarget("new-cluster", targetClient, "router")
+
+				state.WatcherStarted()
+				state.EventSourceSubscribed()
+				state.SyncSucceeded()
+				state.GreetingReceived("router")
+				state.GreetingReceived("service-discovery")
+			})
+
+			It("reports the target without affecting readiness", func() {
+				report := state.Readiness()
+				Expect(report.Healthy).To(BeTrue())
+				Expect(failing(report)).To(ConsistOf("nats-target:new-cluster"))
+				Expect(report.Checks[len(report.Checks)-1].Optional).To(BeTrue())
+				Expect(report.Checks[len(report.Checks)-1].Message).To(ContainSubstring("router"))
+			})
+
+			It("reports a disconnected target", func() {
+				targetClient.OnPing(func() bool { return false })
+				target.GreetingReceived("router")
+
+				report := state.Readiness()
+				Expect(report.Healthy).To(BeTrue())
+				Expect(report.Checks[len(report.Checks)-1].Message).To(Equal("not connected"))
+			})
+
+			It("is healthy once the target's routers have greeted", func() {
+				target.GreetingReceived("router")
+				Expect(failing(state.Readiness())).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Liveness", func() {
+		It("is healthy before the watcher starts", func() {
+			clock.Increment(time.Hour)
+			Expect(state.Liveness().Healthy).To(BeTrue())
+		})
+
+		Context("when the watcher has started", func() {
+			BeforeEach(func() {
+				state.WatcherStarted()
+			})
+
+			It("is healthy within the thresholds", func() {
+				clock.Increment(time.Minute)
+				Expect(state.Liveness().Healthy).To(BeTrue())
+			})
+
+			It("is unhealthy when the subscription has been down for too long", func() {
+				clock.Increment(time.Minute + time.Second)
+				Expect(failing(state.Liveness())).To(ConsistOf(health.SubscriptionCheck))
+			})
+
+			It("is unhealthy when no sync has succeeded for too long", func() {
+				state.EventSourceSubscribed()
+				clock.Increment(5*time.Minute + time.Second)
+				Expect(failing(state.Liveness())).To(ConsistOf(health.SyncCheck))
+
+				state.SyncSucceeded()
+				Expect(state.Liveness().Healthy).To(BeTrue())
+			})
+
+			It("uses the thresholds it was changed to", func() {
+				state.EventSourceSubscribed()
+				clock.Increment(3 * time.Minute)
+				Expect(state.Liveness().Healthy).To(BeTrue())
+
+				state.SetThresholds(health.Thresholds{ReadinessSyncAge: time.Minute, SyncAge: 2 * time.Minute, SubscriptionOutage: time.Minute})
+				Expect(failing(state.Liveness())).To(ConsistOf(health.SyncCheck))
+			})
+
+			It("measures the outage from the first failure", func() {
+				state.EventSourceSubscribed()
+				clock.Increment(time.Minute)
+				state.EventSourceFailed()
+				clock.Increment(30 * time.Second)
+				state.EventSourceFailed()
+				clock.Increment(31 * time.Second)
+				Expect(failing(state.Liveness())).To(ConsistOf(health.SubscriptionCheck))
+			})
+
+			It("ignores nats outages", func() {
+				state.EventSourceSubscribed()
+				natsClient.OnPing(func() bool { return false })
+				Expect(state.Liveness().Healthy).To(BeTrue())
+			})
+		})
+	})
+})
@@ -0,0 +1,68 @@
+package jsonfile_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/route-emitter/jsonfile"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSONFile", func() {
+	type record struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	var (
+		tmpDir string
+		path   string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "jsonfile")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(tmpDir, "records.json")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("round trips the value", func() {
+		Expect(jsonfile.Save(path, []record{{Name: "foo", Count: 1}})).To(Succeed())
+
+		var loaded []record
+		Expect(jsonfile.Load(path, &loaded)).To(Succeed())
+		Expect(loaded).To(Equal([]record{{Name: "foo", Count: 1}}))
+	})
+
+	It("replaces the file without leaving temporary files behind", func() {
+		Expect(jsonfile.Save(path, record{Name: "foo"})).To(Succeed())
+		Expect(jsonfile.Save(path, record{Name: "bar"})).To(Succeed())
+
+		var loaded record
+		Expect(jsonfile.Load(path, &loaded)).To(Succeed())
+		Expect(loaded.Name).To(Equal("bar"))
+
+		files, err := os.ReadDir(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+	})
+
+	Context("when the file does not exist", func() {
+		It("returns an error", func() {
+			var loaded record
+			err := jsonfile.Load(path, &loaded)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("when the directory does not exist", func() {
+		It("returns an error", func() {
+			Expect(jsonfile.Save(filepath.Join(tmpDir, "missing", "records.json"), record{})).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1 @@
+package jsonfile // import "code.cloudfoundry.org/route-emitter/jsonfile"
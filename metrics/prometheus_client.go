@@ -0,0 +1,246 @@
+package metrics
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	loggregator "code.cloudfoundry.org/go-loggregator/v8"
+	"code.cloudfoundry.org/go-loggregator/v8/rpc/loggregator_v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "route_emitter"
+
+	RouterTypeLabel       = "router_type"
+	SubjectLabel          = "subject"
+	IsolationSegmentLabel = "isolation_segment"
+	TargetLabel           = "nats_target"
+)
+
+var labelNames = []string{RouterTypeLabel, SubjectLabel, IsolationSegmentLabel, TargetLabel}
+
+// routerTypes labels the metrics whose router type is implied by the name.
+var routerTypes = map[string]string{
+	"HTTPRouteNATSMessagesEmitted":     "http",
+	"InternalRouteNATSMessagesEmitted": "internal",
+	"HTTPRouteCount":                   "http",
+	"TCPRouteCount":                    "tcp",
+	"RoutesTotal":                      "http",
+	"RoutesSynced":                     "http",
+	"RoutesRegistered":                 "http",
+	"RoutesUnregistered":               "http",
+	"AddressCollisions":                "http",
+	"HostnameConflicts":                "http",
+	"RoutingTableSwapsRefused":         "http",
+}
+
+var durationBuckets = prometheus.ExponentialBuckets(0.05, 2, 12)
+
+// Labels are attached to the Prometheus series of a metric. Empty labels are
+// reported as empty strings.
+type Labels struct {
+	RouterType       string
+	Subject          string
+	IsolationSegment string
+	Target           string
+}
+
+// WithLabels returns gauge options that tag the envelope sent to Loggregator
+// with the labels, the PrometheusClient uses the same tags as series labels.
+func WithLabels(labels Labels) []loggregator.EmitGaugeOption {
+	opts := []loggregator.EmitGaugeOption{}
+	for _, name := range labelNames {
+		if value := labels.tags()[name]; value != "" {
+			opts = append(opts, loggregator.WithEnvelopeTag(name, value))
+		}
+	}
+	return opts
+}
+
+func (l Labels) tags() map[string]string {
+	return map[string]string{
+		RouterTypeLabel:       l.RouterType,
+		SubjectLabel:          l.Subject,
+		IsolationSegmentLabel: l.IsolationSegment,
+		TargetLabel:           l.Target,
+	}
+}
+
+type labeledCounter interface {
+	IncrementLabeledCounter(name string, delta uint64, labels Labels)
+}
+
+// IncrementLabeledCounter reports a breakdown of a counter to Prometheus when
+// the client supports it. The breakdown is not sent to Loggregator, call
+// sites keep reporting the aggregated counter through the metron client.
+func IncrementLabeledCounter(client loggingclient.IngressClient, name string, delta uint64, labels Labels) {
+	labeled, ok := client.(labeledCounter)
+	if ok {
+		labeled.IncrementLabeledCounter(name, delta, labels)
+	}
+}
+
+type labeledAggregateCounter interface {
+	IncrementCounterWithLabels(name string, delta uint64, labels Labels) error
+}
+
+// IncrementCounterWithLabels increments a counter through the metron client.
+// Clients that support labels report it to Prometheus with the labels, the
+// counter sent to Loggregator is the aggregate either way.
+func IncrementCounterWithLabels(client loggingclient.IngressClient, name string, delta uint64, labels Labels) error {
+	labeled, ok := client.(labeledAggregateCounter)
+	if ok {
+		return labeled.IncrementCounterWithLabels(name, delta, labels)
+	}
+	return client.IncrementCounterWithDelta(name, delta)
+}
+
+// PrometheusClient wraps the metron client and mirrors every counter, gauge
+// and duration to a Prometheus registry.
+type PrometheusClient struct {
+	loggingclient.IngressClient
+
+	registry   *prometheus.Registry
+	mutex      sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+func NewPrometheusClient(metronClient loggingclient.IngressClient) *PrometheusClient {
+	return &PrometheusClient{
+		IngressClient: metronClient,
+		registry:      prometheus.NewRegistry(),
+		counters:      map[string]*prometheus.CounterVec{},
+		gauges:        map[string]*prometheus.GaugeVec{},
+		histograms:    map[string]*prometheus.HistogramVec{},
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (c *PrometheusClient) Handler() http.Handler {
+	return promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+}
+
+func (c *PrometheusClient) IncrementCounter(name string) error {
+	c.counter(name).With(labelsFor(name, nil)).Inc()
+	return c.IngressClient.IncrementCounter(name)
+}
+
+func (c *PrometheusClient) IncrementCounterWithDelta(name string, delta uint64) error {
+	c.counter(name).With(labelsFor(name, nil)).Add(float64(delta))
+	return c.IngressClient.IncrementCounterWithDelta(name, delta)
+}
+
+func (c *PrometheusClient) IncrementLabeledCounter(name string, delta uint64, labels Labels) {
+	c.counter(name).With(labelsFor(name, labels.tags())).Add(float64(delta))
+}
+
+func (c *PrometheusClient) IncrementCounterWithLabels(name string, delta uint64, labels Labels) error {
+	c.counter(name).With(labelsFor(name, labels.tags())).Add(float64(delta))
+	return c.IngressClient.IncrementCounterWithDelta(name, delta)
+}
+
+func (c *PrometheusClient) SendMetric(name string, value int, opts ...loggregator.EmitGaugeOption) error {
+	c.gauge(name).With(labelsFor(name, tagsFrom(opts))).Set(float64(value))
+	return c.IngressClient.SendMetric(name, value, opts...)
+}
+
+func (c *PrometheusClient) SendDuration(name string, value time.Duration, opts ...loggregator.EmitGaugeOption) error {
+	c.histogram(name).With(labelsFor(name, tagsFrom(opts))).Observe(value.Seconds())
+	return c.IngressClient.SendDuration(name, value, opts...)
+}
+
+func (c *PrometheusClient) counter(name string) *prometheus.CounterVec {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	counter, ok := c.counters[name]
+	if !ok {
+		counter = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      metricName(name) + "_total",
+			Help:      name,
+		}, labelNames)
+		c.registry.MustRegister(counter)
+		c.counters[name] = counter
+	}
+	return counter
+}
+
+func (c *PrometheusClient) gauge(name string) *prometheus.GaugeVec {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	gauge, ok := c.gauges[name]
+	if !ok {
+		gauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      metricName(name),
+			Help:      name,
+		}, labelNames)
+		c.registry.MustRegister(gauge)
+		c.gauges[name] = gauge
+	}
+	return gauge
+}
+
+func (c *PrometheusClient) histogram(name string) *prometheus.HistogramVec {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	histogram, ok := c.histograms[name]
+	if !ok {
+		histogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      metricName(name) + "_seconds",
+			Help:      name,
+			Buckets:   durationBuckets,
+		}, labelNames)
+		c.registry.MustRegister(histogram)
+		c.histograms[name] = histogram
+	}
+	return histogram
+}
+
+func tagsFrom(opts []loggregator.EmitGaugeOption) map[string]string {
+	envelope := &loggregator_v2.Envelope{Tags: map[string]string{}}
+	for _, opt := range opts {
+		opt(envelope)
+	}
+	return envelope.Tags
+}
+
+func labelsFor(name string, tags map[string]string) prometheus.Labels {
+	labels := prometheus.Labels{
+		RouterTypeLabel:       routerTypes[name],
+		SubjectLabel:          "",
+		IsolationSegmentLabel: "",
+		TargetLabel:           "",
+	}
+	for _, label := range labelNames {
+		if value := tags[label]; value != "" {
+			labels[label] = value
+		}
+	}
+	return labels
+}
+
+var (
+	acronymBoundary = regexp.MustCompile("([A-Z]+)([A-Z][a-z])")
+	wordBoundary    = regexp.MustCompile("([a-z0-9])([A-Z])")
+)
+
+// metricName converts a Loggregator metric name to a Prometheus one, e.g.
+// HTTPRouteNATSMessagesEmitted becomes http_route_nats_messages_emitted.
+func metricName(name string) string {
+	name = acronymBoundary.ReplaceAllString(name, "${1}_${2}")
+	name = wordBoundary.ReplaceAllString(name, "${1}_${2}")
+	return strings.ToLower(name)
+}
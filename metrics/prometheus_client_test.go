@@ -0,0 +1,125 @@
+package metrics_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrometheusClient", func() {
+	var (
+		fakeMetronClient *mfakes.FakeIngressClient
+		client           *metrics.PrometheusClient
+	)
+
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		client.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		body, err := io.ReadAll(recorder.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		client = metrics.NewPrometheusClient(fakeMetronClient)
+	})
+
+	Describe("counters", func() {
+		It("reports to the metron client and prometheus", func() {
+			Expect(client.IncrementCounter("AddressCollisions")).To(Succeed())
+			Expect(client.IncrementCounterWithDelta("RoutesRegistered", 5)).To(Succeed())
+
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("AddressCollisions"))
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(1))
+
+			body := scrape()
+			Expect(body).To(ContainSubstring(`route_emitter_address_collisions_total{isolation_segment="",nats_target="",router_type="http",subject=""} 1`))
+			Expect(body).To(ContainSubstring(`route_emitter_routes_registered_total{isolation_segment="",nats_target="",router_type="http",subject=""} 5`))
+		})
+
+		It("returns the metron client error", func() {
+			fakeMetronClient.IncrementCounterReturns(errors.New("boom"))
+			Expect(client.IncrementCounter("AddressCollisions")).To(MatchError("boom"))
+		})
+
+		It("converts acronyms in the metric name", func() {
+			Expect(client.IncrementCounterWithDelta("HTTPRouteNATSMessagesEmitted", 2)).To(Succeed())
+			Expect(scrape()).To(ContainSubstring(`route_emitter_http_route_nats_messages_emitted_total{isolation_segment="",nats_target="",router_type="http",subject=""} 2`))
+		})
+	})
+
+	Describe("labeled counters", func() {
+		It("only reports to prometheus", func() {
+			metrics.IncrementLabeledCounter(client, "NATSMessagesEmitted", 3, metrics.Labels{
+				RouterType:       "http",
+				Subject:          "router.register",
+				IsolationSegment: "iso-seg",
+			})
+
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(0))
+			Expect(scrape()).To(ContainSubstring(`route_emitter_nats_messages_emitted_total{isolation_segment="iso-seg",nats_target="",router_type="http",subject="router.register"} 3`))
+		})
+
+		It("is a no-op for clients without label support", func() {
+			metrics.IncrementLabeledCounter(fakeMetronClient, "NATSMessagesEmitted", 3, metrics.Labels{})
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("counters with labels", func() {
+		It("reports the labels to prometheus and the aggregate to the metron client", func() {
+			Expect(metrics.IncrementCounterWithLabels(client, "NATSTargetMessagesEmitted", 3, metrics.Labels{Target: "new-cluster"})).To(Succeed())
+
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(1))
+			name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+			Expect(name).To(Equal("NATSTargetMessagesEmitted"))
+			Expect(delta).To(BeEquivalentTo(3))
+
+			body := scrape()
+			Expect(body).To(ContainSubstring(`route_emitter_nats_target_messages_emitted_total{isolation_segment="",nats_target="new-cluster",router_type="",subject=""} 3`))
+			Expect(body).NotTo(ContainSubstring(`route_emitter_nats_target_messages_emitted_total{isolation_segment="",nats_target="",router_type="",subject=""}`))
+		})
+
+		It("sends the aggregate for clients without label support", func() {
+			Expect(metrics.IncrementCounterWithLabels(fakeMetronClient, "NATSTargetMessagesEmitted", 3, metrics.Labels{Target: "new-cluster"})).To(Succeed())
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(1))
+		})
+	})
+
+	Describe("gauges", func() {
+		It("reports to the metron client and prometheus", func() {
+			Expect(client.SendMetric("TCPRouteCount", 7)).To(Succeed())
+			Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(1))
+			Expect(scrape()).To(ContainSubstring(`route_emitter_tcp_route_count{isolation_segment="",nats_target="",router_type="tcp",subject=""} 7`))
+		})
+
+		It("uses the labels passed as options", func() {
+			Expect(client.SendMetric("RoutesTotal", 4, metrics.WithLabels(metrics.Labels{IsolationSegment: "iso-seg"})...)).To(Succeed())
+
+			_, _, opts := fakeMetronClient.SendMetricArgsForCall(0)
+			Expect(opts).To(HaveLen(1))
+			Expect(scrape()).To(ContainSubstring(`route_emitter_routes_total{isolation_segment="iso-seg",nats_target="",router_type="http",subject=""} 4`))
+		})
+	})
+
+	Describe("durations", func() {
+		It("reports to the metron client and a prometheus histogram", func() {
+			Expect(client.SendDuration("RouteEmitterSyncDuration", 2*time.Second)).To(Succeed())
+			Expect(fakeMetronClient.SendDurationCallCount()).To(Equal(1))
+
+			body := scrape()
+			Expect(body).To(ContainSubstring(`route_emitter_route_emitter_sync_duration_seconds_count{isolation_segment="",nats_target="",router_type="",subject=""} 1`))
+			Expect(body).To(ContainSubstring(`route_emitter_route_emitter_sync_duration_seconds_sum{isolation_segment="",nats_target="",router_type="",subject=""} 2`))
+		})
+	})
+})
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"time"
+
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	loggregator "code.cloudfoundry.org/go-loggregator/v8"
+)
+
+// TargetClient is the metron client of the components that emit to an
+// additional NATS target. They report the same counters as the components of
+// the primary NATS cluster, so its counters are only reported to Prometheus,
+// labeled with the target, and the counters sent to Loggregator keep
+// describing the primary cluster. Gauges and durations are tagged with the
+// target.
+type TargetClient struct {
+	loggingclient.IngressClient
+	target string
+}
+
+func NewTargetClient(client loggingclient.IngressClient, target string) *TargetClient {
+	return &TargetClient{IngressClient: client, target: target}
+}
+
+func (c *TargetClient) IncrementCounter(name string) error {
+	return c.IncrementCounterWithDelta(name, 1)
+}
+
+func (c *TargetClient) IncrementCounterWithDelta(name string, delta uint64) error {
+	c.IncrementLabeledCounter(name, delta, Labels{})
+	return nil
+}
+
+func (c *TargetClient) IncrementLabeledCounter(name string, delta uint64, labels Labels) {
+	labels.Target = c.target
+	IncrementLabeledCounter(c.IngressClient, name, delta, labels)
+}
+
+func (c *TargetClient) SendMetric(name string, value int, opts ...loggregator.EmitGaugeOption) error {
+	return c.IngressClient.SendMetric(name, value, c.withTarget(opts)...)
+}
+
+func (c *TargetClient) SendDuration(name string, value time.Duration, opts ...loggregator.EmitGaugeOption) error {
+	return c.IngressClient.SendDuration(name, value, c.withTarget(opts)...)
+}
+
+func (c *TargetClient) withTarget(opts []loggregator.EmitGaugeOption) []loggregator.EmitGaugeOption {
+	return append(append([]loggregator.EmitGaugeOption{}, opts...), loggregator.WithEnvelopeTag(TargetLabel, c.target))
+}
@@ -0,0 +1,60 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TargetClient", func() {
+	var (
+		fakeMetronClient *mfakes.FakeIngressClient
+		prometheusClient *metrics.PrometheusClient
+		client           *metrics.TargetClient
+	)
+
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		prometheusClient.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		body, err := io.ReadAll(recorder.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		prometheusClient = metrics.NewPrometheusClient(fakeMetronClient)
+		client = metrics.NewTargetClient(prometheusClient, "new-cluster")
+	})
+
+	It("only reports the counters to prometheus, labeled with the target", func() {
+		Expect(client.IncrementCounter("EmitTicksDropped")).To(Succeed())
+		Expect(client.IncrementCounterWithDelta("HTTPRouteNATSMessagesEmitted", 4)).To(Succeed())
+		metrics.IncrementLabeledCounter(client, "NATSMessagesEmitted", 2, metrics.Labels{RouterType: "http", Subject: "router.register"})
+
+		Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+		Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(0))
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`route_emitter_emit_ticks_dropped_total{isolation_segment="",nats_target="new-cluster",router_type="",subject=""} 1`))
+		Expect(body).To(ContainSubstring(`route_emitter_http_route_nats_messages_emitted_total{isolation_segment="",nats_target="new-cluster",router_type="http",subject=""} 4`))
+		Expect(body).To(ContainSubstring(`route_emitter_nats_messages_emitted_total{isolation_segment="",nats_target="new-cluster",router_type="http",subject="router.register"} 2`))
+	})
+
+	It("tags the gauges and durations with the target", func() {
+		Expect(client.SendMetric("NATSConnected", 1)).To(Succeed())
+		Expect(client.SendDuration("RouteEmitterSyncDuration", time.Second)).To(Succeed())
+
+		_, _, opts := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(opts).To(HaveLen(1))
+		_, _, opts = fakeMetronClient.SendDurationArgsForCall(0)
+		Expect(opts).To(HaveLen(1))
+		Expect(scrape()).To(ContainSubstring(`route_emitter_nats_connected{isolation_segment="",nats_target="new-cluster",router_type="",subject=""} 1`))
+	})
+})
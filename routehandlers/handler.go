@@ -2,6 +2,7 @@ package routehandlers
 
 import (
 	"errors"
+	"sync"
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/bbs/trace"
@@ -9,8 +10,11 @@ import (
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/route-emitter/emitter"
 	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/scheduler"
 	"code.cloudfoundry.org/route-emitter/unregistration"
 	"code.cloudfoundry.org/route-emitter/watcher"
+	tcpmodels "code.cloudfoundry.org/routing-api/models"
+	"github.com/mitchellh/hashstructure"
 )
 
 const (
@@ -20,6 +24,7 @@ const (
 	routesUnregisteredCounter = "RoutesUnregistered"
 	httpRouteCount            = "HTTPRouteCount"
 	tcpRouteCount             = "TCPRouteCount"
+	refusedSwapsCounter       = "RoutingTableSwapsRefused"
 )
 
 type Handler struct {
@@ -29,25 +34,60 @@ type Handler struct {
 	localMode           bool
 	metronClient        loggingclient.IngressClient
 	unregistrationCache unregistration.Cache
+
+	// refreshes the http routes in the routing api before their ttl expires,
+	// nil when http routes are only emitted over nats
+	httpRoutingAPIEmitter emitter.NATSEmitter
+
+	// percentage of http routes a single sync may remove before the swap is
+	// deferred until the next sync confirms it, zero disables the guard
+	massUnregistrationThreshold int
+	// set with the hash of the unregistrations of a refused swap, only a swap
+	// with the same unregistrations confirms it
+	massUnregistrationPending bool
+	massUnregistrationHash    uint64
+
+	// number of shards the periodic external emission is split into, each
+	// tick of the scheduler emits the next shard
+	emitShards int
+	nextShard  int
+
+	// deletes the tcp route mappings the emitter no longer desires after each
+	// sync, nil disables reconciliation
+	tcpReconciler emitter.TCPReconciler
+
+	// set when the next external emit has to cover the whole table, e.g.
+	// after a nats reconnect
+	fullEmitLock    sync.Mutex
+	fullEmitPending bool
 }
 
 var _ watcher.RouteHandler = new(Handler)
+var _ scheduler.FullEmitRequester = new(Handler)
 
 func NewHandler(
 	routingTable routingtable.RoutingTable,
 	natsEmitter emitter.NATSEmitter,
 	routingAPIEmitter emitter.RoutingAPIEmitter,
+	httpRoutingAPIEmitter emitter.NATSEmitter,
 	localMode bool,
 	metronClient loggingclient.IngressClient,
 	unregistrationCache unregistration.Cache,
+	massUnregistrationThreshold int,
+	emitShards int,
+	tcpReconciler emitter.TCPReconciler,
 ) *Handler {
 	return &Handler{
-		routingTable:        routingTable,
-		natsEmitter:         natsEmitter,
-		routingAPIEmitter:   routingAPIEmitter,
-		localMode:           localMode,
-		metronClient:        metronClient,
-		unregistrationCache: unregistrationCache,
+		routingTable:                routingTable,
+		natsEmitter:                 natsEmitter,
+		routingAPIEmitter:           routingAPIEmitter,
+		httpRoutingAPIEmitter:       httpRoutingAPIEmitter,
+		localMode:                   localMode,
+		metronClient:                metronClient,
+		unregistrationCache:         unregistrationCache,
+		massUnregistrationThreshold: massUnregistrationThreshold,
+		emitShards:                  emitShards,
+		tcpReconciler:               tcpReconciler,
 	}
 }
 
@@ -102,6 +142,17 @@ func (handler *Handler) HandleEvent(logger lager.Logger, event models.Event) {
 func (handler *Handler) EmitExternal(logger lager.Logger) {
 	routingEvents, messagesToEmit := handler.routingTable.GetExternalRoutingEvents()
 
+	// the whole table is read on every tick so that a shard never registers
+	// a route that was removed since the start of the interval, a requested
+	// full emit is never sharded
+	if handler.emitShards > 1 && !handler.takeFullEmit() {
+		shard := handler.nextShard
+		handler.nextShard = (shard + 1) % handler.emitShards
+		routingEvents = routingEvents.Shard(shard, handler.emitShards)
+		messagesToEmit = messagesToEmit.Shard(shard, handler.emitShards)
+		logger = logger.WithData(lager.Data{"shard": shard, "shards": handler.emitShards})
+	}
+
 	logger.Debug("emitting-nats-messages", lager.Data{"messages": messagesToEmit})
 	if handler.natsEmitter != nil {
 		err := handler.natsEmitter.Emit(messagesToEmit)
@@ -128,6 +179,22 @@ func (handler *Handler) EmitExternal(logger lager.Logger) {
 	}
 }
 
+// EmitTCP refreshes the tcp route mappings in the routing api without
+// emitting anything on NATS.
+func (handler *Handler) EmitTCP(logger lager.Logger) {
+	if handler.routingAPIEmitter == nil {
+		return
+	}
+
+	routingEvents, _ := handler.routingTable.GetExternalRoutingEvents()
+
+	logger.Debug("emitting-routing-api-messages", lager.Data{"messages": routingEvents})
+	err := handler.routingAPIEmitter.Emit(routingEvents)
+	if err != nil {
+		logger.Error("failed-to-emit-tcp-routes", err)
+	}
+}
+
 func (handler *Handler) EmitInternal(logger lager.Logger) {
 	_, messagesToEmit := handler.routingTable.GetInternalRoutingEvents()
 
@@ -152,7 +219,7 @@ func (handler *Handler) Sync(
 	defer logger.Debug("completed")
 
 	nullLogger := lager.NewLogger("null-logger") // ignore log messsages from the routing table
-	newTable := routingtable.NewRoutingTable(false, handler.metronClient)
+	newTable := routingtable.NewSyncRoutingTable(false, handler.metronClient)
 
 	for _, lrp := range desired {
 		newTable.SetRoutes(nullLogger, nil, lrp)
@@ -178,18 +245,27 @@ func (handler *Handler) Sync(
 	handler.natsEmitter = natsEmitter
 	handler.routingAPIEmitter = routingAPIEmitter
 
-	routeMappings, messages := handler.routingTable.Swap(nullLogger, newTable, domains)
+	routeMappings, messages, swapped := handler.swap(logger, nullLogger, newTable, domains)
+	if !swapped {
+		// the cached events were only applied to the refused table, apply
+		// them to the live one so they are not lost until the next event
+		for _, event := range cachedEvents {
+			handler.HandleEvent(logger, event)
+		}
+		return
+	}
+
 	logger.Debug("start-emitting-messages", lager.Data{
 		"num-registration-messages":            len(messages.RegistrationMessages),
 		"num-unregistration-messages":          len(messages.UnregistrationMessages),
 		"num-internal-registration-messages":   len(messages.InternalRegistrationMessages),
 		"num-internal-unregistration-messages": len(messages.InternalUnregistrationMessages),
 	})
-	err := handler.unregistrationCache.Add(messages.UnregistrationMessages)
+	err := handler.cacheUnregistrations(messages, routeMappings)
 	if err != nil {
 		logger.Error("failed-to-add-messages-to-cache", err, lager.Data{"messages": messages.UnregistrationMessages})
 	}
-	err = handler.unregistrationCache.Remove(messages.RegistrationMessages)
+	err = handler.uncacheRegistrations(messages, routeMappings)
 	if err != nil {
 		logger.Error("failed-to-remove-messages-from-cache", err, lager.Data{"messages": messages.RegistrationMessages})
 	}
@@ -211,6 +287,136 @@ func (handler *Handler) Sync(
 			logger.Error("failed-to-send-tcp-route-count-metric", err)
 		}
 	}
+
+	handler.reconcileTCPRoutes(logger)
+}
+
+// EmitHTTP refreshes the http routes in the routing api without emitting
+// anything on NATS, so that they do not expire while no router greets.
+func (handler *Handler) EmitHTTP(logger lager.Logger) {
+	if handler.httpRoutingAPIEmitter == nil {
+		return
+	}
+
+	_, messagesToEmit := handler.routingTable.GetExternalRoutingEvents()
+	registrations := routingtable.MessagesToEmit{RegistrationMessages: messagesToEmit.RegistrationMessages}
+
+	logger.Debug("emitting-routing-api-http-routes", lager.Data{"messages": registrations})
+	err := handler.httpRoutingAPIEmitter.Emit(registrations)
+	if err != nil {
+		logger.Error("failed-to-emit-http-routes", err)
+	}
+}
+
+// reconcileTCPRoutes runs after a swap, when the routing table reflects the
+// BBS and its tcp mappings can be trusted as the complete desired state.
+func (handler *Handler) reconcileTCPRoutes(logger lager.Logger) {
+	if handler.tcpReconciler == nil {
+		return
+	}
+
+	routingEvents, _ := handler.routingTable.GetExternalRoutingEvents()
+	err := handler.tcpReconciler.Reconcile(routingEvents)
+	if err != nil {
+		logger.Error("failed-to-reconcile-tcp-routes", err)
+	}
+}
+
+// swap swaps the new table into the live one, guarded by confirmSwap when a
+// mass unregistration threshold is configured.
+func (handler *Handler) swap(logger, tableLogger lager.Logger, newTable routingtable.RoutingTable, domains models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, bool) {
+	if handler.massUnregistrationThreshold <= 0 {
+		routeMappings, messages := handler.routingTable.Swap(tableLogger, newTable, domains)
+		return routeMappings, messages, true
+	}
+
+	// counted up front, the table is locked while the swap is confirmed
+	currentCount := handler.routingTable.HTTPAssociationsCount() + handler.routingTable.TCPAssociationsCount()
+	return handler.routingTable.SwapIf(tableLogger, newTable, domains, func(routeMappings routingtable.TCPRouteMappings, messages routingtable.MessagesToEmit) bool {
+		return handler.confirmSwap(logger, currentCount, routeMappings, messages)
+	})
+}
+
+// confirmSwap refuses to swap in a table that unregisters more than the
+// configured percentage of the http and tcp routes, unless the previous sync
+// refused the same unregistrations. This prevents a truncated response from
+// the BBS from unregistering most routes. The unregistrations are the ones of
+// the swap itself, a drop hidden by as many new routes is still refused.
+func (handler *Handler) confirmSwap(logger lager.Logger, currentCount int, routeMappings routingtable.TCPRouteMappings, messages routingtable.MessagesToEmit) bool {
+	if currentCount == 0 {
+		handler.massUnregistrationPending = false
+		return true
+	}
+
+	unregistrationCount := len(messages.UnregistrationMessages) + len(routeMappings.Unregistrations)
+
+	dropPercent := unregistrationCount * 100 / currentCount
+	if dropPercent <= handler.massUnregistrationThreshold {
+		handler.massUnregistrationPending = false
+		return true
+	}
+
+	data := lager.Data{
+		"current-route-count": currentCount,
+		"unregistrations":     unregistrationCount,
+		"drop-percent":        dropPercent,
+		"threshold-percent":   handler.massUnregistrationThreshold,
+	}
+
+	hash, err := hashstructure.Hash(unregistrationSet{
+		HTTP: messages.UnregistrationMessages,
+		TCP:  tcpMappingKeys(routeMappings.Unregistrations),
+	}, nil)
+	if err != nil {
+		// should never happen, refuse the swap without confirming a later one
+		logger.Error("failed-to-hash-unregistrations", err)
+		handler.massUnregistrationPending = false
+		return false
+	}
+
+	if handler.massUnregistrationPending && handler.massUnregistrationHash == hash {
+		logger.Info("mass-unregistration-confirmed", data)
+		handler.massUnregistrationPending = false
+		return true
+	}
+
+	logger.Info("refusing-to-swap-routing-table", data)
+	handler.massUnregistrationPending = true
+	handler.massUnregistrationHash = hash
+	err = handler.metronClient.IncrementCounter(refusedSwapsCounter)
+	if err != nil {
+		logger.Error("failed-to-send-refused-swaps-metric", err)
+	}
+	return false
+}
+
+// unregistrationSet identifies the routes a swap unregisters, regardless of
+// the order of the messages.
+type unregistrationSet struct {
+	HTTP []routingtable.RegistryMessage `hash:"set"`
+	TCP  []tcpMappingKey                `hash:"set"`
+}
+
+// tcpMappingKey holds the fields that identify a tcp route mapping, the ttl
+// and modification tag are ignored.
+type tcpMappingKey struct {
+	RouterGroupGuid string
+	ExternalPort    uint16
+	HostIP          string
+	HostPort        uint16
+}
+
+func tcpMappingKeys(mappings []tcpmodels.TcpRouteMapping) []tcpMappingKey {
+	keys := make([]tcpMappingKey, 0, len(mappings))
+	for _, mapping := range mappings {
+		keys = append(keys, tcpMappingKey{
+			RouterGroupGuid: mapping.RouterGroupGuid,
+			ExternalPort:    mapping.ExternalPort,
+			HostIP:          mapping.HostIP,
+			HostPort:        mapping.HostPort,
+		})
+	}
+	return keys
 }
 
 func (handler *Handler) RefreshDesired(logger lager.Logger, desiredLRPs []*models.DesiredLRP) {
@@ -231,11 +437,11 @@ func (handler *Handler) handleDesiredCreate(logger lager.Logger, desiredLRP *mod
 
 func (handler *Handler) handleDesiredUpdate(logger lager.Logger, before, after *models.DesiredLRP) error {
 	routeMappings, messagesToEmit := handler.routingTable.SetRoutes(logger, before, after)
-	err := handler.unregistrationCache.Add(messagesToEmit.UnregistrationMessages)
+	err := handler.cacheUnregistrations(messagesToEmit, routeMappings)
 	if err != nil {
 		return err
 	}
-	err = handler.unregistrationCache.Remove(messagesToEmit.RegistrationMessages)
+	err = handler.uncacheRegistrations(messagesToEmit, routeMappings)
 	if err != nil {
 		return err
 	}
@@ -273,7 +479,7 @@ func (handler *Handler) handleActualUpdate(logger lager.Logger, before, after *m
 	case before.State == models.ActualLRPStateRunning && after.State != models.ActualLRPStateRunning:
 		routeMappings, messagesToEmit = handler.routingTable.RemoveEndpoint(logger, before)
 	}
-	err := handler.unregistrationCache.Remove(messagesToEmit.RegistrationMessages)
+	err := handler.uncacheRegistrations(messagesToEmit, routeMappings)
 	if err != nil {
 		return err
 	}
@@ -289,6 +495,34 @@ func (handler *Handler) handleActualDelete(logger lager.Logger, actualLRP *model
 	handler.emitMessages(logger, messagesToEmit, routeMappings)
 }
 
+// cacheUnregistrations adds the unregistrations of every route type to the
+// cache, so that they are sent again in case one is lost.
+func (handler *Handler) cacheUnregistrations(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error {
+	err := handler.unregistrationCache.Add(messagesToEmit.UnregistrationMessages)
+	if err != nil {
+		return err
+	}
+	err = handler.unregistrationCache.AddInternal(messagesToEmit.InternalUnregistrationMessages)
+	if err != nil {
+		return err
+	}
+	return handler.unregistrationCache.AddTCP(routeMappings.Unregistrations)
+}
+
+// uncacheRegistrations stops sending unregistrations for the routes that are
+// registered again.
+func (handler *Handler) uncacheRegistrations(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error {
+	err := handler.unregistrationCache.Remove(messagesToEmit.RegistrationMessages)
+	if err != nil {
+		return err
+	}
+	err = handler.unregistrationCache.RemoveInternal(messagesToEmit.InternalRegistrationMessages)
+	if err != nil {
+		return err
+	}
+	return handler.unregistrationCache.RemoveTCP(routeMappings.Registrations)
+}
+
 func (handler *Handler) emitMessages(logger lager.Logger, messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) {
 	if handler.natsEmitter != nil {
 		logger.Debug("emit-messages", lager.Data{"messages": messagesToEmit})
@@ -315,3 +549,19 @@ func (handler *Handler) emitMessages(logger lager.Logger, messagesToEmit routing
 		}
 	}
 }
+
+// RequestFullEmit makes the next external emit cover the whole table instead
+// of one shard.
+func (handler *Handler) RequestFullEmit() {
+	handler.fullEmitLock.Lock()
+	defer handler.fullEmitLock.Unlock()
+	handler.fullEmitPending = true
+}
+
+func (handler *Handler) takeFullEmit() bool {
+	handler.fullEmitLock.Lock()
+	defer handler.fullEmitLock.Unlock()
+	fullEmit := handler.fullEmitPending
+	handler.fullEmitPending = false
+	return fullEmit
+}
@@ -11,6 +11,7 @@ import (
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
 	"code.cloudfoundry.org/route-emitter/emitter/fakes"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
@@ -22,6 +23,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
 )
 
 const (
@@ -124,7 +126,7 @@ var _ = Describe("Handler", func() {
 
 		fakeUnregistrationCache = &ufakes.FakeCache{}
 
-		routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, fakeRoutingAPIEmitter, false, fakeMetronClient, fakeUnregistrationCache)
+		routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, fakeRoutingAPIEmitter, nil, false, fakeMetronClient, fakeUnregistrationCache, 0, 1, nil)
 	})
 
 	Context("when an unrecognized event is received", func() {
@@ -311,6 +313,40 @@ var _ = Describe("Handler", func() {
 				})
 			})
 
+			Context("when internal and tcp routes are unregistered and registered", func() {
+				var (
+					unregisteredMapping, registeredMapping tcpmodels.TcpRouteMapping
+				)
+
+				BeforeEach(func() {
+					unregisteredMapping = tcpmodels.NewTcpRouteMapping("router-group-guid", 61000, "1.1.1.1", 62000, 0)
+					registeredMapping = tcpmodels.NewTcpRouteMapping("router-group-guid", 61001, "1.1.1.1", 62001, 0)
+					messagesToEmit := routingtable.MessagesToEmit{
+						InternalUnregistrationMessages: []routingtable.RegistryMessage{dummyMessageFoo},
+						InternalRegistrationMessages:   []routingtable.RegistryMessage{dummyMessageBar},
+					}
+					routeMappings := routingtable.TCPRouteMappings{
+						Unregistrations: []tcpmodels.TcpRouteMapping{unregisteredMapping},
+						Registrations:   []tcpmodels.TcpRouteMapping{registeredMapping},
+					}
+					fakeTable.SetRoutesReturns(routeMappings, messagesToEmit)
+				})
+
+				It("caches the unregistrations by route type", func() {
+					Expect(fakeUnregistrationCache.AddInternalCallCount()).To(Equal(1))
+					Expect(fakeUnregistrationCache.AddInternalArgsForCall(0)).To(ConsistOf(dummyMessageFoo))
+					Expect(fakeUnregistrationCache.AddTCPCallCount()).To(Equal(1))
+					Expect(fakeUnregistrationCache.AddTCPArgsForCall(0)).To(ConsistOf(unregisteredMapping))
+				})
+
+				It("removes the registrations from the cache by route type", func() {
+					Expect(fakeUnregistrationCache.RemoveInternalCallCount()).To(Equal(1))
+					Expect(fakeUnregistrationCache.RemoveInternalArgsForCall(0)).To(ConsistOf(dummyMessageBar))
+					Expect(fakeUnregistrationCache.RemoveTCPCallCount()).To(Equal(1))
+					Expect(fakeUnregistrationCache.RemoveTCPArgsForCall(0)).To(ConsistOf(registeredMapping))
+				})
+			})
+
 			Context("when there are diego ssh-keys on the route", func() {
 				BeforeEach(func() {
 					diegoSSHInfo := json.RawMessage([]byte(`{"ssh-key": "ssh-value"}`))
@@ -1141,7 +1177,7 @@ var _ = Describe("Handler", func() {
 
 			Context("when emitting metrics in localMode", func() {
 				BeforeEach(func() {
-					routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, true, fakeMetronClient, fakeUnregistrationCache)
+					routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, nil, true, fakeMetronClient, fakeUnregistrationCache, 0, 1, nil)
 					fakeTable.HTTPAssociationsCountReturns(5)
 				})
 
@@ -1154,6 +1190,154 @@ var _ = Describe("Handler", func() {
 				})
 			})
 
+			Context("when a mass unregistration threshold is configured", func() {
+				var swaps int
+
+				dryRunUnregistersFrom := func(first, httpCount, tcpCount int) {
+					var mappings routingtable.TCPRouteMappings
+					var messages routingtable.MessagesToEmit
+					for i := first; i < first+httpCount; i++ {
+						messages.UnregistrationMessages = append(messages.UnregistrationMessages, routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: fmt.Sprintf("host-%d", i)}, false))
+					}
+					for i := first; i < first+tcpCount; i++ {
+						mappings.Unregistrations = append(mappings.Unregistrations, tcpmodels.NewTcpRouteMapping("router-group-guid", uint16(61000+i), "1.1.1.1", 62000, 0))
+					}
+					fakeTable.SwapIfStub = func(_ lager.Logger, _ routingtable.RoutingTable, _ models.DomainSet, accept func(routingtable.TCPRouteMappings, routingtable.MessagesToEmit) bool) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, bool) {
+						if !accept(mappings, messages) {
+							return mappings, messages, false
+						}
+						swaps++
+						return mappings, messages, true
+					}
+				}
+				dryRunUnregisters := func(httpCount, tcpCount int) {
+					dryRunUnregistersFrom(0, httpCount, tcpCount)
+				}
+
+				BeforeEach(func() {
+					swaps = 0
+					routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, nil, false, fakeMetronClient, fakeUnregistrationCache, 50, 1, nil)
+					fakeTable.HTTPAssociationsCountReturns(6)
+					fakeTable.TCPAssociationsCountReturns(4)
+				})
+
+				Context("and the new table drops less routes than the threshold", func() {
+					BeforeEach(func() {
+						dryRunUnregisters(3, 2)
+					})
+
+					It("swaps the routing table", func() {
+						routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+						Expect(fakeTable.SwapIfCallCount()).To(Equal(1))
+						_, _, swapDomains, _ := fakeTable.SwapIfArgsForCall(0)
+						Expect(swapDomains).To(Equal(domains))
+						Expect(swaps).To(Equal(1))
+						Expect(fakeTable.SwapCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("and the new table drops more routes than the threshold", func() {
+					BeforeEach(func() {
+						dryRunUnregisters(5, 4)
+						routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+					})
+
+					It("refuses to swap the routing table", func() {
+						Expect(swaps).To(Equal(0))
+						Expect(natsEmitter.EmitCallCount()).To(Equal(0))
+						Expect(fakeUnregistrationCache.AddCallCount()).To(Equal(0))
+					})
+
+					It("logs the decision", func() {
+						Expect(logger).To(gbytes.Say("refusing-to-swap-routing-table"))
+						Expect(logger).To(gbytes.Say(`"drop-percent":90`))
+					})
+
+					It("emits a metric", func() {
+						Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+						Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("RoutingTableSwapsRefused"))
+					})
+
+					Context("and the next sync confirms the drop", func() {
+						BeforeEach(func() {
+							routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+						})
+
+						It("swaps the routing table", func() {
+							Expect(swaps).To(Equal(1))
+							Expect(logger).To(gbytes.Say("mass-unregistration-confirmed"))
+						})
+					})
+
+					Context("and the next sync drops different routes", func() {
+						BeforeEach(func() {
+							dryRunUnregistersFrom(100, 5, 4)
+							routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+						})
+
+						It("refuses the drop again", func() {
+							Expect(swaps).To(Equal(0))
+							Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(2))
+						})
+
+						It("swaps when the sync after that drops the same routes", func() {
+							routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+							Expect(swaps).To(Equal(1))
+						})
+					})
+
+					Context("and the next sync does not confirm the drop", func() {
+						BeforeEach(func() {
+							dryRunUnregisters(1, 0)
+							routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+							dryRunUnregisters(5, 4)
+							routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+						})
+
+						It("refuses the following drop again", func() {
+							Expect(swaps).To(Equal(1))
+							Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(2))
+						})
+					})
+				})
+
+				Context("and an event arrives during a refused sync", func() {
+					var desiredLRP *models.DesiredLRP
+
+					BeforeEach(func() {
+						dryRunUnregisters(9, 0)
+						fakeTable.SetRoutesReturns(emptyTCPRouteMappings, routingtable.MessagesToEmit{
+							RegistrationMessages: []routingtable.RegistryMessage{
+								routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: "new-host"}, false),
+							},
+						})
+
+						routes := cfroutes.CFRoutes{
+							cfroutes.CFRoute{Hostnames: []string{"new-host"}, Port: 8080},
+						}.RoutingInfo()
+						desiredLRP = &models.DesiredLRP{ProcessGuid: "pg-new", Routes: &routes, Instances: 1}
+						desiredLRPEvent := models.NewDesiredLRPCreatedEvent(desiredLRP, "some-trace-id")
+
+						routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, map[string]models.Event{
+							desiredLRPEvent.Key(): desiredLRPEvent,
+						})
+					})
+
+					It("applies the event to the live routing table", func() {
+						Expect(swaps).To(Equal(0))
+						Expect(fakeTable.SetRoutesCallCount()).To(Equal(1))
+						_, before, after := fakeTable.SetRoutesArgsForCall(0)
+						Expect(before).To(BeNil())
+						Expect(after).To(Equal(desiredLRP))
+					})
+
+					It("emits the messages of the event", func() {
+						Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+						Expect(natsEmitter.EmitArgsForCall(0).RegistrationMessages).To(HaveLen(1))
+					})
+				})
+			})
+
 			Context("when NATS events are cached", func() {
 				BeforeEach(func() {
 					routes := cfroutes.CFRoutes{
@@ -1273,6 +1457,84 @@ var _ = Describe("Handler", func() {
 				delta: 3,
 			})))
 		})
+
+		Context("when the emission is split into shards", func() {
+			const shards = 3
+
+			BeforeEach(func() {
+				routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, fakeRoutingAPIEmitter, nil, false, fakeMetronClient, fakeUnregistrationCache, 0, shards, nil)
+			})
+
+			It("emits the next shard of a freshly read table on every call", func() {
+				for shard := 0; shard < shards; shard++ {
+					routeHandler.EmitExternal(logger)
+					Expect(fakeTable.GetExternalRoutingEventsCallCount()).To(Equal(shard + 1))
+					Expect(natsEmitter.EmitArgsForCall(shard)).To(Equal(registrationMsgs.Shard(shard, shards)))
+				}
+
+				routeHandler.EmitExternal(logger)
+				Expect(natsEmitter.EmitArgsForCall(shards)).To(Equal(registrationMsgs.Shard(0, shards)))
+			})
+
+			It("refreshes every route once per round", func() {
+				var emitted []routingtable.RegistryMessage
+				for shard := 0; shard < shards; shard++ {
+					routeHandler.EmitExternal(logger)
+					emitted = append(emitted, natsEmitter.EmitArgsForCall(shard).RegistrationMessages...)
+				}
+				Expect(emitted).To(ConsistOf(registrationMsgs.RegistrationMessages))
+			})
+
+			It("emits the whole table when the standby gate is activated", func() {
+				gate := emitter.NewStandbyGate()
+				routeHandler = routehandlers.NewHandler(fakeTable, gate.NATSEmitter(natsEmitter), fakeRoutingAPIEmitter, nil, false, fakeMetronClient, fakeUnregistrationCache, 0, shards, nil)
+				routeHandler.EmitExternal(logger)
+				Expect(natsEmitter.EmitCallCount()).To(Equal(0))
+
+				emitChan := make(chan struct{}, 1)
+				process := ifrit.Invoke(gate.Activator(logger, routeHandler.RequestFullEmit, emitChan))
+				defer func() {
+					ifrit.Interrupt(process)
+					Eventually(process.Wait()).Should(Receive())
+				}()
+
+				Expect(emitChan).To(Receive())
+				routeHandler.EmitExternal(logger)
+				Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(registrationMsgs))
+			})
+
+			It("emits the whole table when the emit queue overflows", func() {
+				emitChan := make(chan struct{}, 1)
+				asyncEmitter := emitter.NewAsyncNATSEmitter(logger, natsEmitter, &mfakes.FakeIngressClient{}, 1, emitter.TriggerFullEmit(func() {
+					routeHandler.RequestFullEmit()
+				}, emitChan))
+				routeHandler = routehandlers.NewHandler(fakeTable, asyncEmitter, fakeRoutingAPIEmitter, nil, false, fakeMetronClient, fakeUnregistrationCache, 0, shards, nil)
+
+				for _, message := range registrationMsgs.RegistrationMessages[:2] {
+					asyncEmitter.Emit(routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{message}})
+				}
+				Expect(emitChan).To(Receive())
+
+				routeHandler.EmitExternal(logger)
+				process := ifrit.Invoke(asyncEmitter)
+				defer func() {
+					ifrit.Interrupt(process)
+					Eventually(process.Wait()).Should(Receive())
+				}()
+
+				Eventually(natsEmitter.EmitCallCount).Should(Equal(1))
+				Expect(natsEmitter.EmitArgsForCall(0).RegistrationMessages).To(ConsistOf(registrationMsgs.RegistrationMessages))
+			})
+
+			It("emits the whole table once when a full emit is requested", func() {
+				routeHandler.RequestFullEmit()
+				routeHandler.EmitExternal(logger)
+				Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(registrationMsgs))
+
+				routeHandler.EmitExternal(logger)
+				Expect(natsEmitter.EmitArgsForCall(1)).To(Equal(registrationMsgs.Shard(0, shards)))
+			})
+		})
 	})
 
 	Describe("EmitInternal", func() {
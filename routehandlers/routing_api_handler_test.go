@@ -36,7 +36,7 @@ var _ = Describe("RoutingAPIHandler", func() {
 		fakeRoutingAPIEmitter = new(emitterfakes.FakeRoutingAPIEmitter)
 		fakeMetronClient = &mfakes.FakeIngressClient{}
 		fakeUnregistrationCache = &ufakes.FakeCache{}
-		routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, false, fakeMetronClient, fakeUnregistrationCache)
+		routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, nil, false, fakeMetronClient, fakeUnregistrationCache, 0, 1, nil)
 	})
 
 	Describe("DesiredLRP Event", func() {
@@ -566,7 +566,7 @@ var _ = Describe("RoutingAPIHandler", func() {
 						}
 						return nil
 					}
-					routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, true, fakeMetronClient, fakeUnregistrationCache)
+					routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, nil, true, fakeMetronClient, fakeUnregistrationCache, 0, 1, nil)
 					fakeRoutingTable.TCPAssociationsCountReturns(1)
 				})
 
@@ -650,6 +650,42 @@ var _ = Describe("RoutingAPIHandler", func() {
 					Expect(fakeRoutingAPIEmitter.EmitCallCount()).To(Equal(1))
 				})
 			})
+
+			Context("when a tcp reconciler is configured", func() {
+				var (
+					fakeTCPReconciler *emitterfakes.FakeTCPReconciler
+					events            routingtable.TCPRouteMappings
+				)
+
+				BeforeEach(func() {
+					fakeTCPReconciler = new(emitterfakes.FakeTCPReconciler)
+					events = routingtable.TCPRouteMappings{
+						Registrations: []tcpmodels.TcpRouteMapping{
+							tcpmodels.NewTcpRouteMapping("router-group-guid", 61000, "some-ip", 61006, 0),
+						},
+					}
+					fakeRoutingTable.GetExternalRoutingEventsReturns(events, emptyNatsMessages)
+					routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, nil, false, fakeMetronClient, fakeUnregistrationCache, 50, 1, fakeTCPReconciler)
+				})
+
+				It("reconciles the mappings of the swapped table", func() {
+					routeHandler.Sync(logger, desiredLRPs, actualLRPs, nil, nil)
+					Expect(fakeTCPReconciler.ReconcileCallCount()).To(Equal(1))
+					Expect(fakeTCPReconciler.ReconcileArgsForCall(0)).To(Equal(events))
+				})
+
+				Context("when the swap is refused", func() {
+					BeforeEach(func() {
+						fakeRoutingTable.HTTPAssociationsCountReturns(10)
+					})
+
+					It("does not reconcile", func() {
+						routeHandler.Sync(logger, desiredLRPs, actualLRPs, nil, nil)
+						Expect(fakeRoutingTable.SwapCallCount()).To(Equal(0))
+						Expect(fakeTCPReconciler.ReconcileCallCount()).To(Equal(0))
+					})
+				})
+			})
 		})
 	})
 
@@ -667,4 +703,73 @@ var _ = Describe("RoutingAPIHandler", func() {
 			Expect(fakeRoutingAPIEmitter.EmitArgsForCall(0)).To(Equal(events))
 		})
 	})
+
+	Describe("EmitTCP", func() {
+		var events routingtable.TCPRouteMappings
+		BeforeEach(func() {
+			events = routingtable.TCPRouteMappings{
+				Registrations: []tcpmodels.TcpRouteMapping{
+					tcpmodels.NewTcpRouteMapping("router-group-guid", 5222, "1.1.1.1", 61000, 0),
+				},
+			}
+			fakeRoutingTable.GetExternalRoutingEventsReturns(events, routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.1", Port: 61001}},
+			})
+		})
+
+		It("only emits the tcp route mappings", func() {
+			routeHandler.EmitTCP(logger)
+			Expect(fakeRoutingAPIEmitter.EmitCallCount()).To(Equal(1))
+			Expect(fakeRoutingAPIEmitter.EmitArgsForCall(0)).To(Equal(events))
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(0))
+		})
+
+		Context("when there is no routing api emitter", func() {
+			BeforeEach(func() {
+				routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, nil, nil, false, fakeMetronClient, fakeUnregistrationCache, 0, 1, nil)
+			})
+
+			It("does nothing", func() {
+				routeHandler.EmitTCP(logger)
+				Expect(fakeRoutingTable.GetExternalRoutingEventsCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("EmitHTTP", func() {
+		var httpEmitter *emitterfakes.FakeNATSEmitter
+
+		BeforeEach(func() {
+			httpEmitter = new(emitterfakes.FakeNATSEmitter)
+			routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, httpEmitter, false, fakeMetronClient, fakeUnregistrationCache, 0, 1, nil)
+			fakeRoutingTable.GetExternalRoutingEventsReturns(routingtable.TCPRouteMappings{
+				Registrations: []tcpmodels.TcpRouteMapping{
+					tcpmodels.NewTcpRouteMapping("router-group-guid", 5222, "1.1.1.1", 61000, 0),
+				},
+			}, routingtable.MessagesToEmit{
+				RegistrationMessages:   []routingtable.RegistryMessage{{Host: "1.1.1.1", Port: 61001}},
+				UnregistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.1", Port: 61002}},
+			})
+		})
+
+		It("only refreshes the http route registrations", func() {
+			routeHandler.EmitHTTP(logger)
+			Expect(httpEmitter.EmitCallCount()).To(Equal(1))
+			Expect(httpEmitter.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.1", Port: 61001}},
+			}))
+			Expect(fakeRoutingAPIEmitter.EmitCallCount()).To(Equal(0))
+		})
+
+		Context("when http routes are not emitted to the routing api", func() {
+			BeforeEach(func() {
+				routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, nil, false, fakeMetronClient, fakeUnregistrationCache, 0, 1, nil)
+			})
+
+			It("does nothing", func() {
+				routeHandler.EmitHTTP(logger)
+				Expect(fakeRoutingTable.GetExternalRoutingEventsCallCount()).To(Equal(0))
+			})
+		})
+	})
 })
@@ -0,0 +1,127 @@
+package routingtable
+
+import (
+	"fmt"
+	"sort"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+// EntryFilter restricts the entries returned by RoutingTable.Entries. Empty
+// fields match every entry; non-empty fields must all match.
+type EntryFilter struct {
+	ProcessGUID     string
+	Hostname        string
+	RouterGroupGUID string
+}
+
+// Entry is a read-only copy of a single routing key's routes and endpoints.
+type Entry struct {
+	RoutingKey       RoutingKey
+	Domain           string
+	Routes           []Route                `json:",omitempty"`
+	TCPRoutes        []ExternalEndpointInfo `json:",omitempty"`
+	InternalRoutes   []InternalRoute        `json:",omitempty"`
+	Endpoints        []Endpoint
+	DesiredInstances int32
+	ModificationTag  *models.ModificationTag
+}
+
+// TableEntries holds the entries of the http, tcp and internal routing tables
+// keyed by the string form of their routing key.
+type TableEntries struct {
+	HTTP     map[string]Entry
+	TCP      map[string]Entry
+	Internal map[string]Entry
+}
+
+func (key RoutingKey) String() string {
+	return fmt.Sprintf("%s:%d", key.ProcessGUID, key.ContainerPort)
+}
+
+func (filter EntryFilter) matches(key RoutingKey, entry Entry) bool {
+	if filter.ProcessGUID != "" && filter.ProcessGUID != key.ProcessGUID {
+		return false
+	}
+
+	if filter.Hostname != "" {
+		found := false
+		for _, route := range entry.Routes {
+			found = found || route.Hostname == filter.Hostname
+		}
+		for _, route := range entry.InternalRoutes {
+			found = found || route.Hostname == filter.Hostname
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if filter.RouterGroupGUID != "" {
+		found := false
+		for _, route := range entry.TCPRoutes {
+			found = found || route.RouterGroupGUID == filter.RouterGroupGUID
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newEntry(key RoutingKey, routableEndpoints RoutableEndpoints) Entry {
+	entry := Entry{
+		RoutingKey:       key,
+		Domain:           routableEndpoints.Domain,
+		Endpoints:        []Endpoint{},
+		DesiredInstances: routableEndpoints.DesiredInstances,
+		ModificationTag:  routableEndpoints.ModificationTag,
+	}
+
+	for _, route := range routableEndpoints.Routes {
+		switch route := route.(type) {
+		case Route:
+			entry.Routes = append(entry.Routes, route)
+		case ExternalEndpointInfo:
+			entry.TCPRoutes = append(entry.TCPRoutes, route)
+		case InternalRoute:
+			entry.InternalRoutes = append(entry.InternalRoutes, route)
+		}
+	}
+
+	for _, endpoint := range routableEndpoints.Endpoints {
+		entry.Endpoints = append(entry.Endpoints, endpoint)
+	}
+	sort.Slice(entry.Endpoints, func(i, j int) bool {
+		if entry.Endpoints[i].InstanceGUID != entry.Endpoints[j].InstanceGUID {
+			return entry.Endpoints[i].InstanceGUID < entry.Endpoints[j].InstanceGUID
+		}
+		return entry.Endpoints[i].Presence < entry.Endpoints[j].Presence
+	})
+
+	return entry
+}
+
+func (t *internalRoutingTable) Entries(filter EntryFilter) map[string]Entry {
+	t.Lock()
+	defer t.Unlock()
+
+	entries := map[string]Entry{}
+	for key, routableEndpoints := range t.entries {
+		entry := newEntry(key, routableEndpoints)
+		if filter.matches(key, entry) {
+			entries[key.String()] = entry
+		}
+	}
+
+	return entries
+}
+
+func (t *routingTable) Entries(filter EntryFilter) TableEntries {
+	return TableEntries{
+		HTTP:     t.httpRoutesRoutingTable.Entries(filter),
+		TCP:      t.tcpRoutesRoutingTable.Entries(filter),
+		Internal: t.internalRoutesRoutingTable.Entries(filter),
+	}
+}
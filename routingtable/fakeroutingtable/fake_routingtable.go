@@ -24,6 +24,17 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
+	EntriesStub        func(routingtable.EntryFilter) routingtable.TableEntries
+	entriesMutex       sync.RWMutex
+	entriesArgsForCall []struct {
+		arg1 routingtable.EntryFilter
+	}
+	entriesReturns struct {
+		result1 routingtable.TableEntries
+	}
+	entriesReturnsOnCall map[int]struct {
+		result1 routingtable.TableEntries
+	}
 	GetExternalRoutingEventsStub        func() (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
 	getExternalRoutingEventsMutex       sync.RWMutex
 	getExternalRoutingEventsArgsForCall []struct {
@@ -69,6 +80,16 @@ type FakeRoutingTable struct {
 	hasExternalRoutesReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	HostnameConflictsStub        func() []routingtable.HostnameConflict
+	hostnameConflictsMutex       sync.RWMutex
+	hostnameConflictsArgsForCall []struct {
+	}
+	hostnameConflictsReturns struct {
+		result1 []routingtable.HostnameConflict
+	}
+	hostnameConflictsReturnsOnCall map[int]struct {
+		result1 []routingtable.HostnameConflict
+	}
 	InternalAssociationsCountStub        func() int
 	internalAssociationsCountMutex       sync.RWMutex
 	internalAssociationsCountArgsForCall []struct {
@@ -79,6 +100,17 @@ type FakeRoutingTable struct {
 	internalAssociationsCountReturnsOnCall map[int]struct {
 		result1 int
 	}
+	LookupInternalRouteStub        func(string) []routingtable.InternalEndpoint
+	lookupInternalRouteMutex       sync.RWMutex
+	lookupInternalRouteArgsForCall []struct {
+		arg1 string
+	}
+	lookupInternalRouteReturns struct {
+		result1 []routingtable.InternalEndpoint
+	}
+	lookupInternalRouteReturnsOnCall map[int]struct {
+		result1 []routingtable.InternalEndpoint
+	}
 	RemoveEndpointStub        func(lager.Logger, *models.ActualLRP) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
 	removeEndpointMutex       sync.RWMutex
 	removeEndpointArgsForCall []struct {
@@ -107,6 +139,18 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
+	RestoreStub        func(lager.Logger, routingtable.Snapshot) error
+	restoreMutex       sync.RWMutex
+	restoreArgsForCall []struct {
+		arg1 lager.Logger
+		arg2 routingtable.Snapshot
+	}
+	restoreReturns struct {
+		result1 error
+	}
+	restoreReturnsOnCall map[int]struct {
+		result1 error
+	}
 	SetRoutesStub        func(lager.Logger, *models.DesiredLRP, *models.DesiredLRP) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
 	setRoutesMutex       sync.RWMutex
 	setRoutesArgsForCall []struct {
@@ -122,6 +166,16 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
+	SnapshotStub        func() routingtable.Snapshot
+	snapshotMutex       sync.RWMutex
+	snapshotArgsForCall []struct {
+	}
+	snapshotReturns struct {
+		result1 routingtable.Snapshot
+	}
+	snapshotReturnsOnCall map[int]struct {
+		result1 routingtable.Snapshot
+	}
 	SwapStub        func(lager.Logger, routingtable.RoutingTable, models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
 	swapMutex       sync.RWMutex
 	swapArgsForCall []struct {
@@ -137,6 +191,24 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
+	SwapIfStub        func(lager.Logger, routingtable.RoutingTable, models.DomainSet, func(routingtable.TCPRouteMappings, routingtable.MessagesToEmit) bool) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, bool)
+	swapIfMutex       sync.RWMutex
+	swapIfArgsForCall []struct {
+		arg1 lager.Logger
+		arg2 routingtable.RoutingTable
+		arg3 models.DomainSet
+		arg4 func(routingtable.TCPRouteMappings, routingtable.MessagesToEmit) bool
+	}
+	swapIfReturns struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+		result3 bool
+	}
+	swapIfReturnsOnCall map[int]struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+		result3 bool
+	}
 	TCPAssociationsCountStub        func() int
 	tCPAssociationsCountMutex       sync.RWMutex
 	tCPAssociationsCountArgsForCall []struct {
@@ -225,6 +297,66 @@ func (fake *FakeRoutingTable) AddEndpointReturnsOnCall(i int, result1 routingtab
 	}{result1, result2}
 }
 
+func (fake *FakeRoutingTable) Entries(arg1 routingtable.EntryFilter) routingtable.TableEntries {
+	fake.entriesMutex.Lock()
+	ret, specificReturn := fake.entriesReturnsOnCall[len(fake.entriesArgsForCall)]
+	fake.entriesArgsForCall = append(fake.entriesArgsForCall, struct {
+		arg1 routingtable.EntryFilter
+	}{arg1})
+	fake.recordInvocation("Entries", []interface{}{arg1})
+	fake.entriesMutex.Unlock()
+	if fake.EntriesStub != nil {
+		return fake.EntriesStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.entriesReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) EntriesCallCount() int {
+	fake.entriesMutex.RLock()
+	defer fake.entriesMutex.RUnlock()
+	return len(fake.entriesArgsForCall)
+}
+
+func (fake *FakeRoutingTable) EntriesCalls(stub func(routingtable.EntryFilter) routingtable.TableEntries) {
+	fake.entriesMutex.Lock()
+	defer fake.entriesMutex.Unlock()
+	fake.EntriesStub = stub
+}
+
+func (fake *FakeRoutingTable) EntriesArgsForCall(i int) routingtable.EntryFilter {
+	fake.entriesMutex.RLock()
+	defer fake.entriesMutex.RUnlock()
+	argsForCall := fake.entriesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRoutingTable) EntriesReturns(result1 routingtable.TableEntries) {
+	fake.entriesMutex.Lock()
+	defer fake.entriesMutex.Unlock()
+	fake.EntriesStub = nil
+	fake.entriesReturns = struct {
+		result1 routingtable.TableEntries
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) EntriesReturnsOnCall(i int, result1 routingtable.TableEntries) {
+	fake.entriesMutex.Lock()
+	defer fake.entriesMutex.Unlock()
+	fake.EntriesStub = nil
+	if fake.entriesReturnsOnCall == nil {
+		fake.entriesReturnsOnCall = make(map[int]struct {
+			result1 routingtable.TableEntries
+		})
+	}
+	fake.entriesReturnsOnCall[i] = struct {
+		result1 routingtable.TableEntries
+	}{result1}
+}
+
 func (fake *FakeRoutingTable) GetExternalRoutingEvents() (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
 	fake.getExternalRoutingEventsMutex.Lock()
 	ret, specificReturn := fake.getExternalRoutingEventsReturnsOnCall[len(fake.getExternalRoutingEventsArgsForCall)]
@@ -447,6 +579,58 @@ func (fake *FakeRoutingTable) HasExternalRoutesReturnsOnCall(i int, result1 bool
 	}{result1}
 }
 
+func (fake *FakeRoutingTable) HostnameConflicts() []routingtable.HostnameConflict {
+	fake.hostnameConflictsMutex.Lock()
+	ret, specificReturn := fake.hostnameConflictsReturnsOnCall[len(fake.hostnameConflictsArgsForCall)]
+	fake.hostnameConflictsArgsForCall = append(fake.hostnameConflictsArgsForCall, struct {
+	}{})
+	fake.recordInvocation("HostnameConflicts", []interface{}{})
+	fake.hostnameConflictsMutex.Unlock()
+	if fake.HostnameConflictsStub != nil {
+		return fake.HostnameConflictsStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.hostnameConflictsReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) HostnameConflictsCallCount() int {
+	fake.hostnameConflictsMutex.RLock()
+	defer fake.hostnameConflictsMutex.RUnlock()
+	return len(fake.hostnameConflictsArgsForCall)
+}
+
+func (fake *FakeRoutingTable) HostnameConflictsCalls(stub func() []routingtable.HostnameConflict) {
+	fake.hostnameConflictsMutex.Lock()
+	defer fake.hostnameConflictsMutex.Unlock()
+	fake.HostnameConflictsStub = stub
+}
+
+func (fake *FakeRoutingTable) HostnameConflictsReturns(result1 []routingtable.HostnameConflict) {
+	fake.hostnameConflictsMutex.Lock()
+	defer fake.hostnameConflictsMutex.Unlock()
+	fake.HostnameConflictsStub = nil
+	fake.hostnameConflictsReturns = struct {
+		result1 []routingtable.HostnameConflict
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) HostnameConflictsReturnsOnCall(i int, result1 []routingtable.HostnameConflict) {
+	fake.hostnameConflictsMutex.Lock()
+	defer fake.hostnameConflictsMutex.Unlock()
+	fake.HostnameConflictsStub = nil
+	if fake.hostnameConflictsReturnsOnCall == nil {
+		fake.hostnameConflictsReturnsOnCall = make(map[int]struct {
+			result1 []routingtable.HostnameConflict
+		})
+	}
+	fake.hostnameConflictsReturnsOnCall[i] = struct {
+		result1 []routingtable.HostnameConflict
+	}{result1}
+}
+
 func (fake *FakeRoutingTable) InternalAssociationsCount() int {
 	fake.internalAssociationsCountMutex.Lock()
 	ret, specificReturn := fake.internalAssociationsCountReturnsOnCall[len(fake.internalAssociationsCountArgsForCall)]
@@ -499,6 +683,66 @@ func (fake *FakeRoutingTable) InternalAssociationsCountReturnsOnCall(i int, resu
 	}{result1}
 }
 
+func (fake *FakeRoutingTable) LookupInternalRoute(arg1 string) []routingtable.InternalEndpoint {
+	fake.lookupInternalRouteMutex.Lock()
+	ret, specificReturn := fake.lookupInternalRouteReturnsOnCall[len(fake.lookupInternalRouteArgsForCall)]
+	fake.lookupInternalRouteArgsForCall = append(fake.lookupInternalRouteArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("LookupInternalRoute", []interface{}{arg1})
+	fake.lookupInternalRouteMutex.Unlock()
+	if fake.LookupInternalRouteStub != nil {
+		return fake.LookupInternalRouteStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.lookupInternalRouteReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) LookupInternalRouteCallCount() int {
+	fake.lookupInternalRouteMutex.RLock()
+	defer fake.lookupInternalRouteMutex.RUnlock()
+	return len(fake.lookupInternalRouteArgsForCall)
+}
+
+func (fake *FakeRoutingTable) LookupInternalRouteCalls(stub func(string) []routingtable.InternalEndpoint) {
+	fake.lookupInternalRouteMutex.Lock()
+	defer fake.lookupInternalRouteMutex.Unlock()
+	fake.LookupInternalRouteStub = stub
+}
+
+func (fake *FakeRoutingTable) LookupInternalRouteArgsForCall(i int) string {
+	fake.lookupInternalRouteMutex.RLock()
+	defer fake.lookupInternalRouteMutex.RUnlock()
+	argsForCall := fake.lookupInternalRouteArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRoutingTable) LookupInternalRouteReturns(result1 []routingtable.InternalEndpoint) {
+	fake.lookupInternalRouteMutex.Lock()
+	defer fake.lookupInternalRouteMutex.Unlock()
+	fake.LookupInternalRouteStub = nil
+	fake.lookupInternalRouteReturns = struct {
+		result1 []routingtable.InternalEndpoint
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) LookupInternalRouteReturnsOnCall(i int, result1 []routingtable.InternalEndpoint) {
+	fake.lookupInternalRouteMutex.Lock()
+	defer fake.lookupInternalRouteMutex.Unlock()
+	fake.LookupInternalRouteStub = nil
+	if fake.lookupInternalRouteReturnsOnCall == nil {
+		fake.lookupInternalRouteReturnsOnCall = make(map[int]struct {
+			result1 []routingtable.InternalEndpoint
+		})
+	}
+	fake.lookupInternalRouteReturnsOnCall[i] = struct {
+		result1 []routingtable.InternalEndpoint
+	}{result1}
+}
+
 func (fake *FakeRoutingTable) RemoveEndpoint(arg1 lager.Logger, arg2 *models.ActualLRP) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
 	fake.removeEndpointMutex.Lock()
 	ret, specificReturn := fake.removeEndpointReturnsOnCall[len(fake.removeEndpointArgsForCall)]
@@ -627,6 +871,67 @@ func (fake *FakeRoutingTable) RemoveRoutesReturnsOnCall(i int, result1 routingta
 	}{result1, result2}
 }
 
+func (fake *FakeRoutingTable) Restore(arg1 lager.Logger, arg2 routingtable.Snapshot) error {
+	fake.restoreMutex.Lock()
+	ret, specificReturn := fake.restoreReturnsOnCall[len(fake.restoreArgsForCall)]
+	fake.restoreArgsForCall = append(fake.restoreArgsForCall, struct {
+		arg1 lager.Logger
+		arg2 routingtable.Snapshot
+	}{arg1, arg2})
+	fake.recordInvocation("Restore", []interface{}{arg1, arg2})
+	fake.restoreMutex.Unlock()
+	if fake.RestoreStub != nil {
+		return fake.RestoreStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.restoreReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) RestoreCallCount() int {
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	return len(fake.restoreArgsForCall)
+}
+
+func (fake *FakeRoutingTable) RestoreCalls(stub func(lager.Logger, routingtable.Snapshot) error) {
+	fake.restoreMutex.Lock()
+	defer fake.restoreMutex.Unlock()
+	fake.RestoreStub = stub
+}
+
+func (fake *FakeRoutingTable) RestoreArgsForCall(i int) (lager.Logger, routingtable.Snapshot) {
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	argsForCall := fake.restoreArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoutingTable) RestoreReturns(result1 error) {
+	fake.restoreMutex.Lock()
+	defer fake.restoreMutex.Unlock()
+	fake.RestoreStub = nil
+	fake.restoreReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RestoreReturnsOnCall(i int, result1 error) {
+	fake.restoreMutex.Lock()
+	defer fake.restoreMutex.Unlock()
+	fake.RestoreStub = nil
+	if fake.restoreReturnsOnCall == nil {
+		fake.restoreReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.restoreReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRoutingTable) SetRoutes(arg1 lager.Logger, arg2 *models.DesiredLRP, arg3 *models.DesiredLRP) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
 	fake.setRoutesMutex.Lock()
 	ret, specificReturn := fake.setRoutesReturnsOnCall[len(fake.setRoutesArgsForCall)]
@@ -692,6 +997,58 @@ func (fake *FakeRoutingTable) SetRoutesReturnsOnCall(i int, result1 routingtable
 	}{result1, result2}
 }
 
+func (fake *FakeRoutingTable) Snapshot() routingtable.Snapshot {
+	fake.snapshotMutex.Lock()
+	ret, specificReturn := fake.snapshotReturnsOnCall[len(fake.snapshotArgsForCall)]
+	fake.snapshotArgsForCall = append(fake.snapshotArgsForCall, struct {
+	}{})
+	fake.recordInvocation("Snapshot", []interface{}{})
+	fake.snapshotMutex.Unlock()
+	if fake.SnapshotStub != nil {
+		return fake.SnapshotStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.snapshotReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) SnapshotCallCount() int {
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	return len(fake.snapshotArgsForCall)
+}
+
+func (fake *FakeRoutingTable) SnapshotCalls(stub func() routingtable.Snapshot) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = stub
+}
+
+func (fake *FakeRoutingTable) SnapshotReturns(result1 routingtable.Snapshot) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = nil
+	fake.snapshotReturns = struct {
+		result1 routingtable.Snapshot
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) SnapshotReturnsOnCall(i int, result1 routingtable.Snapshot) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = nil
+	if fake.snapshotReturnsOnCall == nil {
+		fake.snapshotReturnsOnCall = make(map[int]struct {
+			result1 routingtable.Snapshot
+		})
+	}
+	fake.snapshotReturnsOnCall[i] = struct {
+		result1 routingtable.Snapshot
+	}{result1}
+}
+
 func (fake *FakeRoutingTable) Swap(arg1 lager.Logger, arg2 routingtable.RoutingTable, arg3 models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
 	fake.swapMutex.Lock()
 	ret, specificReturn := fake.swapReturnsOnCall[len(fake.swapArgsForCall)]
@@ -757,6 +1114,75 @@ func (fake *FakeRoutingTable) SwapReturnsOnCall(i int, result1 routingtable.TCPR
 	}{result1, result2}
 }
 
+func (fake *FakeRoutingTable) SwapIf(arg1 lager.Logger, arg2 routingtable.RoutingTable, arg3 models.DomainSet, arg4 func(routingtable.TCPRouteMappings, routingtable.MessagesToEmit) bool) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, bool) {
+	fake.swapIfMutex.Lock()
+	ret, specificReturn := fake.swapIfReturnsOnCall[len(fake.swapIfArgsForCall)]
+	fake.swapIfArgsForCall = append(fake.swapIfArgsForCall, struct {
+		arg1 lager.Logger
+		arg2 routingtable.RoutingTable
+		arg3 models.DomainSet
+		arg4 func(routingtable.TCPRouteMappings, routingtable.MessagesToEmit) bool
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("SwapIf", []interface{}{arg1, arg2, arg3, arg4})
+	fake.swapIfMutex.Unlock()
+	if fake.SwapIfStub != nil {
+		return fake.SwapIfStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	fakeReturns := fake.swapIfReturns
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeRoutingTable) SwapIfCallCount() int {
+	fake.swapIfMutex.RLock()
+	defer fake.swapIfMutex.RUnlock()
+	return len(fake.swapIfArgsForCall)
+}
+
+func (fake *FakeRoutingTable) SwapIfCalls(stub func(lager.Logger, routingtable.RoutingTable, models.DomainSet, func(routingtable.TCPRouteMappings, routingtable.MessagesToEmit) bool) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, bool)) {
+	fake.swapIfMutex.Lock()
+	defer fake.swapIfMutex.Unlock()
+	fake.SwapIfStub = stub
+}
+
+func (fake *FakeRoutingTable) SwapIfArgsForCall(i int) (lager.Logger, routingtable.RoutingTable, models.DomainSet, func(routingtable.TCPRouteMappings, routingtable.MessagesToEmit) bool) {
+	fake.swapIfMutex.RLock()
+	defer fake.swapIfMutex.RUnlock()
+	argsForCall := fake.swapIfArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeRoutingTable) SwapIfReturns(result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit, result3 bool) {
+	fake.swapIfMutex.Lock()
+	defer fake.swapIfMutex.Unlock()
+	fake.SwapIfStub = nil
+	fake.swapIfReturns = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+		result3 bool
+	}{result1, result2, result3}
+}
+
+func (fake *FakeRoutingTable) SwapIfReturnsOnCall(i int, result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit, result3 bool) {
+	fake.swapIfMutex.Lock()
+	defer fake.swapIfMutex.Unlock()
+	fake.SwapIfStub = nil
+	if fake.swapIfReturnsOnCall == nil {
+		fake.swapIfReturnsOnCall = make(map[int]struct {
+			result1 routingtable.TCPRouteMappings
+			result2 routingtable.MessagesToEmit
+			result3 bool
+		})
+	}
+	fake.swapIfReturnsOnCall[i] = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+		result3 bool
+	}{result1, result2, result3}
+}
+
 func (fake *FakeRoutingTable) TCPAssociationsCount() int {
 	fake.tCPAssociationsCountMutex.Lock()
 	ret, specificReturn := fake.tCPAssociationsCountReturnsOnCall[len(fake.tCPAssociationsCountArgsForCall)]
@@ -866,6 +1292,8 @@ func (fake *FakeRoutingTable) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.addEndpointMutex.RLock()
 	defer fake.addEndpointMutex.RUnlock()
+	fake.entriesMutex.RLock()
+	defer fake.entriesMutex.RUnlock()
 	fake.getExternalRoutingEventsMutex.RLock()
 	defer fake.getExternalRoutingEventsMutex.RUnlock()
 	fake.getInternalRoutingEventsMutex.RLock()
@@ -874,16 +1302,26 @@ func (fake *FakeRoutingTable) Invocations() map[string][][]interface{} {
 	defer fake.hTTPAssociationsCountMutex.RUnlock()
 	fake.hasExternalRoutesMutex.RLock()
 	defer fake.hasExternalRoutesMutex.RUnlock()
+	fake.hostnameConflictsMutex.RLock()
+	defer fake.hostnameConflictsMutex.RUnlock()
 	fake.internalAssociationsCountMutex.RLock()
 	defer fake.internalAssociationsCountMutex.RUnlock()
+	fake.lookupInternalRouteMutex.RLock()
+	defer fake.lookupInternalRouteMutex.RUnlock()
 	fake.removeEndpointMutex.RLock()
 	defer fake.removeEndpointMutex.RUnlock()
 	fake.removeRoutesMutex.RLock()
 	defer fake.removeRoutesMutex.RUnlock()
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
 	fake.setRoutesMutex.RLock()
 	defer fake.setRoutesMutex.RUnlock()
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
 	fake.swapMutex.RLock()
 	defer fake.swapMutex.RUnlock()
+	fake.swapIfMutex.RLock()
+	defer fake.swapIfMutex.RUnlock()
 	fake.tCPAssociationsCountMutex.RLock()
 	defer fake.tCPAssociationsCountMutex.RUnlock()
 	fake.tableSizeMutex.RLock()
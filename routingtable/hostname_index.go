@@ -0,0 +1,175 @@
+package routingtable
+
+import (
+	"sort"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const hostnameConflictsCounter = "HostnameConflicts"
+
+// HostnameClaim is a routing key that has a route for a hostname.
+type HostnameClaim struct {
+	RoutingKey RoutingKey
+	LogGUID    string
+}
+
+// HostnameConflict is a hostname that is claimed by more than one process
+// guid with different log guids, i.e. by unrelated apps.
+type HostnameConflict struct {
+	Hostname string
+	Claims   []HostnameClaim
+}
+
+type hostnameIndex map[string]map[RoutingKey]string
+
+func hostnamesFrom(entry RoutableEndpoints) map[string]string {
+	hostnames := map[string]string{}
+	for _, route := range entry.Routes {
+		if route, ok := route.(Route); ok {
+			hostnames[route.Hostname] = route.LogGUID
+		}
+	}
+	return hostnames
+}
+
+func newHostnameIndex(entries map[RoutingKey]RoutableEndpoints) hostnameIndex {
+	index := hostnameIndex{}
+	for key, entry := range entries {
+		for hostname, logGUID := range hostnamesFrom(entry) {
+			index.add(hostname, key, logGUID)
+		}
+	}
+	return index
+}
+
+func (index hostnameIndex) add(hostname string, key RoutingKey, logGUID string) {
+	if index[hostname] == nil {
+		index[hostname] = map[RoutingKey]string{}
+	}
+	index[hostname][key] = logGUID
+}
+
+func (index hostnameIndex) remove(hostname string, key RoutingKey) {
+	delete(index[hostname], key)
+	if len(index[hostname]) == 0 {
+		delete(index, hostname)
+	}
+}
+
+// conflicted returns whether unrelated processes claim the hostname
+func (index hostnameIndex) conflicted(hostname string) bool {
+	for key, logGUID := range index[hostname] {
+		if len(index.conflicting(hostname, key, logGUID)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// claims returns all claims on the hostname, sorted by routing key
+func (index hostnameIndex) claims(hostname string) []HostnameClaim {
+	claims := []HostnameClaim{}
+	for key, logGUID := range index[hostname] {
+		claims = append(claims, HostnameClaim{RoutingKey: key, LogGUID: logGUID})
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		return claims[i].RoutingKey.String() < claims[j].RoutingKey.String()
+	})
+	return claims
+}
+
+// conflicting returns the claims on the hostname that belong to a different
+// process guid and log guid than the given ones
+func (index hostnameIndex) conflicting(hostname string, key RoutingKey, logGUID string) []HostnameClaim {
+	claims := []HostnameClaim{}
+	for otherKey, otherLogGUID := range index[hostname] {
+		if otherKey.ProcessGUID != key.ProcessGUID && otherLogGUID != logGUID {
+			claims = append(claims, HostnameClaim{RoutingKey: otherKey, LogGUID: otherLogGUID})
+		}
+	}
+	return claims
+}
+
+func (table *internalRoutingTable) updateHostnameEntries(logger lager.Logger, key RoutingKey, oldEntry, newEntry RoutableEndpoints) {
+	if !table.detectHostnameConflicts {
+		return
+	}
+
+	oldHostnames := hostnamesFrom(oldEntry)
+	newHostnames := hostnamesFrom(newEntry)
+
+	for hostname := range oldHostnames {
+		if _, ok := newHostnames[hostname]; !ok {
+			table.hostnameEntries.remove(hostname, key)
+		}
+	}
+
+	for hostname, logGUID := range newHostnames {
+		if _, ok := oldHostnames[hostname]; !ok {
+			conflicts := table.hostnameEntries.conflicting(hostname, key, logGUID)
+			if len(conflicts) > 0 && table.reportHostnameConflicts {
+				table.reportHostnameConflict(logger, lager.Data{
+					"hostname":     hostname,
+					"process-guid": key.ProcessGUID,
+					"log-guid":     logGUID,
+					"conflicts":    conflicts,
+				})
+			}
+		}
+		table.hostnameEntries.add(hostname, key, logGUID)
+	}
+}
+
+// rebuildHostnameEntries replaces the index after the entries were replaced
+// by a swap or a restore. Only the hostnames that were not in conflict
+// before are reported, so that a sync does not report the same conflicts
+// again.
+func (table *internalRoutingTable) rebuildHostnameEntries(logger lager.Logger) {
+	if !table.detectHostnameConflicts {
+		return
+	}
+
+	previous := table.hostnameEntries
+	table.hostnameEntries = newHostnameIndex(table.entries)
+	if !table.reportHostnameConflicts {
+		return
+	}
+
+	for hostname := range table.hostnameEntries {
+		if table.hostnameEntries.conflicted(hostname) && !previous.conflicted(hostname) {
+			table.reportHostnameConflict(logger, lager.Data{
+				"hostname": hostname,
+				"claims":   table.hostnameEntries.claims(hostname),
+			})
+		}
+	}
+}
+
+func (table *internalRoutingTable) reportHostnameConflict(logger lager.Logger, data lager.Data) {
+	if table.conflictLogger != nil {
+		logger = table.conflictLogger
+	}
+	err := table.metronClient.IncrementCounter(hostnameConflictsCounter)
+	if err != nil {
+		logger.Error("failed-to-increment-hostname-conflicts-counter", err)
+	}
+	logger.Info("hostname-conflict-detected", data)
+}
+
+func (table *internalRoutingTable) HostnameConflicts() []HostnameConflict {
+	table.Lock()
+	defer table.Unlock()
+
+	conflicts := []HostnameConflict{}
+	for hostname := range table.hostnameEntries {
+		if table.hostnameEntries.conflicted(hostname) {
+			conflicts = append(conflicts, HostnameConflict{Hostname: hostname, Claims: table.hostnameEntries.claims(hostname)})
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Hostname < conflicts[j].Hostname
+	})
+	return conflicts
+}
@@ -0,0 +1,181 @@
+package routingtable_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("HostnameConflicts", func() {
+	var (
+		table            routingtable.RoutingTable
+		logger           *lagertest.TestLogger
+		fakeMetronClient *mfakes.FakeIngressClient
+	)
+
+	hostname := "foo.example.com"
+	currentTag := models.ModificationTag{Epoch: "abc", Index: 1}
+	newerTag := models.ModificationTag{Epoch: "abc", Index: 2}
+	runInfo := models.DesiredLRPRunInfo{}
+
+	keyA := routingtable.RoutingKey{ProcessGUID: "process-guid-a", ContainerPort: 8080}
+	keyB := routingtable.RoutingKey{ProcessGUID: "process-guid-b", ContainerPort: 8080}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		table = routingtable.NewRoutingTable(false, fakeMetronClient)
+
+		table.SetRoutes(logger, nil, createDesiredLRP(keyA.ProcessGUID, 1, keyA.ContainerPort, "log-guid-a", "", currentTag, runInfo, hostname))
+	})
+
+	It("does not report a hostname claimed by a single process", func() {
+		Expect(table.HostnameConflicts()).To(BeEmpty())
+		Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+	})
+
+	Context("when another process with a different log guid claims the hostname", func() {
+		var desiredB *models.DesiredLRP
+
+		BeforeEach(func() {
+			desiredB = createDesiredLRP(keyB.ProcessGUID, 1, keyB.ContainerPort, "log-guid-b", "", currentTag, runInfo, hostname)
+			table.SetRoutes(logger, nil, desiredB)
+		})
+
+		It("reports the conflict", func() {
+			Expect(table.HostnameConflicts()).To(Equal([]routingtable.HostnameConflict{
+				{
+					Hostname: hostname,
+					Claims: []routingtable.HostnameClaim{
+						{RoutingKey: keyA, LogGUID: "log-guid-a"},
+						{RoutingKey: keyB, LogGUID: "log-guid-b"},
+					},
+				},
+			}))
+		})
+
+		It("emits a metric", func() {
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("HostnameConflicts"))
+		})
+
+		It("logs the conflict", func() {
+			Expect(logger).To(Say("hostname-conflict-detected"))
+			Expect(logger).To(Say(`"hostname":"foo.example.com"`))
+		})
+
+		It("does not report the conflict again when the conflicting routes are updated", func() {
+			updatedB := createDesiredLRP(keyB.ProcessGUID, 2, keyB.ContainerPort, "log-guid-b", "", newerTag, runInfo, hostname)
+			table.SetRoutes(logger, desiredB, updatedB)
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+		})
+
+		Context("when the conflicting routes are removed", func() {
+			BeforeEach(func() {
+				table.RemoveRoutes(logger, desiredB)
+			})
+
+			It("no longer reports the conflict", func() {
+				Expect(table.HostnameConflicts()).To(BeEmpty())
+			})
+		})
+
+		It("logs when the metric cannot be sent", func() {
+			fakeMetronClient.IncrementCounterReturns(errors.New("boom"))
+			table.SetRoutes(logger, nil, createDesiredLRP("process-guid-c", 1, 8080, "log-guid-c", "", currentTag, runInfo, hostname))
+			Expect(logger).To(Say("failed-to-increment-hostname-conflicts-counter"))
+		})
+
+		Context("when the table is swapped", func() {
+			It("rebuilds the index from the new table", func() {
+				tempTable := routingtable.NewSyncRoutingTable(false, fakeMetronClient)
+				tempTable.SetRoutes(logger, nil, createDesiredLRP(keyA.ProcessGUID, 1, keyA.ContainerPort, "log-guid-a", "", currentTag, runInfo, hostname))
+				table.Swap(logger, tempTable, models.NewDomainSet([]string{"domain"}))
+
+				Expect(table.HostnameConflicts()).To(BeEmpty())
+			})
+
+			It("does not report the conflict again", func() {
+				tempTable := routingtable.NewSyncRoutingTable(false, fakeMetronClient)
+				tempTable.SetRoutes(logger, nil, createDesiredLRP(keyA.ProcessGUID, 1, keyA.ContainerPort, "log-guid-a", "", currentTag, runInfo, hostname))
+				tempTable.SetRoutes(logger, nil, desiredB)
+				table.Swap(logger, tempTable, models.NewDomainSet([]string{"domain"}))
+
+				Expect(table.HostnameConflicts()).To(HaveLen(1))
+				Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Context("when a sync brings in a new conflict", func() {
+		It("reports it once when the table is swapped", func() {
+			tempTable := routingtable.NewSyncRoutingTable(false, fakeMetronClient)
+			tempTable.SetRoutes(logger, nil, createDesiredLRP(keyA.ProcessGUID, 1, keyA.ContainerPort, "log-guid-a", "", currentTag, runInfo, hostname))
+			tempTable.SetRoutes(logger, nil, createDesiredLRP(keyB.ProcessGUID, 1, keyB.ContainerPort, "log-guid-b", "", currentTag, runInfo, hostname))
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+
+			table.Swap(logger, tempTable, models.NewDomainSet([]string{"domain"}))
+
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			Expect(logger).To(Say("hostname-conflict-detected"))
+			Expect(logger).To(Say(`"hostname":"foo.example.com"`))
+		})
+
+		It("logs it with the logger of the live table", func() {
+			liveLogger := lagertest.NewTestLogger("live")
+			liveTable := routingtable.NewLiveRoutingTable(liveLogger, false, fakeMetronClient)
+			liveTable.SetRoutes(logger, nil, createDesiredLRP(keyA.ProcessGUID, 1, keyA.ContainerPort, "log-guid-a", "", currentTag, runInfo, hostname))
+
+			tempTable := routingtable.NewSyncRoutingTable(false, fakeMetronClient)
+			tempTable.SetRoutes(logger, nil, createDesiredLRP(keyA.ProcessGUID, 1, keyA.ContainerPort, "log-guid-a", "", currentTag, runInfo, hostname))
+			tempTable.SetRoutes(logger, nil, createDesiredLRP(keyB.ProcessGUID, 1, keyB.ContainerPort, "log-guid-b", "", currentTag, runInfo, hostname))
+
+			swapLogger := lagertest.NewTestLogger("swap")
+			liveTable.Swap(swapLogger, tempTable, models.NewDomainSet([]string{"domain"}))
+
+			Expect(liveLogger).To(Say("live.hostname-index.hostname-conflict-detected"))
+			Expect(swapLogger).NotTo(Say("hostname-conflict-detected"))
+		})
+	})
+
+	Context("when a snapshot with a conflict is restored", func() {
+		It("reports the conflict", func() {
+			table.SetRoutes(logger, nil, createDesiredLRP(keyB.ProcessGUID, 1, keyB.ContainerPort, "log-guid-b", "", currentTag, runInfo, hostname))
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+
+			restoredMetronClient := &mfakes.FakeIngressClient{}
+			restoredTable := routingtable.NewRoutingTable(false, restoredMetronClient)
+			Expect(restoredTable.Restore(logger, table.Snapshot())).To(Succeed())
+
+			Expect(restoredTable.HostnameConflicts()).To(HaveLen(1))
+			Expect(restoredMetronClient.IncrementCounterCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when another process with the same log guid claims the hostname", func() {
+		BeforeEach(func() {
+			table.SetRoutes(logger, nil, createDesiredLRP(keyB.ProcessGUID, 1, keyB.ContainerPort, "log-guid-a", "", currentTag, runInfo, hostname))
+		})
+
+		It("does not report a conflict", func() {
+			Expect(table.HostnameConflicts()).To(BeEmpty())
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the same process claims the hostname on another port", func() {
+		BeforeEach(func() {
+			table.SetRoutes(logger, nil, createDesiredLRP(keyA.ProcessGUID, 1, 9090, "log-guid-a", "", currentTag, runInfo, hostname))
+		})
+
+		It("does not report a conflict", func() {
+			Expect(table.HostnameConflicts()).To(BeEmpty())
+		})
+	})
+})
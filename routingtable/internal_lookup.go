@@ -0,0 +1,123 @@
+package routingtable
+
+import (
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+// InternalEndpoint is an instance reachable through an internal route. Ports
+// holds the container ports of the process when they are known from its http
+// or tcp routes.
+type InternalEndpoint struct {
+	Index       int32
+	ContainerIP string
+	Ports       []uint32
+}
+
+// LookupInternalRoute returns the instances of every process that claims the
+// internal hostname, sorted by index. Evacuating instances are only returned
+// when there is no ordinary instance with the same index.
+func (t *routingTable) LookupInternalRoute(hostname string) []InternalEndpoint {
+	processGUIDs, endpoints := t.internalRoutesRoutingTable.lookupInternalRoute(hostname)
+
+	ports := map[string][]uint32{}
+	for processGUID := range processGUIDs {
+		ports[processGUID] = mergePorts(
+			t.httpRoutesRoutingTable.containerPorts(processGUID),
+			t.tcpRoutesRoutingTable.containerPorts(processGUID),
+		)
+	}
+
+	byIndex := map[string]map[int32]Endpoint{}
+	for processGUID, processEndpoints := range endpoints {
+		byIndex[processGUID] = map[int32]Endpoint{}
+		for _, endpoint := range processEndpoints {
+			existing, ok := byIndex[processGUID][endpoint.Index]
+			if ok && existing.Presence == models.ActualLRP_Ordinary {
+				continue
+			}
+			byIndex[processGUID][endpoint.Index] = endpoint
+		}
+	}
+
+	result := []InternalEndpoint{}
+	for processGUID, processEndpoints := range byIndex {
+		for _, endpoint := range processEndpoints {
+			if endpoint.ContainerIP == "" {
+				continue
+			}
+			result = append(result, InternalEndpoint{
+				Index:       endpoint.Index,
+				ContainerIP: endpoint.ContainerIP,
+				Ports:       ports[processGUID],
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Index != result[j].Index {
+			return result[i].Index < result[j].Index
+		}
+		return result[i].ContainerIP < result[j].ContainerIP
+	})
+	return result
+}
+
+func (table *internalRoutingTable) lookupInternalRoute(hostname string) (map[string]struct{}, map[string][]Endpoint) {
+	table.Lock()
+	defer table.Unlock()
+
+	processGUIDs := map[string]struct{}{}
+	endpoints := map[string][]Endpoint{}
+	for key, entry := range table.entries {
+		if !claimsInternalHostname(entry, hostname) {
+			continue
+		}
+		processGUIDs[key.ProcessGUID] = struct{}{}
+		for _, endpoint := range entry.Endpoints {
+			endpoints[key.ProcessGUID] = append(endpoints[key.ProcessGUID], endpoint)
+		}
+	}
+	return processGUIDs, endpoints
+}
+
+func claimsInternalHostname(entry RoutableEndpoints, hostname string) bool {
+	for _, route := range entry.Routes {
+		internalRoute, ok := route.(InternalRoute)
+		if ok && strings.EqualFold(internalRoute.Hostname, hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+func (table *internalRoutingTable) containerPorts(processGUID string) []uint32 {
+	table.Lock()
+	defer table.Unlock()
+
+	ports := []uint32{}
+	for key, entry := range table.entries {
+		if key.ProcessGUID == processGUID && len(entry.Routes) > 0 {
+			ports = append(ports, key.ContainerPort)
+		}
+	}
+	return ports
+}
+
+func mergePorts(portLists ...[]uint32) []uint32 {
+	seen := map[uint32]struct{}{}
+	ports := []uint32{}
+	for _, list := range portLists {
+		for _, port := range list {
+			if _, ok := seen[port]; ok {
+				continue
+			}
+			seen[port] = struct{}{}
+			ports = append(ports, port)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	return ports
+}
@@ -0,0 +1,72 @@
+package routingtable_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LookupInternalRoute", func() {
+	var (
+		table  routingtable.RoutingTable
+		logger *lagertest.TestLogger
+	)
+
+	key := routingtable.RoutingKey{ProcessGUID: "some-process-guid", ContainerPort: 8080}
+	currentTag := &models.ModificationTag{Epoch: "abc", Index: 1}
+	runInfo := models.DesiredLRPRunInfo{}
+
+	endpoint := func(instanceGUID string, index int32, containerIP string, presence models.ActualLRP_Presence) routingtable.Endpoint {
+		return routingtable.Endpoint{
+			InstanceGUID:    instanceGUID,
+			Host:            "1.1.1.1",
+			ContainerIP:     containerIP,
+			Index:           index,
+			Port:            uint32(61000 + index),
+			ContainerPort:   8080,
+			Presence:        presence,
+			ModificationTag: currentTag,
+		}
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		table = routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{})
+
+		routes := createRoutingInfo(key.ContainerPort, []string{"foo.example.com"}, []string{"app.apps.internal"}, "", []uint32{9999}, "router-group-guid")
+		table.SetRoutes(logger, nil, createDesiredLRPWithRoutes(key.ProcessGUID, 2, routes, "log-guid", *currentTag, runInfo))
+		table.AddEndpoint(logger, createActualLRP(key, endpoint("ig-1", 1, "10.255.0.2", models.ActualLRP_Ordinary), "domain"))
+		table.AddEndpoint(logger, createActualLRP(key, endpoint("ig-0", 0, "10.255.0.1", models.ActualLRP_Ordinary), "domain"))
+	})
+
+	It("returns the instances of the hostname sorted by index", func() {
+		Expect(table.LookupInternalRoute("app.apps.internal")).To(Equal([]routingtable.InternalEndpoint{
+			{Index: 0, ContainerIP: "10.255.0.1", Ports: []uint32{8080}},
+			{Index: 1, ContainerIP: "10.255.0.2", Ports: []uint32{8080}},
+		}))
+	})
+
+	It("matches the hostname case insensitively", func() {
+		Expect(table.LookupInternalRoute("APP.apps.internal")).To(HaveLen(2))
+	})
+
+	It("returns nothing for an unknown hostname", func() {
+		Expect(table.LookupInternalRoute("other.apps.internal")).To(BeEmpty())
+		Expect(table.LookupInternalRoute("foo.example.com")).To(BeEmpty())
+	})
+
+	Context("when an instance is evacuating", func() {
+		BeforeEach(func() {
+			table.AddEndpoint(logger, createActualLRP(key, endpoint("ig-0-evacuating", 0, "10.255.0.3", models.ActualLRP_Evacuating), "domain"))
+		})
+
+		It("prefers the ordinary instance", func() {
+			endpoints := table.LookupInternalRoute("app.apps.internal")
+			Expect(endpoints).To(HaveLen(2))
+			Expect(endpoints[0].ContainerIP).To(Equal("10.255.0.1"))
+		})
+	})
+})
@@ -101,8 +101,9 @@ func InternalEndpointRegistryMessageFor(endpoint Endpoint, route InternalRoute,
 }
 
 type ExternalServiceGreetingMessage struct {
-	MinimumRegisterInterval int `json:"minimumRegisterIntervalInSeconds"`
-	PruneThresholdInSeconds int `json:"pruneThresholdInSeconds"`
+	ID                      string `json:"id,omitempty"`
+	MinimumRegisterInterval int    `json:"minimumRegisterIntervalInSeconds"`
+	PruneThresholdInSeconds int    `json:"pruneThresholdInSeconds"`
 }
 
 func populateMetricTags(input map[string]*models.MetricTagValue, endpoint Endpoint) map[string]string {
@@ -34,6 +34,7 @@ type RoutingTable interface {
 	AddEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit)
 	RemoveEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit)
 	Swap(logger lager.Logger, t RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit)
+	SwapIf(logger lager.Logger, t RoutingTable, domains models.DomainSet, accept func(TCPRouteMappings, MessagesToEmit) bool) (TCPRouteMappings, MessagesToEmit, bool) // swap only when accept returns true for the messages of the swap
 	GetInternalRoutingEvents() (TCPRouteMappings, MessagesToEmit)
 	GetExternalRoutingEvents() (TCPRouteMappings, MessagesToEmit)
 
@@ -44,6 +45,17 @@ type RoutingTable interface {
 	InternalAssociationsCount() int // return number of associations desired-lrp-internal-routes * 2 * actual-lrps
 	TCPAssociationsCount() int      // return number of associations desired-lrp-tcp-routes * actual-lrps
 	TableSize() int
+	HostnameConflicts() []HostnameConflict // return hostnames claimed by unrelated process guids
+
+	// introspection
+
+	Entries(filter EntryFilter) TableEntries
+	LookupInternalRoute(hostname string) []InternalEndpoint // return instances reachable through an internal route
+
+	// persistence
+
+	Snapshot() Snapshot
+	Restore(logger lager.Logger, snapshot Snapshot) error
 }
 
 type internalRoutingTable struct {
@@ -55,6 +67,12 @@ type internalRoutingTable struct {
 	directInstanceRoute      bool
 	metronClient             loggingclient.IngressClient
 	suppressAddressCollision bool
+	hostnameEntries          hostnameIndex
+	detectHostnameConflicts  bool
+	reportHostnameConflicts  bool
+	// logs the hostname conflicts instead of the logger of the call that
+	// found them, nil uses the caller's logger
+	conflictLogger lager.Logger
 	sync.Locker
 }
 
@@ -73,14 +91,17 @@ func NewRoutingTable(directInstanceRoute bool, metronClient loggingclient.Ingres
 	}
 
 	httpRoutingTable := &internalRoutingTable{
-		endpointGenerator:   NewEndpointsFromActual,
-		routesGenerator:     httpRoutesFrom,
-		entries:             make(map[RoutingKey]RoutableEndpoints),
-		addressEntries:      make(map[Address]EndpointKey),
-		directInstanceRoute: directInstanceRoute,
-		addressGenerator:    addressGenerator,
-		metronClient:        metronClient,
-		Locker:              &sync.Mutex{},
+		endpointGenerator:       NewEndpointsFromActual,
+		routesGenerator:         httpRoutesFrom,
+		entries:                 make(map[RoutingKey]RoutableEndpoints),
+		addressEntries:          make(map[Address]EndpointKey),
+		directInstanceRoute:     directInstanceRoute,
+		addressGenerator:        addressGenerator,
+		metronClient:            metronClient,
+		hostnameEntries:         hostnameIndex{},
+		detectHostnameConflicts: true,
+		reportHostnameConflicts: true,
+		Locker:                  &sync.Mutex{},
 	}
 	tcpRoutingTable := &internalRoutingTable{
 		endpointGenerator:        NewEndpointsFromActual,
@@ -112,6 +133,24 @@ func NewRoutingTable(directInstanceRoute bool, metronClient loggingclient.Ingres
 	}
 }
 
+// NewLiveRoutingTable returns the table the emitter serves its routes from.
+// Hostname conflicts are logged with the given logger, so that a sync can
+// swap in a new table without logging the swap itself.
+func NewLiveRoutingTable(logger lager.Logger, directInstanceRoute bool, metronClient loggingclient.IngressClient) RoutingTable {
+	table := NewRoutingTable(directInstanceRoute, metronClient).(*routingTable)
+	table.httpRoutesRoutingTable.conflictLogger = logger.Session("hostname-index")
+	return table
+}
+
+// NewSyncRoutingTable returns a table that is built from the BBS during a
+// sync and then swapped into the live table. It does not report hostname
+// conflicts, Swap reports the ones that are new to the live table.
+func NewSyncRoutingTable(directInstanceRoute bool, metronClient loggingclient.IngressClient) RoutingTable {
+	table := NewRoutingTable(directInstanceRoute, metronClient).(*routingTable)
+	table.httpRoutesRoutingTable.reportHostnameConflicts = false
+	return table
+}
+
 func internalEndpointsFromActualLRP(actualLRP *models.ActualLRP) []Endpoint {
 	return []Endpoint{
 		{
@@ -159,22 +198,51 @@ func (table *routingTable) RemoveEndpoint(logger lager.Logger, actualLRP *models
 }
 
 func (t *routingTable) Swap(logger lager.Logger, other RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
+	mappings, messages, _ := t.SwapIf(logger, other, domains, func(TCPRouteMappings, MessagesToEmit) bool { return true })
+	return mappings, messages
+}
+
+// SwapIf computes the messages of the swap once and only swaps the tables
+// when accept returns true for them. The table is locked while accept runs,
+// it must not call into the table.
+func (t *routingTable) SwapIf(logger lager.Logger, other RoutingTable, domains models.DomainSet, accept func(TCPRouteMappings, MessagesToEmit) bool) (TCPRouteMappings, MessagesToEmit, bool) {
 	table, ok := other.(*routingTable)
 	if !ok {
 		logger.Error("failed-to-convert-to-routing-table", nil)
-		return TCPRouteMappings{}, MessagesToEmit{}
+		return TCPRouteMappings{}, MessagesToEmit{}, false
 	}
 	logger = logger.Session("swap")
 	logger.Info("starting", lager.Data{"domains": domains})
 	defer logger.Info("finished")
 
-	httpMappings, httpMessages := t.httpRoutesRoutingTable.Swap(table.httpRoutesRoutingTable, domains)
-	tcpMappings, tcpMessages := t.tcpRoutesRoutingTable.Swap(table.tcpRoutesRoutingTable, domains)
-	internalMappings, internalMessages := t.internalRoutesRoutingTable.Swap(table.internalRoutesRoutingTable, domains)
+	tables := []*internalRoutingTable{t.httpRoutesRoutingTable, t.tcpRoutesRoutingTable, t.internalRoutesRoutingTable}
+	otherTables := []*internalRoutingTable{table.httpRoutesRoutingTable, table.tcpRoutesRoutingTable, table.internalRoutesRoutingTable}
 
-	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
-	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
-	return mappings, messages
+	var mappings TCPRouteMappings
+	var messages MessagesToEmit
+	entries := make([]map[RoutingKey]RoutableEndpoints, len(tables))
+	for i, internalTable := range tables {
+		internalTable.Lock()
+		defer internalTable.Unlock()
+
+		var internalMappings TCPRouteMappings
+		var internalMessages MessagesToEmit
+		internalMappings, internalMessages, entries[i] = internalTable.diff(otherTables[i], domains)
+		mappings = mappings.Merge(internalMappings)
+		messages = messages.Merge(internalMessages)
+	}
+
+	if !accept(mappings, messages) {
+		logger.Info("refused")
+		return mappings, messages, false
+	}
+
+	for i, internalTable := range tables {
+		internalTable.addressEntries = otherTables[i].addressEntries
+		internalTable.entries = entries[i]
+		internalTable.rebuildHostnameEntries(logger)
+	}
+	return mappings, messages, true
 }
 
 func (t *routingTable) GetExternalRoutingEvents() (TCPRouteMappings, MessagesToEmit) {
@@ -191,9 +259,9 @@ func (t *routingTable) GetInternalRoutingEvents() (TCPRouteMappings, MessagesToE
 }
 
 func (t *routingTable) SetRoutes(logger lager.Logger, before, after *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit) {
-	httpMappings, httpMessages, httpChanged := t.httpRoutesRoutingTable.SetRoutes(before, after)
-	tcpMappings, tcpMessages, tcpChanged := t.tcpRoutesRoutingTable.SetRoutes(before, after)
-	internalMappings, internalMessages, internalChanged := t.internalRoutesRoutingTable.SetRoutes(before, after)
+	httpMappings, httpMessages, httpChanged := t.httpRoutesRoutingTable.SetRoutes(logger, before, after)
+	tcpMappings, tcpMessages, tcpChanged := t.tcpRoutesRoutingTable.SetRoutes(logger, before, after)
+	internalMappings, internalMessages, internalChanged := t.internalRoutesRoutingTable.SetRoutes(logger, before, after)
 
 	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
 	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
@@ -206,9 +274,9 @@ func (t *routingTable) SetRoutes(logger lager.Logger, before, after *models.Desi
 }
 
 func (t *routingTable) RemoveRoutes(logger lager.Logger, desiredLRP *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit) {
-	httpMappings, httpMessages, httpChanged := t.httpRoutesRoutingTable.RemoveRoutes(desiredLRP)
-	tcpMappings, tcpMessages, tcpChanged := t.tcpRoutesRoutingTable.RemoveRoutes(desiredLRP)
-	internalMappings, internalMessages, internalChanged := t.internalRoutesRoutingTable.RemoveRoutes(desiredLRP)
+	httpMappings, httpMessages, httpChanged := t.httpRoutesRoutingTable.RemoveRoutes(logger, desiredLRP)
+	tcpMappings, tcpMessages, tcpChanged := t.tcpRoutesRoutingTable.RemoveRoutes(logger, desiredLRP)
+	internalMappings, internalMessages, internalChanged := t.internalRoutesRoutingTable.RemoveRoutes(logger, desiredLRP)
 
 	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
 	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
@@ -337,17 +405,17 @@ func (table *internalRoutingTable) RemoveEndpoint(logger lager.Logger, actualLRP
 	return mappings, messagesToEmit, changeDetected
 }
 
-func (t *internalRoutingTable) Swap(otherTable *internalRoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
-	t.Lock()
-	defer t.Unlock()
-
-	t.addressEntries = otherTable.addressEntries
-
+// diff returns the messages to move from the entries of t to the ones of
+// otherTable, and the entries to swap in. Entries of domains that are not
+// fresh keep their old routes. Neither table is changed.
+func (t *internalRoutingTable) diff(otherTable *internalRoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit, map[RoutingKey]RoutableEndpoints) {
 	var messagesToEmit MessagesToEmit
 	var mappings TCPRouteMappings
 
+	entries := make(map[RoutingKey]RoutableEndpoints, len(otherTable.entries))
 	mergedRoutingKeys := map[RoutingKey]struct{}{}
-	for key, _ := range otherTable.entries {
+	for key, entry := range otherTable.entries {
+		entries[key] = entry
 		mergedRoutingKeys[key] = struct{}{}
 	}
 	for key, _ := range t.entries {
@@ -367,16 +435,17 @@ func (t *internalRoutingTable) Swap(otherTable *internalRoutingTable, domains mo
 
 		// entry exists in both tables or in old table, merge the two entries to ensure non-fresh domain endpoints aren't removed
 		merged := mergeUnfreshRoutes(existingEntry, newEntry, domains)
-		otherTable.entries[key] = merged
-		otherTable.deleteEntryIfEmpty(key)
+		if len(merged.Endpoints) == 0 && len(merged.Routes) == 0 {
+			delete(entries, key)
+		} else {
+			entries[key] = merged
+		}
 		mapping, message, _ := t.emitDiffMessages(key, existingEntry, merged)
 		messagesToEmit = messagesToEmit.Merge(message)
 		mappings = mappings.Merge(mapping)
 	}
 
-	t.entries = otherTable.entries
-
-	return mappings, messagesToEmit
+	return mappings, messagesToEmit, entries
 }
 
 // merge the routes from both endpoints, ensuring that non-fresh routes aren't removed
@@ -489,7 +558,7 @@ func internalRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]routeMapping {
 	return routeEntries
 }
 
-func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit, bool) {
+func (table *internalRoutingTable) SetRoutes(logger lager.Logger, before, after *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit, bool) {
 	table.Lock()
 	defer table.Unlock()
 
@@ -529,6 +598,7 @@ func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRP) (
 		}
 
 		table.entries[key] = newEntry
+		table.updateHostnameEntries(logger, key, currentEntry, newEntry)
 
 		mapping, message, changed := table.emitDiffMessages(key, currentEntry, newEntry)
 		messagesToEmit = messagesToEmit.Merge(message)
@@ -561,6 +631,7 @@ func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRP) (
 		}
 
 		table.entries[key] = newEntry
+		table.updateHostnameEntries(logger, key, currentEntry, newEntry)
 
 		table.deleteEntryIfEmpty(key)
 
@@ -791,8 +862,8 @@ func (table *internalRoutingTable) messages(routesDiff routesDiff, endpointDiff
 	return mappings, messages
 }
 
-func (table *internalRoutingTable) RemoveRoutes(desiredLRP *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit, bool) {
-	return table.SetRoutes(desiredLRP, nil)
+func (table *internalRoutingTable) RemoveRoutes(logger lager.Logger, desiredLRP *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit, bool) {
+	return table.SetRoutes(logger, desiredLRP, nil)
 }
 
 func (t *internalRoutingTable) AssociationsCount() int {
@@ -840,6 +911,10 @@ func (t *routingTable) TableSize() int {
 	return t.httpRoutesRoutingTable.TableSize() + t.tcpRoutesRoutingTable.TableSize() + t.internalRoutesRoutingTable.TableSize()
 }
 
+func (t *routingTable) HostnameConflicts() []HostnameConflict {
+	return t.httpRoutesRoutingTable.HostnameConflicts()
+}
+
 func (t *routingTable) HasExternalRoutes(actualLRP *models.ActualLRP) bool {
 	return t.httpRoutesRoutingTable.HasExternalRoutes(actualLRP) || t.tcpRoutesRoutingTable.HasExternalRoutes(actualLRP)
 }
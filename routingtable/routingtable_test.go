@@ -530,6 +530,47 @@ var _ = Describe("RoutingTable", func() {
 		})
 	})
 
+	Describe("SwapIf", func() {
+		var tempTable routingtable.RoutingTable
+
+		BeforeEach(func() {
+			routingInfo := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{}, "", []uint32{5222}, "router-group-guid")
+			desiredLRP := createDesiredLRPWithRoutes(key.ProcessGUID, 3, routingInfo, logGuid, *currentTag, runInfo)
+			table.SetRoutes(logger, nil, desiredLRP)
+			table.AddEndpoint(logger, createActualLRP(key, endpoint1, domain))
+
+			tempTable = routingtable.NewRoutingTable(false, fakeMetronClient)
+			tempTable.AddEndpoint(logger, createActualLRP(key, endpoint1, domain))
+		})
+
+		It("passes the messages of the swap to accept and swaps when it accepts them", func() {
+			var acceptedMappings routingtable.TCPRouteMappings
+			var acceptedMessages routingtable.MessagesToEmit
+			mappings, messages, swapped := table.SwapIf(logger, tempTable, freshDomains, func(mappings routingtable.TCPRouteMappings, messages routingtable.MessagesToEmit) bool {
+				acceptedMappings, acceptedMessages = mappings, messages
+				return true
+			})
+
+			Expect(swapped).To(BeTrue())
+			Expect(acceptedMessages.UnregistrationMessages).To(HaveLen(1))
+			Expect(acceptedMappings.Unregistrations).To(HaveLen(1))
+			Expect(messages).To(MatchMessagesToEmit(acceptedMessages))
+			Expect(mappings.Unregistrations).To(ConsistOf(acceptedMappings.Unregistrations))
+			Expect(table.HTTPAssociationsCount()).To(Equal(0))
+		})
+
+		It("changes neither table when accept refuses the messages", func() {
+			_, messages, swapped := table.SwapIf(logger, tempTable, freshDomains, func(routingtable.TCPRouteMappings, routingtable.MessagesToEmit) bool {
+				return false
+			})
+
+			Expect(swapped).To(BeFalse())
+			Expect(messages.UnregistrationMessages).To(HaveLen(1))
+			Expect(table.HTTPAssociationsCount()).To(Equal(1))
+			Expect(tempTable.HTTPAssociationsCount()).To(Equal(0))
+		})
+	})
+
 	Describe("Swap", func() {
 		It("preserves the desired LRP domain", func() {
 			By("creating a routing table with a route and endpoint")
@@ -1206,4 +1247,80 @@ var _ = Describe("RoutingTable", func() {
 			})
 		})
 	})
+
+	Describe("Entries", func() {
+		var internalHostname string
+
+		BeforeEach(func() {
+			internalHostname = "internal"
+
+			routes := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{internalHostname}, "", []uint32{9999}, "router-group-guid")
+			desiredLRP := createDesiredLRPWithRoutes(key.ProcessGUID, 2, routes, logGuid, *currentTag, runInfo)
+			table.SetRoutes(logger, nil, desiredLRP)
+
+			table.AddEndpoint(logger, createActualLRP(key, endpoint2, domain))
+			table.AddEndpoint(logger, createActualLRP(key, endpoint1, domain))
+
+			otherKey := routingtable.RoutingKey{ProcessGUID: "other-process-guid", ContainerPort: 8080}
+			otherRoutes := createRoutingInfo(otherKey.ContainerPort, []string{"bar.example.com"}, nil, "", nil, "")
+			table.SetRoutes(logger, nil, createDesiredLRPWithRoutes(otherKey.ProcessGUID, 1, otherRoutes, "other-log-guid", *currentTag, runInfo))
+		})
+
+		It("returns the entries of every table keyed by routing key", func() {
+			entries := table.Entries(routingtable.EntryFilter{})
+
+			Expect(entries.HTTP).To(HaveLen(2))
+			entry := entries.HTTP["some-process-guid:8080"]
+			Expect(entry.RoutingKey).To(Equal(key))
+			Expect(entry.Domain).To(Equal(domain))
+			Expect(entry.DesiredInstances).To(BeEquivalentTo(2))
+			Expect(entry.ModificationTag).To(Equal(currentTag))
+			Expect(entry.Routes).To(ConsistOf(routingtable.Route{Hostname: hostname1, LogGUID: logGuid}))
+			Expect(entry.Endpoints).To(HaveLen(2))
+			Expect(entry.Endpoints[0].InstanceGUID).To(Equal(endpoint1.InstanceGUID))
+			Expect(entry.Endpoints[1].InstanceGUID).To(Equal(endpoint2.InstanceGUID))
+
+			Expect(entries.TCP).To(HaveLen(1))
+			Expect(entries.TCP["some-process-guid:8080"].TCPRoutes).To(ConsistOf(routingtable.ExternalEndpointInfo{
+				RouterGroupGUID: "router-group-guid",
+				Port:            9999,
+			}))
+
+			Expect(entries.Internal).To(HaveLen(1))
+			Expect(entries.Internal["some-process-guid:0"].InternalRoutes).To(ConsistOf(routingtable.InternalRoute{
+				Hostname: internalHostname,
+				LogGUID:  logGuid,
+			}))
+		})
+
+		It("filters by process guid", func() {
+			entries := table.Entries(routingtable.EntryFilter{ProcessGUID: "other-process-guid"})
+			Expect(entries.HTTP).To(HaveLen(1))
+			Expect(entries.HTTP).To(HaveKey("other-process-guid:8080"))
+			Expect(entries.TCP).To(BeEmpty())
+			Expect(entries.Internal).To(BeEmpty())
+		})
+
+		It("filters by hostname", func() {
+			entries := table.Entries(routingtable.EntryFilter{Hostname: hostname1})
+			Expect(entries.HTTP).To(HaveLen(1))
+			Expect(entries.HTTP).To(HaveKey("some-process-guid:8080"))
+			Expect(entries.TCP).To(BeEmpty())
+			Expect(entries.Internal).To(BeEmpty())
+
+			entries = table.Entries(routingtable.EntryFilter{Hostname: internalHostname})
+			Expect(entries.HTTP).To(BeEmpty())
+			Expect(entries.Internal).To(HaveLen(1))
+		})
+
+		It("filters by router group", func() {
+			entries := table.Entries(routingtable.EntryFilter{RouterGroupGUID: "router-group-guid"})
+			Expect(entries.HTTP).To(BeEmpty())
+			Expect(entries.TCP).To(HaveLen(1))
+			Expect(entries.Internal).To(BeEmpty())
+
+			entries = table.Entries(routingtable.EntryFilter{RouterGroupGUID: "unknown"})
+			Expect(entries.TCP).To(BeEmpty())
+		})
+	})
 })
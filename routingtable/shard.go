@@ -0,0 +1,63 @@
+package routingtable
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	tcpmodels "code.cloudfoundry.org/routing-api/models"
+)
+
+// Shard returns the messages of the endpoints that hash to the shard out of
+// shards. All the routes of an endpoint land in the same shard, and every
+// endpoint lands in exactly one.
+func (m MessagesToEmit) Shard(shard, shards int) MessagesToEmit {
+	if shards <= 1 {
+		return m
+	}
+
+	return MessagesToEmit{
+		RegistrationMessages:           shardMessages(m.RegistrationMessages, shard, shards),
+		UnregistrationMessages:         shardMessages(m.UnregistrationMessages, shard, shards),
+		InternalRegistrationMessages:   shardMessages(m.InternalRegistrationMessages, shard, shards),
+		InternalUnregistrationMessages: shardMessages(m.InternalUnregistrationMessages, shard, shards),
+	}
+}
+
+// Shard returns the mappings of the backends that hash to the shard out of
+// shards.
+func (mappings TCPRouteMappings) Shard(shard, shards int) TCPRouteMappings {
+	if shards <= 1 {
+		return mappings
+	}
+
+	return TCPRouteMappings{
+		Registrations:   shardMappings(mappings.Registrations, shard, shards),
+		Unregistrations: shardMappings(mappings.Unregistrations, shard, shards),
+	}
+}
+
+func shardMessages(messages []RegistryMessage, shard, shards int) []RegistryMessage {
+	var result []RegistryMessage
+	for _, message := range messages {
+		if shardOf(message.Host, message.Port, shards) == shard {
+			result = append(result, message)
+		}
+	}
+	return result
+}
+
+func shardMappings(mappings []tcpmodels.TcpRouteMapping, shard, shards int) []tcpmodels.TcpRouteMapping {
+	var result []tcpmodels.TcpRouteMapping
+	for _, mapping := range mappings {
+		if shardOf(mapping.HostIP, uint32(mapping.HostPort), shards) == shard {
+			result = append(result, mapping)
+		}
+	}
+	return result
+}
+
+func shardOf(host string, port uint32, shards int) int {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%s:%d", host, port)
+	return int(hash.Sum32() % uint32(shards))
+}
@@ -0,0 +1,71 @@
+package routingtable_test
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	tcpmodels "code.cloudfoundry.org/routing-api/models"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shard", func() {
+	const shards = 4
+
+	var (
+		messagesToEmit routingtable.MessagesToEmit
+		routeMappings  routingtable.TCPRouteMappings
+	)
+
+	BeforeEach(func() {
+		messagesToEmit = routingtable.MessagesToEmit{}
+		routeMappings = routingtable.TCPRouteMappings{}
+		for i := 0; i < 50; i++ {
+			host := fmt.Sprintf("10.0.0.%d", i)
+			messagesToEmit.RegistrationMessages = append(messagesToEmit.RegistrationMessages,
+				routingtable.RegistryMessage{Host: host, Port: 61000, URIs: []string{"foo.example.com"}},
+				routingtable.RegistryMessage{Host: host, Port: 61000, URIs: []string{"bar.example.com"}},
+			)
+			messagesToEmit.InternalRegistrationMessages = append(messagesToEmit.InternalRegistrationMessages,
+				routingtable.RegistryMessage{Host: host, URIs: []string{"foo.apps.internal"}},
+			)
+			routeMappings.Registrations = append(routeMappings.Registrations,
+				tcpmodels.NewTcpRouteMapping("router-group-guid", 5222, host, 61001, 0),
+			)
+		}
+	})
+
+	It("returns everything when there is a single shard", func() {
+		Expect(messagesToEmit.Shard(0, 1)).To(Equal(messagesToEmit))
+		Expect(routeMappings.Shard(0, 1)).To(Equal(routeMappings))
+	})
+
+	It("puts every message in exactly one shard", func() {
+		var merged routingtable.MessagesToEmit
+		var mergedMappings routingtable.TCPRouteMappings
+		for shard := 0; shard < shards; shard++ {
+			merged = merged.Merge(messagesToEmit.Shard(shard, shards))
+			mergedMappings = mergedMappings.Merge(routeMappings.Shard(shard, shards))
+		}
+
+		Expect(merged.RegistrationMessages).To(ConsistOf(messagesToEmit.RegistrationMessages))
+		Expect(merged.InternalRegistrationMessages).To(ConsistOf(messagesToEmit.InternalRegistrationMessages))
+		Expect(mergedMappings.Registrations).To(ConsistOf(routeMappings.Registrations))
+	})
+
+	It("keeps the routes of an endpoint in the same shard", func() {
+		for shard := 0; shard < shards; shard++ {
+			messages := messagesToEmit.Shard(shard, shards).RegistrationMessages
+			Expect(len(messages) % 2).To(Equal(0))
+			for i := 0; i < len(messages); i += 2 {
+				Expect(messages[i].Host).To(Equal(messages[i+1].Host))
+			}
+		}
+	})
+
+	It("spreads the endpoints across the shards", func() {
+		for shard := 0; shard < shards; shard++ {
+			Expect(messagesToEmit.Shard(shard, shards).RegistrationMessages).NotTo(BeEmpty())
+		}
+	})
+})
@@ -0,0 +1,78 @@
+package routingtable
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// SnapshotVersion is incremented whenever the serialized form of Snapshot
+// changes in an incompatible way.
+const SnapshotVersion = 1
+
+// Snapshot is a serializable copy of all three routing tables, used to warm
+// start the route emitter before the first sync completes.
+type Snapshot struct {
+	Version   int
+	CreatedAt time.Time
+	TableEntries
+}
+
+func (t *routingTable) Snapshot() Snapshot {
+	return Snapshot{
+		Version:      SnapshotVersion,
+		CreatedAt:    time.Now(),
+		TableEntries: t.Entries(EntryFilter{}),
+	}
+}
+
+// Restore replaces the contents of the table with the snapshot entries. No
+// messages are returned, use GetExternalRoutingEvents and
+// GetInternalRoutingEvents to emit the restored routes.
+func (t *routingTable) Restore(logger lager.Logger, snapshot Snapshot) error {
+	if snapshot.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d, expected %d", snapshot.Version, SnapshotVersion)
+	}
+
+	t.httpRoutesRoutingTable.restore(logger, snapshot.HTTP)
+	t.tcpRoutesRoutingTable.restore(logger, snapshot.TCP)
+	t.internalRoutesRoutingTable.restore(logger, snapshot.Internal)
+	return nil
+}
+
+func (t *internalRoutingTable) restore(logger lager.Logger, entries map[string]Entry) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.entries = make(map[RoutingKey]RoutableEndpoints)
+	t.addressEntries = make(map[Address]EndpointKey)
+
+	for _, entry := range entries {
+		routableEndpoints := RoutableEndpoints{
+			Domain:           entry.Domain,
+			Endpoints:        map[EndpointKey]Endpoint{},
+			DesiredInstances: entry.DesiredInstances,
+			ModificationTag:  entry.ModificationTag,
+		}
+		for _, route := range entry.Routes {
+			routableEndpoints.Routes = append(routableEndpoints.Routes, route)
+		}
+		for _, route := range entry.TCPRoutes {
+			routableEndpoints.Routes = append(routableEndpoints.Routes, route)
+		}
+		for _, route := range entry.InternalRoutes {
+			routableEndpoints.Routes = append(routableEndpoints.Routes, route)
+		}
+		for _, endpoint := range entry.Endpoints {
+			routableEndpoints.Endpoints[endpoint.key()] = endpoint
+			if !t.suppressAddressCollision {
+				t.addressEntries[t.addressGenerator(endpoint)] = endpoint.key()
+			}
+		}
+
+		t.entries[entry.RoutingKey] = routableEndpoints
+	}
+
+	t.rebuildHostnameEntries(logger)
+}
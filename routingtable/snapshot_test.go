@@ -0,0 +1,113 @@
+package routingtable_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "code.cloudfoundry.org/route-emitter/routingtable/matchers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot", func() {
+	var (
+		table, restoredTable routingtable.RoutingTable
+		logger               *lagertest.TestLogger
+		fakeMetronClient     *mfakes.FakeIngressClient
+	)
+
+	key := routingtable.RoutingKey{ProcessGUID: "some-process-guid", ContainerPort: 8080}
+	currentTag := &models.ModificationTag{Epoch: "abc", Index: 1}
+	runInfo := models.DesiredLRPRunInfo{}
+
+	endpoint1 := routingtable.Endpoint{
+		InstanceGUID:    "ig-1",
+		Host:            "1.1.1.1",
+		ContainerIP:     "1.2.3.4",
+		Index:           0,
+		Port:            11,
+		ContainerPort:   8080,
+		Presence:        models.ActualLRP_Ordinary,
+		ModificationTag: currentTag,
+	}
+	endpoint2 := routingtable.Endpoint{
+		InstanceGUID:    "ig-2",
+		Host:            "2.2.2.2",
+		ContainerIP:     "2.3.4.5",
+		Index:           1,
+		Port:            22,
+		ContainerPort:   8080,
+		Presence:        models.ActualLRP_Ordinary,
+		ModificationTag: currentTag,
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		table = routingtable.NewRoutingTable(false, fakeMetronClient)
+		restoredTable = routingtable.NewRoutingTable(false, fakeMetronClient)
+
+		routes := createRoutingInfo(key.ContainerPort, []string{"foo.example.com"}, []string{"internal"}, "", []uint32{9999}, "router-group-guid")
+		table.SetRoutes(logger, nil, createDesiredLRPWithRoutes(key.ProcessGUID, 2, routes, "log-guid", *currentTag, runInfo))
+		table.AddEndpoint(logger, createActualLRP(key, endpoint1, "domain"))
+		table.AddEndpoint(logger, createActualLRP(key, endpoint2, "domain"))
+	})
+
+	It("creates a versioned snapshot", func() {
+		snapshot := table.Snapshot()
+		Expect(snapshot.Version).To(Equal(routingtable.SnapshotVersion))
+		Expect(snapshot.HTTP).To(HaveLen(1))
+		Expect(snapshot.TCP).To(HaveLen(1))
+		Expect(snapshot.Internal).To(HaveLen(1))
+	})
+
+	It("restores a table that emits the same routes", func() {
+		payload, err := json.Marshal(table.Snapshot())
+		Expect(err).NotTo(HaveOccurred())
+
+		var snapshot routingtable.Snapshot
+		Expect(json.Unmarshal(payload, &snapshot)).To(Succeed())
+		Expect(restoredTable.Restore(logger, snapshot)).To(Succeed())
+
+		Expect(restoredTable.TableSize()).To(Equal(table.TableSize()))
+		Expect(restoredTable.HTTPAssociationsCount()).To(Equal(table.HTTPAssociationsCount()))
+		Expect(restoredTable.TCPAssociationsCount()).To(Equal(table.TCPAssociationsCount()))
+		Expect(restoredTable.InternalAssociationsCount()).To(Equal(table.InternalAssociationsCount()))
+
+		expectedMappings, expectedMessages := table.GetExternalRoutingEvents()
+		mappings, messages := restoredTable.GetExternalRoutingEvents()
+		Expect(messages).To(MatchMessagesToEmit(expectedMessages))
+		Expect(mappings.Registrations).To(ConsistOf(expectedMappings.Registrations))
+
+		_, expectedMessages = table.GetInternalRoutingEvents()
+		_, messages = restoredTable.GetInternalRoutingEvents()
+		Expect(messages).To(MatchMessagesToEmit(expectedMessages))
+	})
+
+	It("reconciles with a fresh table through swap", func() {
+		Expect(restoredTable.Restore(logger, table.Snapshot())).To(Succeed())
+
+		freshTable := routingtable.NewRoutingTable(false, fakeMetronClient)
+		routes := createRoutingInfo(key.ContainerPort, []string{"foo.example.com"}, []string{"internal"}, "", []uint32{9999}, "router-group-guid")
+		freshTable.SetRoutes(logger, nil, createDesiredLRPWithRoutes(key.ProcessGUID, 2, routes, "log-guid", *currentTag, runInfo))
+		freshTable.AddEndpoint(logger, createActualLRP(key, endpoint1, "domain"))
+
+		_, messages := restoredTable.Swap(logger, freshTable, models.NewDomainSet([]string{"domain"}))
+		Expect(messages.RegistrationMessages).To(BeEmpty())
+		Expect(messages.UnregistrationMessages).To(HaveLen(1))
+		Expect(messages.UnregistrationMessages[0].Host).To(Equal(endpoint2.Host))
+	})
+
+	Context("when the snapshot version is not supported", func() {
+		It("returns an error and leaves the table untouched", func() {
+			snapshot := table.Snapshot()
+			snapshot.Version = routingtable.SnapshotVersion + 1
+
+			Expect(restoredTable.Restore(logger, snapshot)).To(MatchError(ContainSubstring("unsupported snapshot version")))
+			Expect(restoredTable.TableSize()).To(Equal(0))
+		})
+	})
+})
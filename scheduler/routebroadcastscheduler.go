@@ -8,19 +8,65 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"github.com/nats-io/nats.go"
 	uuid "github.com/nu7hatch/gouuid"
 )
 
+const (
+	effectiveEmitIntervalMetric = "EffectiveEmitInterval"
+	droppedEmitTicksCounter     = "EmitTicksDropped"
+
+	// pruneThresholdSafetyFactor leaves room for two missed or late emits
+	// (including jitter) before the external service prunes the routes.
+	pruneThresholdSafetyFactor = 3
+
+	// greetingExpiryIntervals is the number of its own intervals an external
+	// service instance can leave the greetings unanswered before it is
+	// forgotten and no longer holds down the emit interval.
+	greetingExpiryIntervals = 3
+)
+
+var routerTypes = map[string]string{
+	"router":            "http",
+	"service-discovery": "internal",
+}
+
+// GreetingReporter is told when the external service has answered a
+// greeting so that readiness can be reported.
+//
+//go:generate counterfeiter -o fakes/fake_greeting_reporter.go . GreetingReporter
+type GreetingReporter interface {
+	GreetingReceived(externalServiceName string)
+}
+
+// FullEmitRequester makes the next emit cover every route instead of one
+// shard.
+//
+//go:generate counterfeiter -o fakes/fake_full_emit_requester.go . FullEmitRequester
+type FullEmitRequester interface {
+	RequestFullEmit()
+}
+
 type RouteBroadcastScheduler struct {
-	natsClient           diegonats.NATSClient
-	externalServiceName  string
-	clock                clock.Clock
-	emitCh               chan struct{}
-	externalServiceStart chan time.Duration
+	natsClient          diegonats.NATSClient
+	externalServiceName string
+	clock               clock.Clock
+	emitCh              chan struct{}
+	// a full emission is split into shards that are spread across the
+	// register interval, emitCh receives one tick per shard
+	emitShards int
+	// asked for a full emission when a shard would not be emitted in time,
+	// e.g. for an instance that just started with an empty table
+	fullEmitRequester    FullEmitRequester
+	externalServiceStart chan routingtable.ExternalServiceGreetingMessage
+	greetingReplies      chan routingtable.ExternalServiceGreetingMessage
+	greetingReporter     GreetingReporter
+	metronClient         loggingclient.IngressClient
 
 	logger lager.Logger
 }
@@ -31,15 +77,24 @@ func NewRouteBroadcastScheduler(
 	logger lager.Logger,
 	externalServiceName string,
 	emitCh chan struct{},
+	emitShards int,
+	fullEmitRequester FullEmitRequester,
+	greetingReporter GreetingReporter,
+	metronClient loggingclient.IngressClient,
 ) *RouteBroadcastScheduler {
 	return &RouteBroadcastScheduler{
 		natsClient:          natsClient,
 		externalServiceName: externalServiceName,
 
-		clock:  clock,
-		emitCh: emitCh,
+		clock:             clock,
+		emitCh:            emitCh,
+		emitShards:        emitShards,
+		fullEmitRequester: fullEmitRequester,
 
-		externalServiceStart: make(chan time.Duration),
+		externalServiceStart: make(chan routingtable.ExternalServiceGreetingMessage),
+		greetingReplies:      make(chan routingtable.ExternalServiceGreetingMessage),
+		greetingReporter:     greetingReporter,
+		metronClient:         metronClient,
 
 		logger: logger.Session("route-broadcast-scheduler", lager.Data{"name": externalServiceName}),
 	}
@@ -60,6 +115,8 @@ func (s *RouteBroadcastScheduler) Run(signals <-chan os.Signal, ready chan<- str
 	close(ready)
 	s.logger.Info("started")
 
+	// the instances of the external service that answered, keyed by id
+	services := map[string]externalService{}
 	var registerInterval time.Duration
 	retryGreetingTicker := s.clock.NewTicker(time.Second)
 
@@ -73,36 +130,87 @@ GREET_LOOP:
 			return err
 		}
 
+		var greeting routingtable.ExternalServiceGreetingMessage
 		select {
-		case registerInterval = <-s.externalServiceStart:
-			s.logger.Info("received-external-service-registry-interval", lager.Data{"interval": registerInterval.String()})
-			break GREET_LOOP
+		case greeting = <-s.externalServiceStart:
+		case greeting = <-s.greetingReplies:
 		case <-retryGreetingTicker.C():
 			s.logger.Info("retrying")
+			continue
 		case <-signals:
 			s.logger.Info("stopping")
 			return nil
 		}
+
+		services[greeting.ID] = externalService{interval: emitInterval(greeting), lastGreeting: s.clock.Now()}
+		registerInterval = effectiveInterval(services)
+		s.reportInterval(registerInterval)
+		s.logger.Info("received-external-service-registry-interval", lager.Data{"interval": registerInterval.String()})
+		s.greetingReporter.GreetingReceived(s.externalServiceName)
+		break GREET_LOOP
 	}
 	retryGreetingTicker.Stop()
 
 	// now keep emitting at the desired interval
-	emitTicker := s.clock.NewTicker(registerInterval)
+	emitTicker := s.clock.NewTicker(s.tickInterval(registerInterval))
+	lastGreeting := s.clock.Now()
 
 	randSource := rand.New(rand.NewSource(time.Now().UnixNano()))
+	resetInterval := func() {
+		registerInterval = effectiveInterval(services)
+		s.reportInterval(registerInterval)
+		emitTicker.Stop()
+		emitTicker = s.clock.NewTicker(s.tickInterval(registerInterval))
+	}
+	started := func(greeting routingtable.ExternalServiceGreetingMessage) {
+		services[greeting.ID] = externalService{interval: emitInterval(greeting), lastGreeting: s.clock.Now()}
+		registerInterval = effectiveInterval(services)
+		s.reportInterval(registerInterval)
+		s.logger.Info("received-new-external-service-prune-interval", lager.Data{"id": greeting.ID, "interval": registerInterval.String()})
+		tickInterval := s.tickInterval(registerInterval)
+		jitterInterval := randSource.Int63n(int64(0.2 * float64(tickInterval)))
+		s.clock.Sleep(time.Duration(jitterInterval))
+		emitTicker.Stop()
+		emitTicker = s.clock.NewTicker(tickInterval)
+		// the new instance has no routes, it cannot wait for every shard
+		s.requestFullEmit()
+		s.emit()
+	}
+
 	s.logger.Info("for loop")
 	for {
 		select {
-		case registerInterval = <-s.externalServiceStart:
-			s.logger.Info("received-new-external-service-prune-interval", lager.Data{"interval": registerInterval.String()})
-			jitterInterval := randSource.Int63n(int64(0.2 * float64(registerInterval)))
-			s.clock.Sleep(time.Duration(jitterInterval))
-			emitTicker.Stop()
-			emitTicker = s.clock.NewTicker(registerInterval)
-			s.emit()
+		case greeting := <-s.externalServiceStart:
+			started(greeting)
+		case greeting := <-s.greetingReplies:
+			if _, ok := services[greeting.ID]; !ok {
+				// an instance that started without us hearing about it
+				started(greeting)
+				continue
+			}
+			services[greeting.ID] = externalService{interval: emitInterval(greeting), lastGreeting: s.clock.Now()}
+			if effectiveInterval(services) != registerInterval {
+				resetInterval()
+				s.logger.Info("changed-external-service-prune-interval", lager.Data{"id": greeting.ID, "interval": registerInterval.String()})
+			}
 		case <-emitTicker.C():
 			s.logger.Info("emitting-routes")
 			s.emit()
+
+			if s.expireServices(services) {
+				resetInterval()
+				s.logger.Info("changed-external-service-prune-interval", lager.Data{"interval": registerInterval.String()})
+			}
+
+			// greet again so that the instances that are still around
+			// answer before they expire
+			if s.clock.Since(lastGreeting) >= registerInterval {
+				lastGreeting = s.clock.Now()
+				err := s.greetExternalService(replyUuid.String())
+				if err != nil {
+					s.logger.Error("failed-to-greet-external-service", err)
+				}
+			}
 		case <-signals:
 			s.logger.Info("stopping")
 			emitTicker.Stop()
@@ -111,11 +219,98 @@ GREET_LOOP:
 	}
 }
 
+// externalService is an instance of the external service that answered,
+// e.g. one gorouter.
+type externalService struct {
+	// keeps the routes of the instance from being pruned
+	interval     time.Duration
+	lastGreeting time.Time
+}
+
+// expireServices forgets the instances that did not answer a greeting for
+// greetingExpiryIntervals of their own intervals, they were stopped or
+// scaled down. The last instance is kept so that there is always an
+// interval to emit at. It returns whether the effective interval changed.
+func (s *RouteBroadcastScheduler) expireServices(services map[string]externalService) bool {
+	before := effectiveInterval(services)
+	now := s.clock.Now()
+	for id, service := range services {
+		if len(services) == 1 {
+			break
+		}
+		if now.Sub(service.lastGreeting) > greetingExpiryIntervals*service.interval {
+			s.logger.Info("expired-external-service", lager.Data{"id": id, "interval": service.interval.String()})
+			delete(services, id)
+		}
+	}
+	return effectiveInterval(services) != before
+}
+
+// emitInterval is the minimum register interval requested by the external
+// service, shortened to stay well inside its prune threshold.
+func emitInterval(greeting routingtable.ExternalServiceGreetingMessage) time.Duration {
+	interval := time.Duration(greeting.MinimumRegisterInterval) * time.Second
+	if greeting.PruneThresholdInSeconds > 0 {
+		limit := time.Duration(greeting.PruneThresholdInSeconds) * time.Second / pruneThresholdSafetyFactor
+		if interval <= 0 || limit < interval {
+			interval = limit
+		}
+	}
+	return interval
+}
+
+// effectiveInterval is the shortest interval of all the external services
+// that answered, so that none of them prunes the routes.
+func effectiveInterval(services map[string]externalService) time.Duration {
+	var effective time.Duration
+	for _, service := range services {
+		if effective == 0 || service.interval < effective {
+			effective = service.interval
+		}
+	}
+	return effective
+}
+
+// tickInterval is the time between two shards, so that every shard is
+// emitted once per register interval.
+func (s *RouteBroadcastScheduler) tickInterval(registerInterval time.Duration) time.Duration {
+	if s.emitShards <= 1 {
+		return registerInterval
+	}
+	return registerInterval / time.Duration(s.emitShards)
+}
+
+func (s *RouteBroadcastScheduler) reportInterval(interval time.Duration) {
+	labels := metrics.Labels{RouterType: routerTypes[s.externalServiceName]}
+	err := s.metronClient.SendDuration(effectiveEmitIntervalMetric, interval, metrics.WithLabels(labels)...)
+	if err != nil {
+		s.logger.Error("failed-to-send-effective-emit-interval-metric", err)
+	}
+}
+
 func (s *RouteBroadcastScheduler) emit() {
 	select {
 	case s.emitCh <- struct{}{}:
 	default:
 		s.logger.Debug("emit-already-in-progress")
+		if s.emitShards <= 1 {
+			return
+		}
+
+		// the shards after the dropped tick would only be emitted after the
+		// register interval, catch up with a full emit on the pending tick
+		s.logger.Info("dropped-emit-tick", lager.Data{"shards": s.emitShards})
+		err := s.metronClient.IncrementCounter(droppedEmitTicksCounter)
+		if err != nil {
+			s.logger.Error("failed-to-increment-dropped-emit-ticks-counter", err)
+		}
+		s.requestFullEmit()
+	}
+}
+
+func (s *RouteBroadcastScheduler) requestFullEmit() {
+	if s.emitShards > 1 && s.fullEmitRequester != nil {
+		s.fullEmitRequester.RequestFullEmit()
 	}
 }
 
@@ -125,11 +320,12 @@ func (s *RouteBroadcastScheduler) listenForExternalService(replyUUID string) err
 		return err
 	}
 
-	sub, err := s.natsClient.Subscribe(replyUUID, s.handleExternalServiceStart)
+	// the external service is greeted again every register interval, keep
+	// receiving the answers
+	_, err = s.natsClient.Subscribe(replyUUID, s.handleGreetingReply)
 	if err != nil {
 		return err
 	}
-	sub.AutoUnsubscribe(1)
 
 	return nil
 }
@@ -144,6 +340,20 @@ func (s *RouteBroadcastScheduler) greetExternalService(replyUUID string) error {
 }
 
 func (s *RouteBroadcastScheduler) handleExternalServiceStart(msg *nats.Msg) {
+	response, ok := s.parseGreeting(msg)
+	if ok {
+		s.externalServiceStart <- response
+	}
+}
+
+func (s *RouteBroadcastScheduler) handleGreetingReply(msg *nats.Msg) {
+	response, ok := s.parseGreeting(msg)
+	if ok {
+		s.greetingReplies <- response
+	}
+}
+
+func (s *RouteBroadcastScheduler) parseGreeting(msg *nats.Msg) (routingtable.ExternalServiceGreetingMessage, bool) {
 	var response routingtable.ExternalServiceGreetingMessage
 
 	err := json.Unmarshal(msg.Data, &response)
@@ -151,11 +361,17 @@ func (s *RouteBroadcastScheduler) handleExternalServiceStart(msg *nats.Msg) {
 		s.logger.Error("received-invalid-external-service-start", err, lager.Data{
 			"payload": msg.Data,
 		})
-		return
+		return response, false
+	}
+
+	if emitInterval(response) <= 0 {
+		s.logger.Error("received-invalid-external-service-interval", nil, lager.Data{
+			"payload": msg.Data,
+		})
+		return response, false
 	}
 
-	greetInterval := response.MinimumRegisterInterval
-	s.externalServiceStart <- time.Duration(greetInterval) * time.Second
+	return response, true
 }
 
 func (s *RouteBroadcastScheduler) EmitCh() chan struct{} {
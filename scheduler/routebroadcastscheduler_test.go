@@ -6,9 +6,11 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/lager/v3/lagertest"
 	"code.cloudfoundry.org/route-emitter/diegonats"
 	"code.cloudfoundry.org/route-emitter/scheduler"
+	"code.cloudfoundry.org/route-emitter/scheduler/fakes"
 	"github.com/nats-io/nats.go"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -17,11 +19,15 @@ import (
 
 var _ = Describe("RouteBroadcastScheduler", func() {
 	var (
-		natsClient      *diegonats.FakeNATSClient
-		schedulerRunner *scheduler.RouteBroadcastScheduler
-		process         ifrit.Process
-		clock           *fakeclock.FakeClock
-		emitCh          chan struct{}
+		natsClient       *diegonats.FakeNATSClient
+		schedulerRunner  *scheduler.RouteBroadcastScheduler
+		process          ifrit.Process
+		clock            *fakeclock.FakeClock
+		emitCh           chan struct{}
+		emitShards       int
+		greetingReporter *fakes.FakeGreetingReporter
+		fullEmits        *fakes.FakeFullEmitRequester
+		fakeMetronClient *mfakes.FakeIngressClient
 
 		shutdown chan struct{}
 
@@ -36,6 +42,10 @@ var _ = Describe("RouteBroadcastScheduler", func() {
 				clock = fakeclock.NewFakeClock(time.Now())
 
 				emitCh = make(chan struct{}, 1)
+				emitShards = 1
+				greetingReporter = &fakes.FakeGreetingReporter{}
+				fullEmits = &fakes.FakeFullEmitRequester{}
+				fakeMetronClient = &mfakes.FakeIngressClient{}
 				startMessages := make(chan *nats.Msg)
 				natsStartMessages = startMessages
 
@@ -52,7 +62,7 @@ var _ = Describe("RouteBroadcastScheduler", func() {
 
 			JustBeforeEach(func() {
 				logger := lagertest.NewTestLogger("test")
-				schedulerRunner = scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, prefix, emitCh)
+				schedulerRunner = scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, prefix, emitCh, emitShards, fullEmits, greetingReporter, fakeMetronClient)
 
 				shutdown = make(chan struct{})
 
@@ -87,21 +97,205 @@ var _ = Describe("RouteBroadcastScheduler", func() {
 							}
 						})
 
-						It("should emit routes with the frequency of the passed-in-interval", func() {
+						It("should emit routes well inside the prune threshold", func() {
 							Eventually(greetings).Should(Receive())
 							Consistently(schedulerRunner.EmitCh()).ShouldNot(Receive())
 
-							clock.WaitForWatcherAndIncrement(3 * time.Second)
+							clock.WaitForWatcherAndIncrement(time.Second)
 							Eventually(schedulerRunner.EmitCh()).Should(Receive())
 
-							clock.WaitForWatcherAndIncrement(2 * time.Second)
+							clock.WaitForWatcherAndIncrement(time.Second)
 							Eventually(schedulerRunner.EmitCh()).Should(Receive())
 						})
 
+						It("should report the effective interval", func() {
+							Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+							name, interval, opts := fakeMetronClient.SendDurationArgsForCall(0)
+							Expect(name).To(Equal("EffectiveEmitInterval"))
+							Expect(interval).To(Equal(time.Second))
+							Expect(opts).To(HaveLen(1))
+						})
+
 						It("should only greet the external service once", func() {
 							Eventually(greetings).Should(Receive())
 							Consistently(greetings, 1).ShouldNot(Receive())
 						})
+
+						It("should report the greeting", func() {
+							Eventually(greetingReporter.GreetingReceivedCallCount).Should(Equal(1))
+							Expect(greetingReporter.GreetingReceivedArgsForCall(0)).To(Equal(prefix))
+						})
+					})
+				})
+
+				Context("when the prune threshold leaves room for the minimum register interval", func() {
+					JustBeforeEach(func() {
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{"minimumRegisterIntervalInSeconds":2, "pruneThresholdInSeconds": 30}`),
+						}
+					})
+
+					It("should emit routes with the frequency of the minimum register interval", func() {
+						Eventually(greetings).Should(Receive())
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+
+						clock.WaitForWatcherAndIncrement(time.Second)
+						Consistently(schedulerRunner.EmitCh()).ShouldNot(Receive())
+
+						clock.WaitForWatcherAndIncrement(time.Second)
+						Eventually(schedulerRunner.EmitCh()).Should(Receive())
+					})
+				})
+
+				Context("when the emission is split into shards", func() {
+					BeforeEach(func() {
+						emitShards = 4
+					})
+
+					JustBeforeEach(func() {
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{"minimumRegisterIntervalInSeconds":2, "pruneThresholdInSeconds": 30}`),
+						}
+					})
+
+					It("should tick once per shard within the register interval", func() {
+						Eventually(greetings).Should(Receive())
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+
+						for i := 0; i < emitShards; i++ {
+							clock.WaitForWatcherAndIncrement(500 * time.Millisecond)
+							Eventually(schedulerRunner.EmitCh()).Should(Receive())
+						}
+						Expect(fullEmits.RequestFullEmitCallCount()).To(Equal(0))
+					})
+
+					It("should request a full emit when a new instance starts", func() {
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{"id":"router-2", "minimumRegisterIntervalInSeconds":2, "pruneThresholdInSeconds": 30}`),
+						}
+
+						// wait for the jitter before the emit
+						Eventually(clock.WatcherCount).Should(Equal(2))
+						clock.Increment(time.Second)
+						Eventually(schedulerRunner.EmitCh()).Should(Receive())
+						Expect(fullEmits.RequestFullEmitCallCount()).To(Equal(1))
+					})
+
+					Context("when the previous tick was not emitted yet", func() {
+						It("reports the dropped tick and catches up with a full emit", func() {
+							Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+
+							clock.WaitForWatcherAndIncrement(500 * time.Millisecond)
+							Eventually(func() int { return len(emitCh) }).Should(Equal(1))
+							clock.WaitForWatcherAndIncrement(500 * time.Millisecond)
+
+							Eventually(fullEmits.RequestFullEmitCallCount).Should(Equal(1))
+							Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+							Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("EmitTicksDropped"))
+						})
+					})
+				})
+
+				Context("when the external service only sends a prune threshold", func() {
+					JustBeforeEach(func() {
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{"pruneThresholdInSeconds": 6}`),
+						}
+					})
+
+					It("should derive the interval from the prune threshold", func() {
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+						_, interval, _ := fakeMetronClient.SendDurationArgsForCall(0)
+						Expect(interval).To(Equal(2 * time.Second))
+					})
+				})
+
+				Context("when the external service sends an invalid interval", func() {
+					JustBeforeEach(func() {
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{"minimumRegisterIntervalInSeconds":0, "pruneThresholdInSeconds": 0}`),
+						}
+					})
+
+					It("should ignore it and keep greeting", func() {
+						Eventually(greetings).Should(Receive())
+						clock.WaitForWatcherAndIncrement(time.Second)
+						Eventually(greetings).Should(Receive())
+						Expect(greetingReporter.GreetingReceivedCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when several external service instances advertise different intervals", func() {
+					JustBeforeEach(func() {
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{"id":"router-1", "minimumRegisterIntervalInSeconds":2, "pruneThresholdInSeconds": 30}`),
+						}
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+					})
+
+					It("should use the shortest interval", func() {
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{"id":"router-2", "minimumRegisterIntervalInSeconds":5, "pruneThresholdInSeconds": 3}`),
+						}
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(2))
+						_, interval, _ := fakeMetronClient.SendDurationArgsForCall(1)
+						Expect(interval).To(Equal(time.Second))
+
+						// wait for the jitter before the emit
+						Eventually(clock.WatcherCount).Should(Equal(2))
+						clock.Increment(time.Second)
+						Eventually(schedulerRunner.EmitCh()).Should(Receive())
+
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{"id":"router-1", "minimumRegisterIntervalInSeconds":10, "pruneThresholdInSeconds": 60}`),
+						}
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(3))
+						_, interval, _ = fakeMetronClient.SendDurationArgsForCall(2)
+						Expect(interval).To(Equal(time.Second))
+					})
+				})
+
+				Context("when an instance of the external service stops answering the greetings", func() {
+					BeforeEach(func() {
+						natsClient.WhenPublishing(fmt.Sprintf("%s.greet", prefix), func(msg *nats.Msg) error {
+							go natsClient.Publish(msg.Reply, []byte(`{"id":"router-long", "minimumRegisterIntervalInSeconds":10, "pruneThresholdInSeconds": 60}`))
+							return nil
+						})
+					})
+
+					JustBeforeEach(func() {
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{"id":"router-short", "minimumRegisterIntervalInSeconds":1, "pruneThresholdInSeconds": 3}`),
+						}
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(2))
+
+						// wait for the jitter before the emit
+						Eventually(clock.WatcherCount).Should(Equal(2))
+						clock.Increment(time.Second)
+						Eventually(schedulerRunner.EmitCh()).Should(Receive())
+					})
+
+					It("forgets it after a few of its intervals and goes back to the interval of the others", func() {
+						for i := 0; i < 3; i++ {
+							clock.WaitForWatcherAndIncrement(time.Second)
+							Eventually(schedulerRunner.EmitCh()).Should(Receive())
+						}
+
+						Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(3))
+						_, interval, _ := fakeMetronClient.SendDurationArgsForCall(2)
+						Expect(interval).To(Equal(10 * time.Second))
+					})
+
+					It("keeps greeting the instances that still answer", func() {
+						for i := 0; i < 2; i++ {
+							clock.WaitForWatcherAndIncrement(time.Second)
+							Eventually(schedulerRunner.EmitCh()).Should(Receive())
+						}
+						Eventually(func() int {
+							return len(natsClient.PublishedMessages(fmt.Sprintf("%s.greet", prefix)))
+						}).Should(BeNumerically(">=", 2))
 					})
 				})
 
@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const (
+	// defaultTCPRouteTTL is the ttl the routing api applies to mappings
+	// upserted without one.
+	defaultTCPRouteTTL = 120 * time.Second
+
+	// refreshFraction leaves room for two failed refreshes before the
+	// routing api expires the routes.
+	refreshFraction = 3
+)
+
+// RefreshScheduler ticks at a fraction of a route ttl so that the routes in
+// the routing api are refreshed even when NATS or the routers are
+// unavailable.
+type RefreshScheduler struct {
+	clock    clock.Clock
+	interval time.Duration
+	emitCh   chan struct{}
+	logger   lager.Logger
+}
+
+// NewTCPRefreshScheduler ticks for the tcp route mappings, a zero ttl is the
+// default ttl of the routing api.
+func NewTCPRefreshScheduler(
+	clock clock.Clock,
+	logger lager.Logger,
+	routeTTL time.Duration,
+	emitCh chan struct{},
+) *RefreshScheduler {
+	if routeTTL <= 0 {
+		routeTTL = defaultTCPRouteTTL
+	}
+
+	return newRefreshScheduler(clock, logger.Session("tcp-refresh-scheduler"), routeTTL, emitCh)
+}
+
+// NewHTTPRefreshScheduler ticks for the http routes emitted to the routing
+// api with the given ttl.
+func NewHTTPRefreshScheduler(
+	clock clock.Clock,
+	logger lager.Logger,
+	routeTTL time.Duration,
+	emitCh chan struct{},
+) *RefreshScheduler {
+	return newRefreshScheduler(clock, logger.Session("http-refresh-scheduler"), routeTTL, emitCh)
+}
+
+func newRefreshScheduler(clock clock.Clock, logger lager.Logger, routeTTL time.Duration, emitCh chan struct{}) *RefreshScheduler {
+	return &RefreshScheduler{
+		clock:    clock,
+		interval: routeTTL / refreshFraction,
+		emitCh:   emitCh,
+		logger:   logger,
+	}
+}
+
+func (s *RefreshScheduler) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	s.logger.Info("starting", lager.Data{"interval": s.interval.String()})
+	ticker := s.clock.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	close(ready)
+	s.logger.Info("started")
+
+	for {
+		select {
+		case <-ticker.C():
+			s.logger.Debug("refreshing-routes")
+			select {
+			case s.emitCh <- struct{}{}:
+			default:
+				s.logger.Debug("refresh-already-in-progress")
+			}
+		case <-signals:
+			s.logger.Info("stopping")
+			return nil
+		}
+	}
+}
+
+func (s *RefreshScheduler) EmitCh() chan struct{} {
+	return s.emitCh
+}
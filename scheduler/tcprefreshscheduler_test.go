@@ -0,0 +1,89 @@
+package scheduler_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/scheduler"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("TCPRefreshScheduler", func() {
+	var (
+		clock    *fakeclock.FakeClock
+		routeTTL time.Duration
+		emitCh   chan struct{}
+		process  ifrit.Process
+	)
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		routeTTL = 30 * time.Second
+		emitCh = make(chan struct{}, 1)
+	})
+
+	JustBeforeEach(func() {
+		runner := scheduler.NewTCPRefreshScheduler(clock, lagertest.NewTestLogger("test"), routeTTL, emitCh)
+		process = ifrit.Invoke(runner)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("ticks at a third of the route ttl", func() {
+		clock.WaitForWatcherAndIncrement(9 * time.Second)
+		Consistently(emitCh).ShouldNot(Receive())
+
+		clock.Increment(time.Second)
+		Eventually(emitCh).Should(Receive())
+
+		clock.Increment(10 * time.Second)
+		Eventually(emitCh).Should(Receive())
+	})
+
+	It("does not block when a refresh is already pending", func() {
+		clock.WaitForWatcherAndIncrement(10 * time.Second)
+		Eventually(emitCh).Should(HaveLen(1))
+
+		clock.Increment(10 * time.Second)
+		Consistently(emitCh).Should(HaveLen(1))
+	})
+
+	Context("when the route ttl is not set", func() {
+		BeforeEach(func() {
+			routeTTL = 0
+		})
+
+		It("uses the routing api default ttl", func() {
+			clock.WaitForWatcherAndIncrement(39 * time.Second)
+			Consistently(emitCh).ShouldNot(Receive())
+
+			clock.Increment(time.Second)
+			Eventually(emitCh).Should(Receive())
+		})
+	})
+})
+
+var _ = Describe("HTTPRefreshScheduler", func() {
+	It("ticks at a third of the route ttl", func() {
+		clock := fakeclock.NewFakeClock(time.Now())
+		emitCh := make(chan struct{}, 1)
+		process := ifrit.Invoke(scheduler.NewHTTPRefreshScheduler(clock, lagertest.NewTestLogger("test"), time.Minute, emitCh))
+		defer func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		}()
+
+		clock.WaitForWatcherAndIncrement(19 * time.Second)
+		Consistently(emitCh).ShouldNot(Receive())
+
+		clock.Increment(time.Second)
+		Eventually(emitCh).Should(Receive())
+	})
+})
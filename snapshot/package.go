@@ -0,0 +1 @@
+package snapshot // import "code.cloudfoundry.org/route-emitter/snapshot"
@@ -0,0 +1,158 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/jsonfile"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// Save atomically writes the snapshot to path.
+func Save(path string, snapshot routingtable.Snapshot) error {
+	return jsonfile.Save(path, snapshot)
+}
+
+func Load(path string) (routingtable.Snapshot, error) {
+	var snapshot routingtable.Snapshot
+	err := jsonfile.Load(path, &snapshot)
+	if err != nil {
+		return routingtable.Snapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// Restore loads the snapshot at path into the table. Snapshots older than
+// maxAge are ignored, a zero maxAge accepts snapshots of any age.
+func Restore(logger lager.Logger, clk clock.Clock, path string, maxAge time.Duration, table routingtable.RoutingTable) error {
+	logger = logger.Session("restore-snapshot", lager.Data{"path": path})
+
+	snapshot, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	age := clk.Since(snapshot.CreatedAt)
+	if maxAge > 0 && age > maxAge {
+		return fmt.Errorf("snapshot is too old: %s", age)
+	}
+
+	err = table.Restore(logger, snapshot)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("restored", lager.Data{
+		"age":        age.String(),
+		"table-size": table.TableSize(),
+	})
+	return nil
+}
+
+// SyncTracker reports when the table was last synced with the BBS.
+type SyncTracker interface {
+	LastSync() time.Time
+}
+
+type Writer struct {
+	logger lager.Logger
+	clock  clock.Clock
+	table  routingtable.RoutingTable
+	syncs  SyncTracker
+	path   string
+
+	lock            sync.Mutex
+	interval        time.Duration
+	intervalChanged chan struct{}
+}
+
+// NewWriter returns a runner that writes the table to path at an interval.
+// Nothing is written until the table was synced, an instance that never
+// syncs, e.g. while it waits for the lock, keeps the restored snapshot with
+// its original age.
+func NewWriter(
+	logger lager.Logger,
+	clock clock.Clock,
+	table routingtable.RoutingTable,
+	syncs SyncTracker,
+	path string,
+	interval time.Duration,
+) *Writer {
+	return &Writer{
+		logger:          logger.Session("snapshot-writer", lager.Data{"path": path}),
+		clock:           clock,
+		table:           table,
+		syncs:           syncs,
+		path:            path,
+		interval:        interval,
+		intervalChanged: make(chan struct{}, 1),
+	}
+}
+
+// SetInterval changes the interval at runtime, the next write happens one
+// new interval after the change.
+func (w *Writer) SetInterval(interval time.Duration) {
+	w.lock.Lock()
+	w.interval = interval
+	w.lock.Unlock()
+
+	select {
+	case w.intervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Writer) currentInterval() time.Duration {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.interval
+}
+
+func (w *Writer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	w.logger.Info("starting")
+	close(ready)
+	defer w.logger.Info("exiting")
+
+	ticker := w.clock.NewTicker(w.currentInterval())
+	defer func() { ticker.Stop() }()
+
+	for {
+		select {
+		case <-ticker.C():
+			w.write()
+		case <-w.intervalChanged:
+			ticker.Stop()
+			interval := w.currentInterval()
+			ticker = w.clock.NewTicker(interval)
+			w.logger.Info("changed-snapshot-interval", lager.Data{"interval": interval.String()})
+		case <-signals:
+			w.logger.Info("stopping")
+			w.write()
+			return nil
+		}
+	}
+}
+
+// write stamps the snapshot with the time of the last sync, so that the max
+// age on restore is measured from the last time the table matched the BBS.
+func (w *Writer) write() {
+	lastSync := w.syncs.LastSync()
+	if lastSync.IsZero() {
+		w.logger.Debug("skipping-write-before-first-sync")
+		return
+	}
+
+	snapshot := w.table.Snapshot()
+	snapshot.CreatedAt = lastSync
+
+	err := Save(w.path, snapshot)
+	if err != nil {
+		w.logger.Error("failed-to-write-snapshot", err)
+		return
+	}
+	w.logger.Debug("wrote-snapshot")
+}
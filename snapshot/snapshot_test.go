@@ -0,0 +1,208 @@
+package snapshot_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/health"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	"code.cloudfoundry.org/route-emitter/snapshot"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Snapshot", func() {
+	var (
+		tmpDir    string
+		path      string
+		logger    *lagertest.TestLogger
+		clock     *fakeclock.FakeClock
+		fakeTable *fakeroutingtable.FakeRoutingTable
+		tableSnap routingtable.Snapshot
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "snapshot")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(tmpDir, "routing-table.json")
+
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		fakeTable = &fakeroutingtable.FakeRoutingTable{}
+
+		key := routingtable.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+		tableSnap = routingtable.Snapshot{
+			Version:   routingtable.SnapshotVersion,
+			CreatedAt: clock.Now().UTC(),
+			TableEntries: routingtable.TableEntries{
+				HTTP: map[string]routingtable.Entry{
+					key.String(): {
+						RoutingKey: key,
+						Domain:     "domain",
+						Routes:     []routingtable.Route{{Hostname: "foo.example.com", LogGUID: "log-guid"}},
+						Endpoints:  []routingtable.Endpoint{{InstanceGUID: "instance-guid", Host: "1.1.1.1", Port: 61000, ContainerPort: 8080}},
+					},
+				},
+				TCP:      map[string]routingtable.Entry{},
+				Internal: map[string]routingtable.Entry{},
+			},
+		}
+		fakeTable.SnapshotReturns(tableSnap)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Describe("Save and Load", func() {
+		It("round trips the snapshot", func() {
+			Expect(snapshot.Save(path, tableSnap)).To(Succeed())
+
+			loaded, err := snapshot.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded.Version).To(Equal(routingtable.SnapshotVersion))
+			Expect(loaded.CreatedAt.Equal(tableSnap.CreatedAt)).To(BeTrue())
+			Expect(loaded.HTTP).To(HaveKey("process-guid:8080"))
+			Expect(loaded.HTTP["process-guid:8080"].Routes).To(Equal(tableSnap.HTTP["process-guid:8080"].Routes))
+		})
+
+		It("does not leave temporary files behind", func() {
+			Expect(snapshot.Save(path, tableSnap)).To(Succeed())
+
+			files, err := os.ReadDir(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+		})
+
+		Context("when the file does not exist", func() {
+			It("returns an error", func() {
+				_, err := snapshot.Load(path)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Restore", func() {
+		BeforeEach(func() {
+			Expect(snapshot.Save(path, tableSnap)).To(Succeed())
+		})
+
+		It("restores the table from the snapshot", func() {
+			Expect(snapshot.Restore(logger, clock, path, time.Hour, fakeTable)).To(Succeed())
+			Expect(fakeTable.RestoreCallCount()).To(Equal(1))
+			_, restored := fakeTable.RestoreArgsForCall(0)
+			Expect(restored.HTTP).To(HaveKey("process-guid:8080"))
+		})
+
+		Context("when the snapshot is older than the max age", func() {
+			It("does not restore the table", func() {
+				clock.Increment(2 * time.Hour)
+				Expect(snapshot.Restore(logger, clock, path, time.Hour, fakeTable)).To(MatchError(ContainSubstring("too old")))
+				Expect(fakeTable.RestoreCallCount()).To(Equal(0))
+			})
+
+			It("restores the table when there is no max age", func() {
+				clock.Increment(2 * time.Hour)
+				Expect(snapshot.Restore(logger, clock, path, 0, fakeTable)).To(Succeed())
+			})
+		})
+
+		Context("when the table rejects the snapshot", func() {
+			It("returns the error", func() {
+				fakeTable.RestoreReturns(errors.New("boom"))
+				Expect(snapshot.Restore(logger, clock, path, 0, fakeTable)).To(MatchError("boom"))
+			})
+		})
+	})
+
+	Describe("Writer", func() {
+		var (
+			writer      *snapshot.Writer
+			process     ifrit.Process
+			healthState *health.State
+			syncedAt    time.Time
+		)
+
+		BeforeEach(func() {
+			healthState = health.NewState(clock, nil, health.Thresholds{})
+			healthState.SyncSucceeded()
+			syncedAt = clock.Now()
+		})
+
+		JustBeforeEach(func() {
+			writer = snapshot.NewWriter(logger, clock, fakeTable, healthState, path, 10*time.Second)
+			process = ifrit.Invoke(writer)
+		})
+
+		AfterEach(func() {
+			ifrit.Interrupt(process)
+			Eventually(process.Wait()).Should(Receive())
+		})
+
+		It("writes the snapshot on every interval", func() {
+			clock.WaitForWatcherAndIncrement(10 * time.Second)
+			Eventually(func() error {
+				_, err := snapshot.Load(path)
+				return err
+			}).Should(Succeed())
+			Expect(fakeTable.SnapshotCallCount()).To(Equal(1))
+
+			clock.WaitForWatcherAndIncrement(10 * time.Second)
+			Eventually(fakeTable.SnapshotCallCount).Should(Equal(2))
+		})
+
+		It("writes at the interval it was changed to", func() {
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			writer.SetInterval(time.Minute)
+			Eventually(logger).Should(gbytes.Say("changed-snapshot-interval"))
+
+			clock.WaitForWatcherAndIncrement(10 * time.Second)
+			Consistently(fakeTable.SnapshotCallCount).Should(Equal(0))
+
+			clock.WaitForWatcherAndIncrement(50 * time.Second)
+			Eventually(fakeTable.SnapshotCallCount).Should(Equal(1))
+		})
+
+		It("writes the snapshot when stopping", func() {
+			ifrit.Interrupt(process)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+
+			loaded, err := snapshot.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded.CreatedAt.Equal(syncedAt)).To(BeTrue())
+		})
+
+		It("stamps the snapshot with the time of the last sync", func() {
+			clock.WaitForWatcherAndIncrement(10 * time.Second)
+			Eventually(fakeTable.SnapshotCallCount).Should(Equal(1))
+
+			loaded, err := snapshot.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded.CreatedAt.Equal(syncedAt)).To(BeTrue())
+		})
+
+		Context("when the table was never synced", func() {
+			BeforeEach(func() {
+				healthState = health.NewState(clock, nil, health.Thresholds{})
+			})
+
+			It("does not overwrite the snapshot", func() {
+				clock.WaitForWatcherAndIncrement(10 * time.Second)
+				ifrit.Interrupt(process)
+				Eventually(process.Wait()).Should(Receive(BeNil()))
+
+				Expect(fakeTable.SnapshotCallCount()).To(Equal(0))
+				_, err := os.Stat(path)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+	})
+})
@@ -2,6 +2,7 @@ package syncer
 
 import (
 	"os"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/clock"
@@ -10,10 +11,13 @@ import (
 
 type NatsSyncer struct {
 	clock                clock.Clock
-	syncInterval         time.Duration
 	syncCh               chan struct{}
 	externalServiceStart chan time.Duration
 
+	lock            sync.Mutex
+	syncInterval    time.Duration
+	intervalChanged chan struct{}
+
 	logger lager.Logger
 }
 
@@ -23,9 +27,10 @@ func NewSyncer(
 	logger lager.Logger,
 ) *NatsSyncer {
 	return &NatsSyncer{
-		clock:        clock,
-		syncInterval: syncInterval,
-		syncCh:       make(chan struct{}, 1),
+		clock:           clock,
+		syncInterval:    syncInterval,
+		syncCh:          make(chan struct{}, 1),
+		intervalChanged: make(chan struct{}, 1),
 
 		externalServiceStart: make(chan time.Duration),
 
@@ -40,12 +45,17 @@ func (s *NatsSyncer) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 	s.sync()
 
 	// now keep emitting at the desired interval, syncing every syncInterval
-	syncTicker := s.clock.NewTicker(s.syncInterval)
+	syncTicker := s.clock.NewTicker(s.interval())
 
 	for {
 		select {
 		case <-syncTicker.C():
 			s.sync()
+		case <-s.intervalChanged:
+			syncTicker.Stop()
+			interval := s.interval()
+			syncTicker = s.clock.NewTicker(interval)
+			s.logger.Info("changed-sync-interval", lager.Data{"sync-interval": interval.String()})
 		case <-signals:
 			s.logger.Info("stopping")
 			syncTicker.Stop()
@@ -54,10 +64,29 @@ func (s *NatsSyncer) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 	}
 }
 
+// SetSyncInterval changes the interval at runtime, the next sync happens one
+// new interval after the change.
+func (s *NatsSyncer) SetSyncInterval(syncInterval time.Duration) {
+	s.lock.Lock()
+	s.syncInterval = syncInterval
+	s.lock.Unlock()
+
+	select {
+	case s.intervalChanged <- struct{}{}:
+	default:
+	}
+}
+
 func (s *NatsSyncer) SyncCh() chan struct{} {
 	return s.syncCh
 }
 
+func (s *NatsSyncer) interval() time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.syncInterval
+}
+
 func (s *NatsSyncer) sync() {
 	s.syncCh <- struct{}{}
 }
@@ -9,6 +9,7 @@ import (
 	"code.cloudfoundry.org/route-emitter/syncer"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/tedsuo/ifrit"
 )
 
@@ -18,6 +19,7 @@ var _ = Describe("NatsSyncer", func() {
 		process      ifrit.Process
 		clock        *fakeclock.FakeClock
 		syncInterval time.Duration
+		logger       *lagertest.TestLogger
 
 		shutdown chan struct{}
 	)
@@ -28,7 +30,7 @@ var _ = Describe("NatsSyncer", func() {
 	})
 
 	JustBeforeEach(func() {
-		logger := lagertest.NewTestLogger("test")
+		logger = lagertest.NewTestLogger("test")
 		syncerRunner = syncer.NewSyncer(clock, syncInterval, logger)
 
 		shutdown = make(chan struct{})
@@ -55,4 +57,19 @@ var _ = Describe("NatsSyncer", func() {
 			Eventually(syncerRunner.SyncCh()).Should(Receive())
 		})
 	})
+
+	Context("when the sync interval is changed", func() {
+		It("syncs at the new interval", func() {
+			Eventually(syncerRunner.SyncCh()).Should(Receive())
+
+			syncerRunner.SetSyncInterval(2 * time.Second)
+			Eventually(logger).Should(gbytes.Say("changed-sync-interval"))
+
+			clock.WaitForWatcherAndIncrement(syncInterval)
+			Consistently(syncerRunner.SyncCh()).ShouldNot(Receive())
+
+			clock.WaitForWatcherAndIncrement(2*time.Second - syncInterval)
+			Eventually(syncerRunner.SyncCh()).Should(Receive())
+		})
+	})
 })
@@ -0,0 +1,76 @@
+package tlsreload
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// BBSClient forwards the watcher's calls to a bbs.Client that is created
+// again whenever the certificates of its Source change, since the BBS client
+// only reads its certificates when it is created. Event streams that are
+// already open keep their connection until they are resubscribed.
+type BBSClient struct {
+	logger    lager.Logger
+	newClient func() (bbs.Client, error)
+
+	lock   sync.RWMutex
+	client bbs.Client
+}
+
+func NewBBSClient(logger lager.Logger, source *Source, newClient func() (bbs.Client, error)) (*BBSClient, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &BBSClient{
+		logger:    logger.Session("bbs-client"),
+		newClient: newClient,
+		client:    client,
+	}
+	source.OnChange(c.recreate)
+	return c, nil
+}
+
+func (c *BBSClient) recreate() {
+	client, err := c.newClient()
+	if err != nil {
+		c.logger.Error("failed-to-recreate-client", err)
+		return
+	}
+
+	c.lock.Lock()
+	c.client = client
+	c.lock.Unlock()
+	c.logger.Info("recreated-client")
+}
+
+func (c *BBSClient) current() bbs.Client {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.client
+}
+
+func (c *BBSClient) ActualLRPs(logger lager.Logger, traceID string, filter models.ActualLRPFilter) ([]*models.ActualLRP, error) {
+	return c.current().ActualLRPs(logger, traceID, filter)
+}
+
+func (c *BBSClient) DesiredLRPs(logger lager.Logger, traceID string, filter models.DesiredLRPFilter) ([]*models.DesiredLRP, error) {
+	return c.current().DesiredLRPs(logger, traceID, filter)
+}
+
+func (c *BBSClient) DesiredLRPRoutingInfos(logger lager.Logger, traceID string, filter models.DesiredLRPFilter) ([]*models.DesiredLRP, error) {
+	return c.current().DesiredLRPRoutingInfos(logger, traceID, filter)
+}
+
+func (c *BBSClient) Domains(logger lager.Logger, traceID string) ([]string, error) {
+	return c.current().Domains(logger, traceID)
+}
+
+func (c *BBSClient) SubscribeToInstanceEventsByCellID(logger lager.Logger, cellID string) (events.EventSource, error) {
+	return c.current().SubscribeToInstanceEventsByCellID(logger, cellID)
+}
@@ -0,0 +1,102 @@
+package tlsreload_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/fake_bbs"
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/route-emitter/tlsreload"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("BBSClient", func() {
+	var (
+		certDepot   string
+		caFile      string
+		otherCAFile string
+		source      *tlsreload.Source
+		clients     []*fake_bbs.FakeClient
+		createErr   error
+		bbsClient   *tlsreload.BBSClient
+		logger      *lagertest.TestLogger
+	)
+
+	BeforeEach(func() {
+		var err error
+		certDepot, err = os.MkdirTemp("", "tlsreload")
+		Expect(err).NotTo(HaveOccurred())
+
+		certAuthority, err := certauthority.NewCertAuthority(certDepot, "ca")
+		Expect(err).NotTo(HaveOccurred())
+		_, generatedCAFile := certAuthority.CAAndKey()
+		caFile = filepath.Join(certDepot, "current-ca.crt")
+		content, err := os.ReadFile(generatedCAFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(caFile, content, 0600)).To(Succeed())
+
+		otherAuthority, err := certauthority.NewCertAuthority(certDepot, "other-ca")
+		Expect(err).NotTo(HaveOccurred())
+		_, otherCAFile = otherAuthority.CAAndKey()
+
+		source, err = tlsreload.NewSource("BBSClientCertificateExpiry", metrics.Labels{}, "", "", caFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		clients = nil
+		createErr = nil
+		logger = lagertest.NewTestLogger("test")
+		bbsClient, err = tlsreload.NewBBSClient(logger, source, func() (bbs.Client, error) {
+			if createErr != nil {
+				return nil, createErr
+			}
+			client := &fake_bbs.FakeClient{}
+			clients = append(clients, client)
+			return client, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(certDepot)).To(Succeed())
+	})
+
+	rotate := func() {
+		content, err := os.ReadFile(otherCAFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(caFile, content, 0600)).To(Succeed())
+		changed, err := source.Reload()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+	}
+
+	It("uses a new client once the certificates changed", func() {
+		_, err := bbsClient.Domains(logger, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clients).To(HaveLen(1))
+		Expect(clients[0].DomainsCallCount()).To(Equal(1))
+
+		rotate()
+
+		_, err = bbsClient.Domains(logger, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clients).To(HaveLen(2))
+		Expect(clients[0].DomainsCallCount()).To(Equal(1))
+		Expect(clients[1].DomainsCallCount()).To(Equal(1))
+	})
+
+	It("keeps the previous client when a new one cannot be created", func() {
+		createErr = errors.New("boom")
+		rotate()
+
+		_, err := bbsClient.Domains(logger, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clients[0].DomainsCallCount()).To(Equal(1))
+		Expect(logger).To(gbytes.Say("failed-to-recreate-client"))
+	})
+})
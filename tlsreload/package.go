@@ -0,0 +1 @@
+package tlsreload // import "code.cloudfoundry.org/route-emitter/tlsreload"
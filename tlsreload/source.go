@@ -0,0 +1,285 @@
+package tlsreload
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/tlsconfig"
+)
+
+var (
+	ErrNoCertificates = errors.New("no certificates found in CA file")
+	ErrNoServerName   = errors.New("no server name to verify the server certificate against")
+)
+
+// Source holds the client certificate and the certificate authorities read
+// from a set of files. Either the key pair or the CA file may be empty. The
+// TLS configs built from a Source read the material on every handshake, so
+// new connections use the files as of the last Reload.
+type Source struct {
+	metric   string
+	labels   metrics.Labels
+	certFile string
+	keyFile  string
+	caFile   string
+
+	lock        sync.RWMutex
+	contents    [][]byte
+	certificate *tls.Certificate
+	rootCAs     *x509.CertPool
+	notAfter    time.Time
+	listeners   []func()
+}
+
+// NewSource loads the files, metric names the gauge the Watcher reports the
+// expiry of the certificates with, e.g. "NATSCertificateExpiry".
+func NewSource(metric string, labels metrics.Labels, certFile, keyFile, caFile string) (*Source, error) {
+	s := &Source{
+		metric:   metric,
+		labels:   labels,
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+
+	_, err := s.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the files again and returns whether they changed. Invalid
+// files, e.g. a certificate that was written before its key, are reported
+// and the previous material is kept.
+func (s *Source) Reload() (bool, error) {
+	contents := [][]byte{}
+	for _, file := range []string{s.certFile, s.keyFile, s.caFile} {
+		if file == "" {
+			contents = append(contents, nil)
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return false, err
+		}
+		contents = append(contents, content)
+	}
+
+	s.lock.RLock()
+	unchanged := s.contents != nil && equal(s.contents, contents)
+	s.lock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	var certificate *tls.Certificate
+	var notAfter time.Time
+	if s.certFile != "" {
+		keyPair, err := tls.X509KeyPair(contents[0], contents[1])
+		if err != nil {
+			return false, err
+		}
+		keyPair.Leaf, err = x509.ParseCertificate(keyPair.Certificate[0])
+		if err != nil {
+			return false, err
+		}
+		certificate = &keyPair
+		notAfter = keyPair.Leaf.NotAfter
+	}
+
+	var rootCAs *x509.CertPool
+	if s.caFile != "" {
+		var caNotAfter time.Time
+		var err error
+		rootCAs, caNotAfter, err = parseCertificateAuthorities(contents[2])
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", s.caFile, err)
+		}
+		if notAfter.IsZero() || caNotAfter.Before(notAfter) {
+			notAfter = caNotAfter
+		}
+	}
+
+	s.lock.Lock()
+	s.contents = contents
+	s.certificate = certificate
+	s.rootCAs = rootCAs
+	s.notAfter = notAfter
+	listeners := append([]func(){}, s.listeners...)
+	s.lock.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+	return true, nil
+}
+
+// OnChange registers a listener that is called after the files changed, for
+// clients that cannot read the material on every handshake.
+func (s *Source) OnChange(listener func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// NotAfter returns the earliest expiry of the client certificate and the
+// certificate authorities.
+func (s *Source) NotAfter() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.notAfter
+}
+
+func (s *Source) Certificate() (tls.Certificate, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.certificate == nil {
+		return tls.Certificate{}, errors.New("no certificate configured")
+	}
+	return *s.certificate, nil
+}
+
+func (s *Source) RootCAs() (*x509.CertPool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.rootCAs == nil {
+		return nil, errors.New("no certificate authorities configured")
+	}
+	return s.rootCAs, nil
+}
+
+// ClientTLSConfig returns a client config with the internal service
+// defaults. The server certificate is verified against the current
+// certificate authorities instead of a fixed pool, which is why the standard
+// verification is replaced. The replacement checks the hostname like the
+// standard verification, a handshake without a server name fails with
+// ErrNoServerName. The clients set it from the address they dial unless the
+// config sets one.
+func (s *Source) ClientTLSConfig() (*tls.Config, error) {
+	config, err := tlsconfig.Build(tlsconfig.WithInternalServiceDefaults()).Client()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.certFile != "" {
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, err := s.Certificate()
+			return &certificate, err
+		}
+	}
+
+	if s.caFile != "" {
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = s.verifyConnection
+	}
+	return config, nil
+}
+
+// ServerTLSConfig returns a server config with the internal service defaults
+// that presents the current certificate. When the source has certificate
+// authorities, clients must present a certificate signed by one of the
+// current ones.
+func (s *Source) ServerTLSConfig() (*tls.Config, error) {
+	config, err := tlsconfig.Build(tlsconfig.WithInternalServiceDefaults()).Server()
+	if err != nil {
+		return nil, err
+	}
+
+	config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		certificate, err := s.Certificate()
+		return &certificate, err
+	}
+
+	if s.caFile != "" {
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyConnection = s.verifyClient
+	}
+	return config, nil
+}
+
+func (s *Source) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("no server certificate presented")
+	}
+	// an empty DNSName would skip the hostname check
+	if state.ServerName == "" {
+		return ErrNoServerName
+	}
+
+	return s.verifyChain(state.PeerCertificates, x509.VerifyOptions{DNSName: state.ServerName})
+}
+
+func (s *Source) verifyClient(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("no client certificate presented")
+	}
+
+	return s.verifyChain(state.PeerCertificates, x509.VerifyOptions{
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+}
+
+func (s *Source) verifyChain(certificates []*x509.Certificate, options x509.VerifyOptions) error {
+	rootCAs, err := s.RootCAs()
+	if err != nil {
+		return err
+	}
+
+	options.Roots = rootCAs
+	options.Intermediates = x509.NewCertPool()
+	for _, certificate := range certificates[1:] {
+		options.Intermediates.AddCert(certificate)
+	}
+	_, err = certificates[0].Verify(options)
+	return err
+}
+
+func parseCertificateAuthorities(content []byte) (*x509.CertPool, time.Time, error) {
+	pool := x509.NewCertPool()
+	var notAfter time.Time
+	rest := content
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		pool.AddCert(certificate)
+		if notAfter.IsZero() || certificate.NotAfter.Before(notAfter) {
+			notAfter = certificate.NotAfter
+		}
+	}
+
+	if notAfter.IsZero() {
+		return nil, time.Time{}, ErrNoCertificates
+	}
+	return pool, notAfter, nil
+}
+
+func equal(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+package tlsreload_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/route-emitter/tlsreload"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Source", func() {
+	var (
+		certDepot                   string
+		certAuthority               certauthority.CertAuthority
+		certFile, keyFile, caFile   string
+		otherCertFile, otherKeyFile string
+	)
+
+	copyFile := func(from, to string) {
+		content, err := os.ReadFile(from)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(to, content, 0600)).To(Succeed())
+	}
+
+	peerCertificate := func(file string) *x509.Certificate {
+		content, err := os.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+		block, _ := pem.Decode(content)
+		Expect(block).NotTo(BeNil())
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+		return certificate
+	}
+
+	BeforeEach(func() {
+		var err error
+		certDepot, err = os.MkdirTemp("", "tlsreload")
+		Expect(err).NotTo(HaveOccurred())
+
+		certAuthority, err = certauthority.NewCertAuthority(certDepot, "ca")
+		Expect(err).NotTo(HaveOccurred())
+		_, caFile = certAuthority.CAAndKey()
+
+		generatedKey, generatedCert, err := certAuthority.GenerateSelfSignedCertAndKey("client", []string{"client"}, false)
+		Expect(err).NotTo(HaveOccurred())
+		certFile = filepath.Join(certDepot, "current.crt")
+		keyFile = filepath.Join(certDepot, "current.key")
+		copyFile(generatedCert, certFile)
+		copyFile(generatedKey, keyFile)
+
+		otherKeyFile, otherCertFile, err = certAuthority.GenerateSelfSignedCertAndKey("rotated", []string{"rotated"}, false)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(certDepot)).To(Succeed())
+	})
+
+	It("loads the client certificate and the certificate authorities", func() {
+		source, err := tlsreload.NewSource("BBSClientCertificateExpiry", metrics.Labels{}, certFile, keyFile, caFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		certificate, err := source.Certificate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificate.Leaf.Subject.CommonName).To(Equal("client"))
+		Expect(source.NotAfter()).To(Equal(certificate.Leaf.NotAfter))
+
+		_, err = source.RootCAs()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("fails when the CA file contains no certificates", func() {
+		Expect(os.WriteFile(caFile, []byte("not a certificate"), 0600)).To(Succeed())
+		_, err := tlsreload.NewSource("BBSClientCertificateExpiry", metrics.Labels{}, certFile, keyFile, caFile)
+		Expect(err).To(MatchError(ContainSubstring(tlsreload.ErrNoCertificates.Error())))
+	})
+
+	Describe("Reload", func() {
+		var (
+			source  *tlsreload.Source
+			changes int
+		)
+
+		BeforeEach(func() {
+			var err error
+			source, err = tlsreload.NewSource("BBSClientCertificateExpiry", metrics.Labels{}, certFile, keyFile, caFile)
+			Expect(err).NotTo(HaveOccurred())
+			changes = 0
+			source.OnChange(func() { changes++ })
+		})
+
+		It("does nothing when the files did not change", func() {
+			changed, err := source.Reload()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeFalse())
+			Expect(changes).To(Equal(0))
+		})
+
+		It("uses the rotated certificate and notifies the listeners", func() {
+			copyFile(otherCertFile, certFile)
+			copyFile(otherKeyFile, keyFile)
+
+			changed, err := source.Reload()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			Expect(changes).To(Equal(1))
+
+			certificate, err := source.Certificate()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certificate.Leaf.Subject.CommonName).To(Equal("rotated"))
+		})
+
+		It("keeps the previous certificate when the key does not match yet", func() {
+			copyFile(otherCertFile, certFile)
+
+			_, err := source.Reload()
+			Expect(err).To(HaveOccurred())
+			Expect(changes).To(Equal(0))
+
+			certificate, err := source.Certificate()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certificate.Leaf.Subject.CommonName).To(Equal("client"))
+		})
+	})
+
+	Describe("ClientTLSConfig", func() {
+		var (
+			source    *tlsreload.Source
+			tlsConfig *tls.Config
+		)
+
+		BeforeEach(func() {
+			var err error
+			source, err = tlsreload.NewSource("RoutingAPIClientCertificateExpiry", metrics.Labels{}, certFile, keyFile, caFile)
+			Expect(err).NotTo(HaveOccurred())
+			tlsConfig, err = source.ClientTLSConfig()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("presents the current client certificate", func() {
+			copyFile(otherCertFile, certFile)
+			copyFile(otherKeyFile, keyFile)
+			_, err := source.Reload()
+			Expect(err).NotTo(HaveOccurred())
+
+			certificate, err := tlsConfig.GetClientCertificate(&tls.CertificateRequestInfo{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certificate.Leaf.Subject.CommonName).To(Equal("rotated"))
+		})
+
+		It("verifies the server against the current certificate authorities", func() {
+			state := tls.ConnectionState{
+				ServerName:       "rotated",
+				PeerCertificates: []*x509.Certificate{peerCertificate(otherCertFile)},
+			}
+			Expect(tlsConfig.VerifyConnection(state)).To(Succeed())
+
+			otherAuthority, err := certauthority.NewCertAuthority(certDepot, "other-ca")
+			Expect(err).NotTo(HaveOccurred())
+			_, otherCAFile := otherAuthority.CAAndKey()
+			copyFile(otherCAFile, caFile)
+			_, err = source.Reload()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(tlsConfig.VerifyConnection(state)).To(HaveOccurred())
+		})
+
+		It("rejects a server certificate for another name", func() {
+			state := tls.ConnectionState{
+				ServerName:       "routing-api",
+				PeerCertificates: []*x509.Certificate{peerCertificate(otherCertFile)},
+			}
+			Expect(tlsConfig.VerifyConnection(state)).To(HaveOccurred())
+		})
+
+		It("fails closed without a server name", func() {
+			state := tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{peerCertificate(otherCertFile)},
+			}
+			Expect(tlsConfig.VerifyConnection(state)).To(MatchError(tlsreload.ErrNoServerName))
+		})
+	})
+
+	Describe("ServerTLSConfig", func() {
+		var (
+			source    *tlsreload.Source
+			tlsConfig *tls.Config
+		)
+
+		BeforeEach(func() {
+			var err error
+			source, err = tlsreload.NewSource("XDSServerCertificateExpiry", metrics.Labels{}, certFile, keyFile, caFile)
+			Expect(err).NotTo(HaveOccurred())
+			tlsConfig, err = source.ServerTLSConfig()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("presents the current certificate", func() {
+			copyFile(otherCertFile, certFile)
+			copyFile(otherKeyFile, keyFile)
+			_, err := source.Reload()
+			Expect(err).NotTo(HaveOccurred())
+
+			certificate, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certificate.Leaf.Subject.CommonName).To(Equal("rotated"))
+		})
+
+		It("requires a client certificate signed by the current certificate authorities", func() {
+			Expect(tlsConfig.ClientAuth).To(Equal(tls.RequireAnyClientCert))
+			Expect(tlsConfig.VerifyConnection(tls.ConnectionState{})).To(HaveOccurred())
+
+			state := tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{peerCertificate(otherCertFile)},
+			}
+			Expect(tlsConfig.VerifyConnection(state)).To(Succeed())
+
+			otherAuthority, err := certauthority.NewCertAuthority(certDepot, "other-ca")
+			Expect(err).NotTo(HaveOccurred())
+			_, otherCAFile := otherAuthority.CAAndKey()
+			copyFile(otherCAFile, caFile)
+			_, err = source.Reload()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(tlsConfig.VerifyConnection(state)).To(HaveOccurred())
+		})
+
+		It("does not ask for a client certificate without certificate authorities", func() {
+			source, err := tlsreload.NewSource("XDSServerCertificateExpiry", metrics.Labels{}, certFile, keyFile, "")
+			Expect(err).NotTo(HaveOccurred())
+			tlsConfig, err := source.ServerTLSConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tlsConfig.ClientAuth).To(Equal(tls.NoClientCert))
+		})
+	})
+})
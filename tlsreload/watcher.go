@@ -0,0 +1,85 @@
+package tlsreload
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/metrics"
+)
+
+// Watcher reloads the sources at an interval and reports the expiry of
+// their certificates as a Unix timestamp.
+type Watcher struct {
+	logger       lager.Logger
+	clock        clock.Clock
+	metronClient loggingclient.IngressClient
+	interval     time.Duration
+	sources      []*Source
+}
+
+func NewWatcher(
+	logger lager.Logger,
+	clock clock.Clock,
+	metronClient loggingclient.IngressClient,
+	interval time.Duration,
+) *Watcher {
+	return &Watcher{
+		logger:       logger.Session("tls-watcher"),
+		clock:        clock,
+		metronClient: metronClient,
+		interval:     interval,
+	}
+}
+
+// Add watches the source, sources are added before the watcher is run.
+func (w *Watcher) Add(source *Source) {
+	w.sources = append(w.sources, source)
+}
+
+func (w *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	w.logger.Info("starting")
+	close(ready)
+	defer w.logger.Info("exiting")
+
+	w.reportExpiry()
+
+	ticker := w.clock.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			w.reload()
+			w.reportExpiry()
+		case <-signals:
+			w.logger.Info("stopping")
+			return nil
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	for _, source := range w.sources {
+		logger := w.logger.WithData(lager.Data{"cert-file": source.certFile, "ca-file": source.caFile})
+		changed, err := source.Reload()
+		if err != nil {
+			logger.Error("failed-to-reload-certificates", err)
+			continue
+		}
+		if changed {
+			logger.Info("reloaded-certificates", lager.Data{"not-after": source.NotAfter()})
+		}
+	}
+}
+
+func (w *Watcher) reportExpiry() {
+	for _, source := range w.sources {
+		err := w.metronClient.SendMetric(source.metric, int(source.NotAfter().Unix()), metrics.WithLabels(source.labels)...)
+		if err != nil {
+			w.logger.Error("failed-to-send-certificate-expiry-metric", err, lager.Data{"metric": source.metric})
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package tlsreload_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/route-emitter/tlsreload"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	ginkgomon "github.com/tedsuo/ifrit/ginkgomon_v2"
+)
+
+var _ = Describe("Watcher", func() {
+	var (
+		certDepot        string
+		caFile           string
+		otherCAFile      string
+		fakeClock        *fakeclock.FakeClock
+		fakeMetronClient *mfakes.FakeIngressClient
+		source           *tlsreload.Source
+		process          ifrit.Process
+	)
+
+	BeforeEach(func() {
+		var err error
+		certDepot, err = os.MkdirTemp("", "tlsreload")
+		Expect(err).NotTo(HaveOccurred())
+
+		certAuthority, err := certauthority.NewCertAuthority(certDepot, "ca")
+		Expect(err).NotTo(HaveOccurred())
+		_, generatedCAFile := certAuthority.CAAndKey()
+		caFile = filepath.Join(certDepot, "current-ca.crt")
+		content, err := os.ReadFile(generatedCAFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(caFile, content, 0600)).To(Succeed())
+
+		otherAuthority, err := certauthority.NewCertAuthority(certDepot, "other-ca")
+		Expect(err).NotTo(HaveOccurred())
+		_, otherCAFile = otherAuthority.CAAndKey()
+
+		source, err = tlsreload.NewSource("NATSCertificateExpiry", metrics.Labels{Target: "new-cluster"}, "", "", caFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		watcher := tlsreload.NewWatcher(lagertest.NewTestLogger("test"), fakeClock, fakeMetronClient, time.Minute)
+		watcher.Add(source)
+		process = ginkgomon.Invoke(watcher)
+	})
+
+	AfterEach(func() {
+		ginkgomon.Interrupt(process)
+		Expect(os.RemoveAll(certDepot)).To(Succeed())
+	})
+
+	It("reports the expiry of the certificates", func() {
+		Eventually(fakeMetronClient.SendMetricCallCount).Should(Equal(1))
+		name, value, opts := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(name).To(Equal("NATSCertificateExpiry"))
+		Expect(value).To(Equal(int(source.NotAfter().Unix())))
+		Expect(opts).To(HaveLen(1))
+	})
+
+	It("reloads the certificates at the interval", func() {
+		changed := make(chan struct{}, 1)
+		source.OnChange(func() { changed <- struct{}{} })
+		content, err := os.ReadFile(otherCAFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(caFile, content, 0600)).To(Succeed())
+
+		fakeClock.WaitForWatcherAndIncrement(time.Minute)
+		Eventually(changed).Should(Receive())
+		Eventually(fakeMetronClient.SendMetricCallCount).Should(Equal(2))
+	})
+})
@@ -1,72 +1,329 @@
 package unregistration
 
 import (
+	"container/list"
+	"sort"
 	"sync"
+	"time"
 
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-api/models"
 	"github.com/mitchellh/hashstructure"
 )
 
+const (
+	unregistrationCacheSizeMetric     = "UnregistrationCacheSize"
+	unregistrationCacheEvictedCounter = "UnregistrationCacheEvicted"
+	unregistrationCacheExpiredCounter = "UnregistrationCacheExpired"
+)
+
 //go:generate counterfeiter -o fakes/fake_cache.go . Cache
 type Cache interface {
 	Add([]routingtable.RegistryMessage) error
 	Remove([]routingtable.RegistryMessage) error
+	AddInternal([]routingtable.RegistryMessage) error
+	RemoveInternal([]routingtable.RegistryMessage) error
+	AddTCP([]models.TcpRouteMapping) error
+	RemoveTCP([]models.TcpRouteMapping) error
 	List() []*Message
+	MarkSent(*Message) int
+	Snapshot() []Message
+	Restore([]Message) error
+}
+
+type cacheKey struct {
+	routeType RouteType
+	hash      uint64
+}
+
+// tcpMappingKey holds the fields that identify a tcp route mapping, the ttl
+// and modification tag are ignored.
+type tcpMappingKey struct {
+	RouterGroupGuid string
+	ExternalPort    uint16
+	HostIP          string
+	HostPort        uint16
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	message *Message
 }
 
 type cache struct {
-	messages map[uint64]*Message
-	mux      *sync.Mutex
-	logger   lager.Logger
+	// messages indexes the elements of order, which holds the cache entries
+	// oldest first
+	messages     map[cacheKey]*list.Element
+	order        *list.List
+	mux          *sync.Mutex
+	logger       lager.Logger
+	clock        clock.Clock
+	metronClient loggingclient.IngressClient
+	maxSize      int
+	ttl          time.Duration
 }
 
+// NewCache returns an unbounded cache whose entries never expire.
 func NewCache(logger lager.Logger) Cache {
+	return NewBoundedCache(logger, clock.NewClock(), nil, 0, 0)
+}
+
+// NewBoundedCache returns a cache that holds at most maxSize unregistrations
+// and drops the ones older than ttl. The oldest unregistrations are evicted
+// first when the cache is full. A zero maxSize or ttl disables the bound.
+func NewBoundedCache(
+	logger lager.Logger,
+	clock clock.Clock,
+	metronClient loggingclient.IngressClient,
+	maxSize int,
+	ttl time.Duration,
+) Cache {
 	cacheLogger := logger.Session("unregistration-cache")
 	return &cache{
-		messages: map[uint64]*Message{},
-		mux:      &sync.Mutex{},
-		logger:   cacheLogger,
+		messages:     map[cacheKey]*list.Element{},
+		order:        list.New(),
+		mux:          &sync.Mutex{},
+		logger:       cacheLogger,
+		clock:        clock,
+		metronClient: metronClient,
+		maxSize:      maxSize,
+		ttl:          ttl,
 	}
 }
 
+// Add caches http unregistrations.
 func (c *cache) Add(registryMessages []routingtable.RegistryMessage) error {
+	return c.addRegistryMessages(HTTPRoute, registryMessages)
+}
+
+// Remove drops the cached http unregistrations of the given registrations.
+func (c *cache) Remove(registryMessages []routingtable.RegistryMessage) error {
+	return c.removeRegistryMessages(HTTPRoute, registryMessages)
+}
+
+// AddInternal caches service discovery unregistrations.
+func (c *cache) AddInternal(registryMessages []routingtable.RegistryMessage) error {
+	return c.addRegistryMessages(InternalRoute, registryMessages)
+}
+
+// RemoveInternal drops the cached service discovery unregistrations of the
+// given registrations.
+func (c *cache) RemoveInternal(registryMessages []routingtable.RegistryMessage) error {
+	return c.removeRegistryMessages(InternalRoute, registryMessages)
+}
+
+// AddTCP caches tcp route mapping deletions.
+func (c *cache) AddTCP(mappings []models.TcpRouteMapping) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.logger.Debug("add", lager.Data{"route-type": TCPRoute, "cache": mappings})
+	now := c.clock.Now()
+	for _, mapping := range mappings {
+		key, err := tcpCacheKey(mapping)
+		if err != nil {
+			return err
+		}
+		c.add(key, &Message{
+			RouteType:       TCPRoute,
+			TCPRouteMapping: mapping,
+			AddedAt:         now,
+		})
+	}
+	return nil
+}
+
+// RemoveTCP drops the cached deletions of the given tcp route mappings.
+func (c *cache) RemoveTCP(mappings []models.TcpRouteMapping) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.logger.Debug("remove", lager.Data{"route-type": TCPRoute, "cache": mappings})
+	for _, mapping := range mappings {
+		key, err := tcpCacheKey(mapping)
+		if err != nil {
+			return err
+		}
+		c.remove(key)
+	}
+	return nil
+}
+
+func (c *cache) addRegistryMessages(routeType RouteType, registryMessages []routingtable.RegistryMessage) error {
 	c.mux.Lock()
 	defer c.mux.Unlock()
-	c.logger.Debug("add", lager.Data{"cache": registryMessages})
+	c.logger.Debug("add", lager.Data{"route-type": routeType, "cache": registryMessages})
+	now := c.clock.Now()
 	for _, registryMessage := range registryMessages {
 		registryMessageHash, err := hashstructure.Hash(registryMessage, nil)
 		if err != nil {
 			return err
 		}
-		c.messages[registryMessageHash] = &Message{
+		c.add(cacheKey{routeType: routeType, hash: registryMessageHash}, &Message{
+			RouteType:       routeType,
 			RegistryMessage: registryMessage,
-		}
+			AddedAt:         now,
+		})
 	}
 	return nil
 }
 
-func (c *cache) Remove(registryMessages []routingtable.RegistryMessage) error {
+func (c *cache) removeRegistryMessages(routeType RouteType, registryMessages []routingtable.RegistryMessage) error {
 	c.mux.Lock()
 	defer c.mux.Unlock()
-	c.logger.Debug("remove", lager.Data{"cache": registryMessages})
+	c.logger.Debug("remove", lager.Data{"route-type": routeType, "cache": registryMessages})
 	for _, registryMessage := range registryMessages {
 		registryMessageHash, err := hashstructure.Hash(registryMessage, nil)
 		if err != nil {
 			return err
 		}
-		delete(c.messages, registryMessageHash)
+		c.remove(cacheKey{routeType: routeType, hash: registryMessageHash})
 	}
 	return nil
 }
 
+// add replaces the entry for key and evicts the oldest entries while the
+// cache is over its maximum size. It must be called with the lock held.
+func (c *cache) add(key cacheKey, message *Message) {
+	c.remove(key)
+	c.messages[key] = c.order.PushBack(cacheEntry{key: key, message: message})
+
+	evicted := 0
+	for c.maxSize > 0 && c.order.Len() > c.maxSize {
+		c.remove(c.order.Front().Value.(cacheEntry).key)
+		evicted++
+	}
+	if evicted > 0 {
+		c.logger.Info("evicted-unregistrations", lager.Data{"count": evicted, "max-size": c.maxSize})
+		c.incrementCounter(unregistrationCacheEvictedCounter, evicted)
+	}
+}
+
+func (c *cache) remove(key cacheKey) {
+	element, ok := c.messages[key]
+	if !ok {
+		return
+	}
+	c.order.Remove(element)
+	delete(c.messages, key)
+}
+
+// expire drops the entries that are older than the ttl. It must be called
+// with the lock held.
+func (c *cache) expire() {
+	if c.ttl <= 0 {
+		return
+	}
+
+	cutoff := c.clock.Now().Add(-c.ttl)
+	expired := 0
+	for element := c.order.Front(); element != nil; element = c.order.Front() {
+		entry := element.Value.(cacheEntry)
+		if entry.message.AddedAt.After(cutoff) {
+			break
+		}
+		c.remove(entry.key)
+		expired++
+	}
+	if expired > 0 {
+		c.logger.Info("expired-unregistrations", lager.Data{"count": expired, "ttl": c.ttl.String()})
+		c.incrementCounter(unregistrationCacheExpiredCounter, expired)
+	}
+}
+
+func keyFor(message Message) (cacheKey, error) {
+	if message.RouteType == TCPRoute {
+		return tcpCacheKey(message.TCPRouteMapping)
+	}
+	hash, err := hashstructure.Hash(message.RegistryMessage, nil)
+	return cacheKey{routeType: message.RouteType, hash: hash}, err
+}
+
+func tcpCacheKey(mapping models.TcpRouteMapping) (cacheKey, error) {
+	hash, err := hashstructure.Hash(tcpMappingKey{
+		RouterGroupGuid: mapping.RouterGroupGuid,
+		ExternalPort:    mapping.ExternalPort,
+		HostIP:          mapping.HostIP,
+		HostPort:        mapping.HostPort,
+	}, nil)
+	return cacheKey{routeType: TCPRoute, hash: hash}, err
+}
+
+// List drops the expired entries and returns the remaining ones, oldest
+// first. Use MarkSent to update the sent count of a returned message.
 func (c *cache) List() []*Message {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	list := []*Message{}
-	for _, message := range c.messages {
-		list = append(list, message)
+	c.expire()
+
+	messages := make([]*Message, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		messages = append(messages, element.Value.(cacheEntry).message)
+	}
+	c.sendSize(len(messages))
+	return messages
+}
+
+// MarkSent increments the sent count of a message returned by List and
+// returns the new count.
+func (c *cache) MarkSent(message *Message) int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	message.SentCount++
+	return message.SentCount
+}
+
+// Snapshot returns a copy of the cached unregistrations, oldest first.
+func (c *cache) Snapshot() []Message {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	snapshot := make([]Message, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		snapshot = append(snapshot, *element.Value.(cacheEntry).message)
+	}
+	return snapshot
+}
+
+// Restore adds previously snapshotted unregistrations to the cache, keeping
+// their age and sent count.
+func (c *cache) Restore(messages []Message) error {
+	restored := append([]Message{}, messages...)
+	sort.SliceStable(restored, func(i, j int) bool {
+		return restored[i].AddedAt.Before(restored[j].AddedAt)
+	})
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for i := range restored {
+		key, err := keyFor(restored[i])
+		if err != nil {
+			return err
+		}
+		c.add(key, &restored[i])
+	}
+	c.expire()
+	return nil
+}
+
+func (c *cache) sendSize(size int) {
+	if c.metronClient == nil {
+		return
+	}
+	err := c.metronClient.SendMetric(unregistrationCacheSizeMetric, size)
+	if err != nil {
+		c.logger.Error("failed-to-send-cache-size-metric", err)
+	}
+}
+
+func (c *cache) incrementCounter(name string, delta int) {
+	if c.metronClient == nil {
+		return
+	}
+	err := c.metronClient.IncrementCounterWithDelta(name, uint64(delta))
+	if err != nil {
+		c.logger.Error("failed-to-increment-counter", err, lager.Data{"counter": name})
 	}
-	return list
 }
@@ -2,11 +2,15 @@ package unregistration_test
 
 import (
 	"sync"
+	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/lager/v3/lagertest"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/unregistration"
+	"code.cloudfoundry.org/routing-api/models"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -131,6 +135,137 @@ var _ = Describe("Cache", func() {
 		})
 	})
 
+	Describe("AddInternal and RemoveInternal", func() {
+		It("keeps internal messages apart from http ones", func() {
+			err := cache.Add([]routingtable.RegistryMessage{registryMessage1})
+			Expect(err).NotTo(HaveOccurred())
+			err = cache.AddInternal([]routingtable.RegistryMessage{registryMessage1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cache.List()).To(HaveLen(2))
+
+			err = cache.RemoveInternal([]routingtable.RegistryMessage{registryMessage1})
+			Expect(err).NotTo(HaveOccurred())
+			cachedMessages := cache.List()
+			Expect(cachedMessages).To(HaveLen(1))
+			Expect(cachedMessages[0].RouteType).To(Equal(unregistration.HTTPRoute))
+		})
+	})
+
+	Describe("AddTCP and RemoveTCP", func() {
+		var mapping models.TcpRouteMapping
+
+		BeforeEach(func() {
+			mapping = models.NewTcpRouteMapping("router-group-guid", 61000, "1.1.1.1", 62000, 120)
+		})
+
+		It("adds and removes a tcp route mapping", func() {
+			err := cache.AddTCP([]models.TcpRouteMapping{mapping})
+			Expect(err).NotTo(HaveOccurred())
+			cachedMessages := cache.List()
+			Expect(cachedMessages).To(HaveLen(1))
+			Expect(cachedMessages[0].RouteType).To(Equal(unregistration.TCPRoute))
+			Expect(cachedMessages[0].TCPRouteMapping).To(Equal(mapping))
+
+			err = cache.RemoveTCP([]models.TcpRouteMapping{mapping})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cache.List()).To(HaveLen(0))
+		})
+
+		It("uses only the relevant router group/port fields in the cache key", func() {
+			err := cache.AddTCP([]models.TcpRouteMapping{mapping})
+			Expect(err).NotTo(HaveOccurred())
+
+			other := models.NewTcpRouteMapping("router-group-guid", 61000, "1.1.1.1", 62000, 60)
+			err = cache.RemoveTCP([]models.TcpRouteMapping{other})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cache.List()).To(HaveLen(0))
+		})
+	})
+
+	Describe("MarkSent and Snapshot", func() {
+		It("returns copies of the cached messages with their sent count", func() {
+			err := cache.Add([]routingtable.RegistryMessage{registryMessage1})
+			Expect(err).NotTo(HaveOccurred())
+			message := cache.List()[0]
+			Expect(cache.MarkSent(message)).To(Equal(1))
+			Expect(cache.MarkSent(message)).To(Equal(2))
+
+			snapshot := cache.Snapshot()
+			Expect(snapshot).To(HaveLen(1))
+			Expect(snapshot[0].RegistryMessage).To(Equal(registryMessage1))
+			Expect(snapshot[0].SentCount).To(Equal(2))
+		})
+	})
+
+	Describe("bounded cache", func() {
+		var (
+			clock            *fakeclock.FakeClock
+			fakeMetronClient *mfakes.FakeIngressClient
+		)
+
+		BeforeEach(func() {
+			clock = fakeclock.NewFakeClock(time.Now())
+			fakeMetronClient = &mfakes.FakeIngressClient{}
+			cache = unregistration.NewBoundedCache(logger, clock, fakeMetronClient, 2, time.Minute)
+		})
+
+		It("evicts the oldest messages when the cache is full", func() {
+			registryMessage3 := registryMessage1
+			registryMessage3.Port = 61003
+
+			Expect(cache.Add([]routingtable.RegistryMessage{registryMessage1})).To(Succeed())
+			clock.Increment(time.Second)
+			Expect(cache.Add([]routingtable.RegistryMessage{registryMessage2, registryMessage3})).To(Succeed())
+
+			cachedMessages := cache.List()
+			Expect(cachedMessages).To(HaveLen(2))
+			Expect(cachedMessages[0].RegistryMessage).To(Equal(registryMessage2))
+			Expect(cachedMessages[1].RegistryMessage).To(Equal(registryMessage3))
+
+			Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(1))
+			name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+			Expect(name).To(Equal("UnregistrationCacheEvicted"))
+			Expect(delta).To(BeEquivalentTo(1))
+		})
+
+		It("drops the messages older than the ttl", func() {
+			Expect(cache.Add([]routingtable.RegistryMessage{registryMessage1})).To(Succeed())
+			clock.Increment(30 * time.Second)
+			Expect(cache.Add([]routingtable.RegistryMessage{registryMessage2})).To(Succeed())
+			clock.Increment(30 * time.Second)
+
+			cachedMessages := cache.List()
+			Expect(cachedMessages).To(HaveLen(1))
+			Expect(cachedMessages[0].RegistryMessage).To(Equal(registryMessage2))
+
+			name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+			Expect(name).To(Equal("UnregistrationCacheExpired"))
+			Expect(delta).To(BeEquivalentTo(1))
+		})
+
+		It("reports the cache size", func() {
+			Expect(cache.Add([]routingtable.RegistryMessage{registryMessage1, registryMessage2})).To(Succeed())
+			cache.List()
+
+			Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(1))
+			name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+			Expect(name).To(Equal("UnregistrationCacheSize"))
+			Expect(value).To(Equal(2))
+		})
+
+		Describe("Restore", func() {
+			It("restores the messages that have not expired", func() {
+				messages := []unregistration.Message{
+					{RouteType: unregistration.HTTPRoute, RegistryMessage: registryMessage1, SentCount: 1, AddedAt: clock.Now().Add(-2 * time.Minute)},
+					{RouteType: unregistration.InternalRoute, RegistryMessage: registryMessage2, SentCount: 2, AddedAt: clock.Now().Add(-time.Second)},
+				}
+				Expect(cache.Restore(messages)).To(Succeed())
+
+				Expect(cache.Snapshot()).To(Equal(messages[1:]))
+			})
+		})
+	})
+
 	Describe("concurrent cache access", func() {
 		It("does not cause a data race", func() {
 			registryMessages := []routingtable.RegistryMessage{registryMessage1}
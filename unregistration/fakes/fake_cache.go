@@ -6,6 +6,7 @@ import (
 
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/unregistration"
+	"code.cloudfoundry.org/routing-api/models"
 )
 
 type FakeCache struct {
@@ -20,6 +21,28 @@ type FakeCache struct {
 	addReturnsOnCall map[int]struct {
 		result1 error
 	}
+	AddInternalStub        func([]routingtable.RegistryMessage) error
+	addInternalMutex       sync.RWMutex
+	addInternalArgsForCall []struct {
+		arg1 []routingtable.RegistryMessage
+	}
+	addInternalReturns struct {
+		result1 error
+	}
+	addInternalReturnsOnCall map[int]struct {
+		result1 error
+	}
+	AddTCPStub        func([]models.TcpRouteMapping) error
+	addTCPMutex       sync.RWMutex
+	addTCPArgsForCall []struct {
+		arg1 []models.TcpRouteMapping
+	}
+	addTCPReturns struct {
+		result1 error
+	}
+	addTCPReturnsOnCall map[int]struct {
+		result1 error
+	}
 	ListStub        func() []*unregistration.Message
 	listMutex       sync.RWMutex
 	listArgsForCall []struct {
@@ -30,6 +53,17 @@ type FakeCache struct {
 	listReturnsOnCall map[int]struct {
 		result1 []*unregistration.Message
 	}
+	MarkSentStub        func(*unregistration.Message) int
+	markSentMutex       sync.RWMutex
+	markSentArgsForCall []struct {
+		arg1 *unregistration.Message
+	}
+	markSentReturns struct {
+		result1 int
+	}
+	markSentReturnsOnCall map[int]struct {
+		result1 int
+	}
 	RemoveStub        func([]routingtable.RegistryMessage) error
 	removeMutex       sync.RWMutex
 	removeArgsForCall []struct {
@@ -41,6 +75,49 @@ type FakeCache struct {
 	removeReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RemoveInternalStub        func([]routingtable.RegistryMessage) error
+	removeInternalMutex       sync.RWMutex
+	removeInternalArgsForCall []struct {
+		arg1 []routingtable.RegistryMessage
+	}
+	removeInternalReturns struct {
+		result1 error
+	}
+	removeInternalReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RemoveTCPStub        func([]models.TcpRouteMapping) error
+	removeTCPMutex       sync.RWMutex
+	removeTCPArgsForCall []struct {
+		arg1 []models.TcpRouteMapping
+	}
+	removeTCPReturns struct {
+		result1 error
+	}
+	removeTCPReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RestoreStub        func([]unregistration.Message) error
+	restoreMutex       sync.RWMutex
+	restoreArgsForCall []struct {
+		arg1 []unregistration.Message
+	}
+	restoreReturns struct {
+		result1 error
+	}
+	restoreReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SnapshotStub        func() []unregistration.Message
+	snapshotMutex       sync.RWMutex
+	snapshotArgsForCall []struct {
+	}
+	snapshotReturns struct {
+		result1 []unregistration.Message
+	}
+	snapshotReturnsOnCall map[int]struct {
+		result1 []unregistration.Message
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -110,6 +187,136 @@ func (fake *FakeCache) AddReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeCache) AddInternal(arg1 []routingtable.RegistryMessage) error {
+	var arg1Copy []routingtable.RegistryMessage
+	if arg1 != nil {
+		arg1Copy = make([]routingtable.RegistryMessage, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.addInternalMutex.Lock()
+	ret, specificReturn := fake.addInternalReturnsOnCall[len(fake.addInternalArgsForCall)]
+	fake.addInternalArgsForCall = append(fake.addInternalArgsForCall, struct {
+		arg1 []routingtable.RegistryMessage
+	}{arg1Copy})
+	fake.recordInvocation("AddInternal", []interface{}{arg1Copy})
+	fake.addInternalMutex.Unlock()
+	if fake.AddInternalStub != nil {
+		return fake.AddInternalStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.addInternalReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) AddInternalCallCount() int {
+	fake.addInternalMutex.RLock()
+	defer fake.addInternalMutex.RUnlock()
+	return len(fake.addInternalArgsForCall)
+}
+
+func (fake *FakeCache) AddInternalCalls(stub func([]routingtable.RegistryMessage) error) {
+	fake.addInternalMutex.Lock()
+	defer fake.addInternalMutex.Unlock()
+	fake.AddInternalStub = stub
+}
+
+func (fake *FakeCache) AddInternalArgsForCall(i int) []routingtable.RegistryMessage {
+	fake.addInternalMutex.RLock()
+	defer fake.addInternalMutex.RUnlock()
+	argsForCall := fake.addInternalArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCache) AddInternalReturns(result1 error) {
+	fake.addInternalMutex.Lock()
+	defer fake.addInternalMutex.Unlock()
+	fake.AddInternalStub = nil
+	fake.addInternalReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) AddInternalReturnsOnCall(i int, result1 error) {
+	fake.addInternalMutex.Lock()
+	defer fake.addInternalMutex.Unlock()
+	fake.AddInternalStub = nil
+	if fake.addInternalReturnsOnCall == nil {
+		fake.addInternalReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.addInternalReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) AddTCP(arg1 []models.TcpRouteMapping) error {
+	var arg1Copy []models.TcpRouteMapping
+	if arg1 != nil {
+		arg1Copy = make([]models.TcpRouteMapping, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.addTCPMutex.Lock()
+	ret, specificReturn := fake.addTCPReturnsOnCall[len(fake.addTCPArgsForCall)]
+	fake.addTCPArgsForCall = append(fake.addTCPArgsForCall, struct {
+		arg1 []models.TcpRouteMapping
+	}{arg1Copy})
+	fake.recordInvocation("AddTCP", []interface{}{arg1Copy})
+	fake.addTCPMutex.Unlock()
+	if fake.AddTCPStub != nil {
+		return fake.AddTCPStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.addTCPReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) AddTCPCallCount() int {
+	fake.addTCPMutex.RLock()
+	defer fake.addTCPMutex.RUnlock()
+	return len(fake.addTCPArgsForCall)
+}
+
+func (fake *FakeCache) AddTCPCalls(stub func([]models.TcpRouteMapping) error) {
+	fake.addTCPMutex.Lock()
+	defer fake.addTCPMutex.Unlock()
+	fake.AddTCPStub = stub
+}
+
+func (fake *FakeCache) AddTCPArgsForCall(i int) []models.TcpRouteMapping {
+	fake.addTCPMutex.RLock()
+	defer fake.addTCPMutex.RUnlock()
+	argsForCall := fake.addTCPArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCache) AddTCPReturns(result1 error) {
+	fake.addTCPMutex.Lock()
+	defer fake.addTCPMutex.Unlock()
+	fake.AddTCPStub = nil
+	fake.addTCPReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) AddTCPReturnsOnCall(i int, result1 error) {
+	fake.addTCPMutex.Lock()
+	defer fake.addTCPMutex.Unlock()
+	fake.AddTCPStub = nil
+	if fake.addTCPReturnsOnCall == nil {
+		fake.addTCPReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.addTCPReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeCache) List() []*unregistration.Message {
 	fake.listMutex.Lock()
 	ret, specificReturn := fake.listReturnsOnCall[len(fake.listArgsForCall)]
@@ -162,6 +369,66 @@ func (fake *FakeCache) ListReturnsOnCall(i int, result1 []*unregistration.Messag
 	}{result1}
 }
 
+func (fake *FakeCache) MarkSent(arg1 *unregistration.Message) int {
+	fake.markSentMutex.Lock()
+	ret, specificReturn := fake.markSentReturnsOnCall[len(fake.markSentArgsForCall)]
+	fake.markSentArgsForCall = append(fake.markSentArgsForCall, struct {
+		arg1 *unregistration.Message
+	}{arg1})
+	fake.recordInvocation("MarkSent", []interface{}{arg1})
+	fake.markSentMutex.Unlock()
+	if fake.MarkSentStub != nil {
+		return fake.MarkSentStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.markSentReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) MarkSentCallCount() int {
+	fake.markSentMutex.RLock()
+	defer fake.markSentMutex.RUnlock()
+	return len(fake.markSentArgsForCall)
+}
+
+func (fake *FakeCache) MarkSentCalls(stub func(*unregistration.Message) int) {
+	fake.markSentMutex.Lock()
+	defer fake.markSentMutex.Unlock()
+	fake.MarkSentStub = stub
+}
+
+func (fake *FakeCache) MarkSentArgsForCall(i int) *unregistration.Message {
+	fake.markSentMutex.RLock()
+	defer fake.markSentMutex.RUnlock()
+	argsForCall := fake.markSentArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCache) MarkSentReturns(result1 int) {
+	fake.markSentMutex.Lock()
+	defer fake.markSentMutex.Unlock()
+	fake.MarkSentStub = nil
+	fake.markSentReturns = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeCache) MarkSentReturnsOnCall(i int, result1 int) {
+	fake.markSentMutex.Lock()
+	defer fake.markSentMutex.Unlock()
+	fake.MarkSentStub = nil
+	if fake.markSentReturnsOnCall == nil {
+		fake.markSentReturnsOnCall = make(map[int]struct {
+			result1 int
+		})
+	}
+	fake.markSentReturnsOnCall[i] = struct {
+		result1 int
+	}{result1}
+}
+
 func (fake *FakeCache) Remove(arg1 []routingtable.RegistryMessage) error {
 	var arg1Copy []routingtable.RegistryMessage
 	if arg1 != nil {
@@ -227,15 +494,276 @@ func (fake *FakeCache) RemoveReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeCache) RemoveInternal(arg1 []routingtable.RegistryMessage) error {
+	var arg1Copy []routingtable.RegistryMessage
+	if arg1 != nil {
+		arg1Copy = make([]routingtable.RegistryMessage, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.removeInternalMutex.Lock()
+	ret, specificReturn := fake.removeInternalReturnsOnCall[len(fake.removeInternalArgsForCall)]
+	fake.removeInternalArgsForCall = append(fake.removeInternalArgsForCall, struct {
+		arg1 []routingtable.RegistryMessage
+	}{arg1Copy})
+	fake.recordInvocation("RemoveInternal", []interface{}{arg1Copy})
+	fake.removeInternalMutex.Unlock()
+	if fake.RemoveInternalStub != nil {
+		return fake.RemoveInternalStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.removeInternalReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) RemoveInternalCallCount() int {
+	fake.removeInternalMutex.RLock()
+	defer fake.removeInternalMutex.RUnlock()
+	return len(fake.removeInternalArgsForCall)
+}
+
+func (fake *FakeCache) RemoveInternalCalls(stub func([]routingtable.RegistryMessage) error) {
+	fake.removeInternalMutex.Lock()
+	defer fake.removeInternalMutex.Unlock()
+	fake.RemoveInternalStub = stub
+}
+
+func (fake *FakeCache) RemoveInternalArgsForCall(i int) []routingtable.RegistryMessage {
+	fake.removeInternalMutex.RLock()
+	defer fake.removeInternalMutex.RUnlock()
+	argsForCall := fake.removeInternalArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCache) RemoveInternalReturns(result1 error) {
+	fake.removeInternalMutex.Lock()
+	defer fake.removeInternalMutex.Unlock()
+	fake.RemoveInternalStub = nil
+	fake.removeInternalReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) RemoveInternalReturnsOnCall(i int, result1 error) {
+	fake.removeInternalMutex.Lock()
+	defer fake.removeInternalMutex.Unlock()
+	fake.RemoveInternalStub = nil
+	if fake.removeInternalReturnsOnCall == nil {
+		fake.removeInternalReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.removeInternalReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) RemoveTCP(arg1 []models.TcpRouteMapping) error {
+	var arg1Copy []models.TcpRouteMapping
+	if arg1 != nil {
+		arg1Copy = make([]models.TcpRouteMapping, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.removeTCPMutex.Lock()
+	ret, specificReturn := fake.removeTCPReturnsOnCall[len(fake.removeTCPArgsForCall)]
+	fake.removeTCPArgsForCall = append(fake.removeTCPArgsForCall, struct {
+		arg1 []models.TcpRouteMapping
+	}{arg1Copy})
+	fake.recordInvocation("RemoveTCP", []interface{}{arg1Copy})
+	fake.removeTCPMutex.Unlock()
+	if fake.RemoveTCPStub != nil {
+		return fake.RemoveTCPStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.removeTCPReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) RemoveTCPCallCount() int {
+	fake.removeTCPMutex.RLock()
+	defer fake.removeTCPMutex.RUnlock()
+	return len(fake.removeTCPArgsForCall)
+}
+
+func (fake *FakeCache) RemoveTCPCalls(stub func([]models.TcpRouteMapping) error) {
+	fake.removeTCPMutex.Lock()
+	defer fake.removeTCPMutex.Unlock()
+	fake.RemoveTCPStub = stub
+}
+
+func (fake *FakeCache) RemoveTCPArgsForCall(i int) []models.TcpRouteMapping {
+	fake.removeTCPMutex.RLock()
+	defer fake.removeTCPMutex.RUnlock()
+	argsForCall := fake.removeTCPArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCache) RemoveTCPReturns(result1 error) {
+	fake.removeTCPMutex.Lock()
+	defer fake.removeTCPMutex.Unlock()
+	fake.RemoveTCPStub = nil
+	fake.removeTCPReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) RemoveTCPReturnsOnCall(i int, result1 error) {
+	fake.removeTCPMutex.Lock()
+	defer fake.removeTCPMutex.Unlock()
+	fake.RemoveTCPStub = nil
+	if fake.removeTCPReturnsOnCall == nil {
+		fake.removeTCPReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.removeTCPReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) Restore(arg1 []unregistration.Message) error {
+	var arg1Copy []unregistration.Message
+	if arg1 != nil {
+		arg1Copy = make([]unregistration.Message, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.restoreMutex.Lock()
+	ret, specificReturn := fake.restoreReturnsOnCall[len(fake.restoreArgsForCall)]
+	fake.restoreArgsForCall = append(fake.restoreArgsForCall, struct {
+		arg1 []unregistration.Message
+	}{arg1Copy})
+	fake.recordInvocation("Restore", []interface{}{arg1Copy})
+	fake.restoreMutex.Unlock()
+	if fake.RestoreStub != nil {
+		return fake.RestoreStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.restoreReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) RestoreCallCount() int {
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	return len(fake.restoreArgsForCall)
+}
+
+func (fake *FakeCache) RestoreCalls(stub func([]unregistration.Message) error) {
+	fake.restoreMutex.Lock()
+	defer fake.restoreMutex.Unlock()
+	fake.RestoreStub = stub
+}
+
+func (fake *FakeCache) RestoreArgsForCall(i int) []unregistration.Message {
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	argsForCall := fake.restoreArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCache) RestoreReturns(result1 error) {
+	fake.restoreMutex.Lock()
+	defer fake.restoreMutex.Unlock()
+	fake.RestoreStub = nil
+	fake.restoreReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) RestoreReturnsOnCall(i int, result1 error) {
+	fake.restoreMutex.Lock()
+	defer fake.restoreMutex.Unlock()
+	fake.RestoreStub = nil
+	if fake.restoreReturnsOnCall == nil {
+		fake.restoreReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.restoreReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) Snapshot() []unregistration.Message {
+	fake.snapshotMutex.Lock()
+	ret, specificReturn := fake.snapshotReturnsOnCall[len(fake.snapshotArgsForCall)]
+	fake.snapshotArgsForCall = append(fake.snapshotArgsForCall, struct {
+	}{})
+	fake.recordInvocation("Snapshot", []interface{}{})
+	fake.snapshotMutex.Unlock()
+	if fake.SnapshotStub != nil {
+		return fake.SnapshotStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.snapshotReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) SnapshotCallCount() int {
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	return len(fake.snapshotArgsForCall)
+}
+
+func (fake *FakeCache) SnapshotCalls(stub func() []unregistration.Message) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = stub
+}
+
+func (fake *FakeCache) SnapshotReturns(result1 []unregistration.Message) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = nil
+	fake.snapshotReturns = struct {
+		result1 []unregistration.Message
+	}{result1}
+}
+
+func (fake *FakeCache) SnapshotReturnsOnCall(i int, result1 []unregistration.Message) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = nil
+	if fake.snapshotReturnsOnCall == nil {
+		fake.snapshotReturnsOnCall = make(map[int]struct {
+			result1 []unregistration.Message
+		})
+	}
+	fake.snapshotReturnsOnCall[i] = struct {
+		result1 []unregistration.Message
+	}{result1}
+}
+
 func (fake *FakeCache) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.addMutex.RLock()
 	defer fake.addMutex.RUnlock()
+	fake.addInternalMutex.RLock()
+	defer fake.addInternalMutex.RUnlock()
+	fake.addTCPMutex.RLock()
+	defer fake.addTCPMutex.RUnlock()
 	fake.listMutex.RLock()
 	defer fake.listMutex.RUnlock()
+	fake.markSentMutex.RLock()
+	defer fake.markSentMutex.RUnlock()
 	fake.removeMutex.RLock()
 	defer fake.removeMutex.RUnlock()
+	fake.removeInternalMutex.RLock()
+	defer fake.removeInternalMutex.RUnlock()
+	fake.removeTCPMutex.RLock()
+	defer fake.removeTCPMutex.RUnlock()
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
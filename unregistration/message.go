@@ -1,8 +1,26 @@
 package unregistration
 
-import "code.cloudfoundry.org/route-emitter/routingtable"
+import (
+	"time"
 
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-api/models"
+)
+
+type RouteType string
+
+const (
+	HTTPRoute     RouteType = "http"
+	InternalRoute RouteType = "internal"
+	TCPRoute      RouteType = "tcp"
+)
+
+// Message is a cached unregistration. Http and internal unregistrations are
+// kept as registry messages, tcp unregistrations as route mappings.
 type Message struct {
-	RegistryMessage routingtable.RegistryMessage
-	SentCount       int
+	RouteType       RouteType                    `json:"route_type"`
+	RegistryMessage routingtable.RegistryMessage `json:"registry_message"`
+	TCPRouteMapping models.TcpRouteMapping       `json:"tcp_route_mapping"`
+	SentCount       int                          `json:"sent_count"`
+	AddedAt         time.Time                    `json:"added_at"`
 }
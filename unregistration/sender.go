@@ -2,48 +2,85 @@ package unregistration
 
 import (
 	"os"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/route-emitter/emitter"
 	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-api/models"
 )
 
 type Sender struct {
-	logger      lager.Logger
-	clock       clock.Clock
-	cache       Cache
-	natsEmitter emitter.NATSEmitter
-	interval    time.Duration
-	sendCount   int
+	logger            lager.Logger
+	clock             clock.Clock
+	cache             Cache
+	natsEmitter       emitter.NATSEmitter
+	routingAPIEmitter emitter.RoutingAPIEmitter
+
+	lock            sync.Mutex
+	interval        time.Duration
+	sendCount       int
+	intervalChanged chan struct{}
 }
 
+// NewSender returns a runner that re-sends each cached unregistration
+// sendCount times. Http and internal unregistrations are sent through the
+// nats emitter, tcp ones through the routing api emitter.
 func NewSender(
 	logger lager.Logger,
 	clock clock.Clock,
 	cache Cache,
 	natsEmitter emitter.NATSEmitter,
+	routingAPIEmitter emitter.RoutingAPIEmitter,
 	interval time.Duration,
 	sendCount int,
-) Sender {
-	return Sender{
-		logger:      logger.Session("unregistration-sender"),
-		clock:       clock,
-		cache:       cache,
-		natsEmitter: natsEmitter,
-		interval:    interval,
-		sendCount:   sendCount,
+) *Sender {
+	return &Sender{
+		logger:            logger.Session("unregistration-sender"),
+		clock:             clock,
+		cache:             cache,
+		natsEmitter:       natsEmitter,
+		routingAPIEmitter: routingAPIEmitter,
+		interval:          interval,
+		sendCount:         sendCount,
+		intervalChanged:   make(chan struct{}, 1),
+	}
+}
+
+// Reconfigure changes the interval and the send count at runtime. Cached
+// unregistrations that were sent sendCount times already are forgotten on
+// the next tick.
+func (s *Sender) Reconfigure(interval time.Duration, sendCount int) {
+	s.lock.Lock()
+	changed := s.interval != interval
+	s.interval = interval
+	s.sendCount = sendCount
+	s.lock.Unlock()
+
+	if changed {
+		select {
+		case s.intervalChanged <- struct{}{}:
+		default:
+		}
 	}
 }
 
-func (s Sender) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+func (s *Sender) settings() (time.Duration, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.interval, s.sendCount
+}
+
+func (s *Sender) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	s.logger.Info("starting")
 	close(ready)
 	defer s.logger.Info("exiting")
 
-	sendTicker := s.clock.NewTicker(s.interval)
-	defer sendTicker.Stop()
+	interval, _ := s.settings()
+	sendTicker := s.clock.NewTicker(interval)
+	defer func() { sendTicker.Stop() }()
 
 	for {
 		select {
@@ -51,20 +88,65 @@ func (s Sender) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 			s.logger.Info("stopping")
 			return nil
 
+		case <-s.intervalChanged:
+			sendTicker.Stop()
+			interval, _ = s.settings()
+			sendTicker = s.clock.NewTicker(interval)
+			s.logger.Info("changed-interval", lager.Data{"interval": interval.String()})
+
 		case <-sendTicker.C():
+			_, sendCount := s.settings()
 			messages := s.cache.List()
 			if len(messages) > 0 {
 				s.logger.Debug("messages", lager.Data{"cache": messages})
 			}
 			for _, message := range messages {
-				s.natsEmitter.Emit(routingtable.MessagesToEmit{
-					UnregistrationMessages: []routingtable.RegistryMessage{message.RegistryMessage},
-				})
-				message.SentCount++
-				if message.SentCount == s.sendCount {
-					s.cache.Remove([]routingtable.RegistryMessage{message.RegistryMessage})
+				if !s.send(message) {
+					s.forget(message)
+					continue
+				}
+				if s.cache.MarkSent(message) >= sendCount {
+					s.forget(message)
 				}
 			}
 		}
 	}
 }
+
+// send returns false when there is no emitter for the route type of the
+// message.
+func (s *Sender) send(message *Message) bool {
+	var err error
+	switch message.RouteType {
+	case InternalRoute:
+		err = s.natsEmitter.Emit(routingtable.MessagesToEmit{
+			InternalUnregistrationMessages: []routingtable.RegistryMessage{message.RegistryMessage},
+		})
+	case TCPRoute:
+		if s.routingAPIEmitter == nil {
+			return false
+		}
+		err = s.routingAPIEmitter.Emit(routingtable.TCPRouteMappings{
+			Unregistrations: []models.TcpRouteMapping{message.TCPRouteMapping},
+		})
+	default:
+		err = s.natsEmitter.Emit(routingtable.MessagesToEmit{
+			UnregistrationMessages: []routingtable.RegistryMessage{message.RegistryMessage},
+		})
+	}
+	if err != nil {
+		s.logger.Error("failed-to-send-unregistration", err, lager.Data{"route-type": message.RouteType})
+	}
+	return true
+}
+
+func (s *Sender) forget(message *Message) {
+	switch message.RouteType {
+	case InternalRoute:
+		s.cache.RemoveInternal([]routingtable.RegistryMessage{message.RegistryMessage})
+	case TCPRoute:
+		s.cache.RemoveTCP([]models.TcpRouteMapping{message.TCPRouteMapping})
+	default:
+		s.cache.Remove([]routingtable.RegistryMessage{message.RegistryMessage})
+	}
+}
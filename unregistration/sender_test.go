@@ -8,31 +8,42 @@ import (
 
 	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
 	"code.cloudfoundry.org/route-emitter/emitter/fakes"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/unregistration"
+	"code.cloudfoundry.org/routing-api/models"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("Sender", func() {
 	var (
-		sender        ifrit.Runner
+		sender        *unregistration.Sender
 		senderProcess ifrit.Process
 		natsEmitter   *fakes.FakeNATSEmitter
+		tcpEmitter    emitter.RoutingAPIEmitter
+		apiEmitter    *fakes.FakeRoutingAPIEmitter
 		cache         unregistration.Cache
 		clock         *fakeclock.FakeClock
 		sendInterval  time.Duration
+		logger        *lagertest.TestLogger
 	)
 
 	BeforeEach(func() {
-		logger := lagertest.NewTestLogger("sender")
+		logger = lagertest.NewTestLogger("sender")
 		cache = unregistration.NewCache(logger)
 		natsEmitter = &fakes.FakeNATSEmitter{}
+		apiEmitter = &fakes.FakeRoutingAPIEmitter{}
+		tcpEmitter = apiEmitter
 		clock = fakeclock.NewFakeClock(time.Now())
 		sendInterval = 500 * time.Millisecond
-		sender = unregistration.NewSender(logger, clock, cache, natsEmitter, sendInterval, 3)
+	})
+
+	JustBeforeEach(func() {
+		sender = unregistration.NewSender(logger, clock, cache, natsEmitter, tcpEmitter, sendInterval, 3)
 		senderProcess = ifrit.Background(sender)
 	})
 
@@ -89,6 +100,22 @@ var _ = Describe("Sender", func() {
 			Consistently(natsEmitter.EmitCallCount).Should(Equal(6))
 		})
 
+		Context("when it is reconfigured", func() {
+			It("sends at the new interval the new number of times", func() {
+				sender.Reconfigure(2*sendInterval, 1)
+				Eventually(logger).Should(gbytes.Say("changed-interval"))
+
+				clock.WaitForWatcherAndIncrement(sendInterval)
+				Consistently(natsEmitter.EmitCallCount).Should(Equal(0))
+
+				clock.WaitForWatcherAndIncrement(sendInterval)
+				Eventually(natsEmitter.EmitCallCount).Should(Equal(2))
+
+				clock.WaitForWatcherAndIncrement(2 * sendInterval)
+				Consistently(natsEmitter.EmitCallCount).Should(Equal(2))
+			})
+		})
+
 		Context("when one of the messages is removed", func() {
 			It("stops emitting unregistration messages", func() {
 				clock.WaitForWatcherAndIncrement(sendInterval)
@@ -136,4 +163,63 @@ var _ = Describe("Sender", func() {
 			})
 		})
 	})
+
+	Context("when there are internal unregistrations in cache", func() {
+		var message routingtable.RegistryMessage
+
+		BeforeEach(func() {
+			message = routingtable.InternalEndpointRegistryMessageFor(routingtable.Endpoint{
+				InstanceGUID:  "instance-guid-1",
+				Host:          "1.1.1.1",
+				ContainerIP:   "10.0.0.1",
+				Port:          61001,
+				ContainerPort: 11,
+			}, routingtable.InternalRoute{Hostname: "host-1.apps.internal", ContainerIP: "10.0.0.1"}, false)
+			cache.AddInternal([]routingtable.RegistryMessage{message})
+		})
+
+		It("emits them as internal unregistrations", func() {
+			clock.WaitForWatcherAndIncrement(sendInterval)
+			Eventually(natsEmitter.EmitCallCount).Should(Equal(1))
+			Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+				InternalUnregistrationMessages: []routingtable.RegistryMessage{message},
+			}))
+		})
+	})
+
+	Context("when there are tcp unregistrations in cache", func() {
+		var mapping models.TcpRouteMapping
+
+		BeforeEach(func() {
+			mapping = models.NewTcpRouteMapping("router-group-guid", 61000, "1.1.1.1", 62000, 120)
+			cache.AddTCP([]models.TcpRouteMapping{mapping})
+		})
+
+		It("emits them through the routing api emitter the required number of times", func() {
+			clock.WaitForWatcherAndIncrement(sendInterval)
+			Eventually(apiEmitter.EmitCallCount).Should(Equal(1))
+			Expect(apiEmitter.EmitArgsForCall(0)).To(Equal(routingtable.TCPRouteMappings{
+				Unregistrations: []models.TcpRouteMapping{mapping},
+			}))
+
+			clock.WaitForWatcherAndIncrement(sendInterval)
+			clock.WaitForWatcherAndIncrement(sendInterval)
+			Eventually(apiEmitter.EmitCallCount).Should(Equal(3))
+
+			clock.WaitForWatcherAndIncrement(sendInterval)
+			Consistently(apiEmitter.EmitCallCount).Should(Equal(3))
+			Expect(natsEmitter.EmitCallCount()).To(Equal(0))
+		})
+
+		Context("when there is no routing api emitter", func() {
+			BeforeEach(func() {
+				tcpEmitter = nil
+			})
+
+			It("drops them from the cache", func() {
+				clock.WaitForWatcherAndIncrement(sendInterval)
+				Eventually(func() int { return len(cache.List()) }).Should(Equal(0))
+			})
+		})
+	})
 })
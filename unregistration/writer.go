@@ -0,0 +1,112 @@
+package unregistration
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/jsonfile"
+)
+
+// Save atomically writes the cached unregistrations to path.
+func Save(path string, messages []Message) error {
+	return jsonfile.Save(path, messages)
+}
+
+func Load(path string) ([]Message, error) {
+	var messages []Message
+	err := jsonfile.Load(path, &messages)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Restore loads the unregistrations saved at path into the cache.
+func Restore(logger lager.Logger, path string, cache Cache) error {
+	logger = logger.Session("restore-unregistrations", lager.Data{"path": path})
+
+	messages, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	err = cache.Restore(messages)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("restored", lager.Data{"count": len(messages)})
+	return nil
+}
+
+// Writer periodically saves the contents of the cache to path, so that
+// pending unregistrations survive a restart. It restores the saved
+// unregistrations into the cache when it starts, run it only while holding
+// the lock so that an instance waiting for it neither sends nor overwrites
+// them.
+type Writer struct {
+	logger   lager.Logger
+	clock    clock.Clock
+	cache    Cache
+	path     string
+	interval time.Duration
+}
+
+func NewWriter(
+	logger lager.Logger,
+	clock clock.Clock,
+	cache Cache,
+	path string,
+	interval time.Duration,
+) *Writer {
+	return &Writer{
+		logger:   logger.Session("unregistration-writer", lager.Data{"path": path}),
+		clock:    clock,
+		cache:    cache,
+		path:     path,
+		interval: interval,
+	}
+}
+
+func (w *Writer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	w.logger.Info("starting")
+	w.restore()
+	close(ready)
+	defer w.logger.Info("exiting")
+
+	ticker := w.clock.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			w.write()
+		case <-signals:
+			w.logger.Info("stopping")
+			w.write()
+			return nil
+		}
+	}
+}
+
+func (w *Writer) restore() {
+	err := Restore(w.logger, w.path, w.cache)
+	if os.IsNotExist(err) {
+		w.logger.Info("no-unregistrations-found")
+		return
+	}
+	if err != nil {
+		w.logger.Error("failed-to-restore-unregistrations", err)
+	}
+}
+
+func (w *Writer) write() {
+	err := Save(w.path, w.cache.Snapshot())
+	if err != nil {
+		w.logger.Error("failed-to-write-unregistrations", err)
+		return
+	}
+	w.logger.Debug("wrote-unregistrations")
+}
@@ -0,0 +1,152 @@
+package unregistration_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/unregistration"
+	"code.cloudfoundry.org/route-emitter/unregistration/fakes"
+	"code.cloudfoundry.org/routing-api/models"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Writer", func() {
+	var (
+		tmpDir    string
+		path      string
+		logger    *lagertest.TestLogger
+		clock     *fakeclock.FakeClock
+		fakeCache *fakes.FakeCache
+		messages  []unregistration.Message
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "unregistrations")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(tmpDir, "unregistrations.json")
+
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		fakeCache = &fakes.FakeCache{}
+
+		messages = []unregistration.Message{
+			{
+				RouteType:       unregistration.HTTPRoute,
+				RegistryMessage: routingtable.RegistryMessage{URIs: []string{"foo.example.com"}, Host: "1.1.1.1", Port: 61000},
+				SentCount:       1,
+				AddedAt:         clock.Now().UTC(),
+			},
+			{
+				RouteType:       unregistration.TCPRoute,
+				TCPRouteMapping: models.NewTcpRouteMapping("router-group-guid", 61000, "1.1.1.1", 62000, 120),
+				AddedAt:         clock.Now().UTC(),
+			},
+		}
+		fakeCache.SnapshotReturns(messages)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Describe("Save and Load", func() {
+		It("round trips the messages", func() {
+			Expect(unregistration.Save(path, messages)).To(Succeed())
+
+			loaded, err := unregistration.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(HaveLen(2))
+			Expect(loaded[0].RegistryMessage).To(Equal(messages[0].RegistryMessage))
+			Expect(loaded[0].SentCount).To(Equal(1))
+			Expect(loaded[0].AddedAt.Equal(messages[0].AddedAt)).To(BeTrue())
+			Expect(loaded[1].RouteType).To(Equal(unregistration.TCPRoute))
+			Expect(loaded[1].TCPRouteMapping.ExternalPort).To(BeEquivalentTo(61000))
+		})
+
+		Context("when the file does not exist", func() {
+			It("returns an error", func() {
+				_, err := unregistration.Load(path)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Restore", func() {
+		BeforeEach(func() {
+			Expect(unregistration.Save(path, messages)).To(Succeed())
+		})
+
+		It("restores the messages into the cache", func() {
+			Expect(unregistration.Restore(logger, path, fakeCache)).To(Succeed())
+			Expect(fakeCache.RestoreCallCount()).To(Equal(1))
+			Expect(fakeCache.RestoreArgsForCall(0)).To(HaveLen(2))
+		})
+
+		Context("when the cache rejects the messages", func() {
+			It("returns the error", func() {
+				fakeCache.RestoreReturns(errors.New("boom"))
+				Expect(unregistration.Restore(logger, path, fakeCache)).To(MatchError("boom"))
+			})
+		})
+	})
+
+	Describe("Writer", func() {
+		var process ifrit.Process
+
+		JustBeforeEach(func() {
+			writer := unregistration.NewWriter(logger, clock, fakeCache, path, 10*time.Second)
+			process = ifrit.Invoke(writer)
+		})
+
+		AfterEach(func() {
+			ifrit.Interrupt(process)
+			Eventually(process.Wait()).Should(Receive())
+		})
+
+		It("starts without unregistrations to restore", func() {
+			Expect(fakeCache.RestoreCallCount()).To(Equal(0))
+			Expect(logger).To(gbytes.Say("no-unregistrations-found"))
+		})
+
+		Context("when unregistrations were saved", func() {
+			BeforeEach(func() {
+				Expect(unregistration.Save(path, messages)).To(Succeed())
+			})
+
+			It("restores them into the cache before it is ready", func() {
+				Expect(fakeCache.RestoreCallCount()).To(Equal(1))
+				Expect(fakeCache.RestoreArgsForCall(0)).To(HaveLen(2))
+			})
+		})
+
+		It("writes the cache on every interval", func() {
+			clock.WaitForWatcherAndIncrement(10 * time.Second)
+			Eventually(func() error {
+				_, err := unregistration.Load(path)
+				return err
+			}).Should(Succeed())
+			Expect(fakeCache.SnapshotCallCount()).To(Equal(1))
+
+			clock.WaitForWatcherAndIncrement(10 * time.Second)
+			Eventually(fakeCache.SnapshotCallCount).Should(Equal(2))
+		})
+
+		It("writes the cache when stopping", func() {
+			ifrit.Interrupt(process)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+
+			loaded, err := unregistration.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(HaveLen(2))
+		})
+	})
+})
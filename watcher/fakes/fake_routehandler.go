@@ -15,11 +15,21 @@ type FakeRouteHandler struct {
 	emitExternalArgsForCall []struct {
 		arg1 lager.Logger
 	}
+	EmitHTTPStub        func(lager.Logger)
+	emitHTTPMutex       sync.RWMutex
+	emitHTTPArgsForCall []struct {
+		arg1 lager.Logger
+	}
 	EmitInternalStub        func(lager.Logger)
 	emitInternalMutex       sync.RWMutex
 	emitInternalArgsForCall []struct {
 		arg1 lager.Logger
 	}
+	EmitTCPStub        func(lager.Logger)
+	emitTCPMutex       sync.RWMutex
+	emitTCPArgsForCall []struct {
+		arg1 lager.Logger
+	}
 	HandleEventStub        func(lager.Logger, models.Event)
 	handleEventMutex       sync.RWMutex
 	handleEventArgsForCall []struct {
@@ -87,6 +97,30 @@ func (fake *FakeRouteHandler) EmitExternalArgsForCall(i int) lager.Logger {
 	return argsForCall.arg1
 }
 
+func (fake *FakeRouteHandler) EmitHTTP(arg1 lager.Logger) {
+	fake.emitHTTPMutex.Lock()
+	fake.emitHTTPArgsForCall = append(fake.emitHTTPArgsForCall, struct {
+		arg1 lager.Logger
+	}{arg1})
+	fake.recordInvocation("EmitHTTP", []interface{}{arg1})
+	fake.emitHTTPMutex.Unlock()
+	if fake.EmitHTTPStub != nil {
+		fake.EmitHTTPStub(arg1)
+	}
+}
+
+func (fake *FakeRouteHandler) EmitHTTPCallCount() int {
+	fake.emitHTTPMutex.RLock()
+	defer fake.emitHTTPMutex.RUnlock()
+	return len(fake.emitHTTPArgsForCall)
+}
+
+func (fake *FakeRouteHandler) EmitHTTPCalls(stub func(lager.Logger)) {
+	fake.emitHTTPMutex.Lock()
+	defer fake.emitHTTPMutex.Unlock()
+	fake.EmitHTTPStub = stub
+}
+
 func (fake *FakeRouteHandler) EmitInternal(arg1 lager.Logger) {
 	fake.emitInternalMutex.Lock()
 	fake.emitInternalArgsForCall = append(fake.emitInternalArgsForCall, struct {
@@ -100,6 +134,8 @@ func (fake *FakeRouteHandler) EmitInternal(arg1 lager.Logger) {
 }
 
 func (fake *FakeRouteHandler) EmitInternalCallCount() int {
+	fake.emitHTTPMutex.RLock()
+	defer fake.emitHTTPMutex.RUnlock()
 	fake.emitInternalMutex.RLock()
 	defer fake.emitInternalMutex.RUnlock()
 	return len(fake.emitInternalArgsForCall)
@@ -118,6 +154,37 @@ func (fake *FakeRouteHandler) EmitInternalArgsForCall(i int) lager.Logger {
 	return argsForCall.arg1
 }
 
+func (fake *FakeRouteHandler) EmitTCP(arg1 lager.Logger) {
+	fake.emitTCPMutex.Lock()
+	fake.emitTCPArgsForCall = append(fake.emitTCPArgsForCall, struct {
+		arg1 lager.Logger
+	}{arg1})
+	fake.recordInvocation("EmitTCP", []interface{}{arg1})
+	fake.emitTCPMutex.Unlock()
+	if fake.EmitTCPStub != nil {
+		fake.EmitTCPStub(arg1)
+	}
+}
+
+func (fake *FakeRouteHandler) EmitTCPCallCount() int {
+	fake.emitTCPMutex.RLock()
+	defer fake.emitTCPMutex.RUnlock()
+	return len(fake.emitTCPArgsForCall)
+}
+
+func (fake *FakeRouteHandler) EmitTCPCalls(stub func(lager.Logger)) {
+	fake.emitTCPMutex.Lock()
+	defer fake.emitTCPMutex.Unlock()
+	fake.EmitTCPStub = stub
+}
+
+func (fake *FakeRouteHandler) EmitTCPArgsForCall(i int) lager.Logger {
+	fake.emitTCPMutex.RLock()
+	defer fake.emitTCPMutex.RUnlock()
+	argsForCall := fake.emitTCPArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeRouteHandler) HandleEvent(arg1 lager.Logger, arg2 models.Event) {
 	fake.handleEventMutex.Lock()
 	fake.handleEventArgsForCall = append(fake.handleEventArgsForCall, struct {
@@ -299,6 +366,8 @@ func (fake *FakeRouteHandler) Invocations() map[string][][]interface{} {
 	defer fake.emitExternalMutex.RUnlock()
 	fake.emitInternalMutex.RLock()
 	defer fake.emitInternalMutex.RUnlock()
+	fake.emitTCPMutex.RLock()
+	defer fake.emitTCPMutex.RUnlock()
 	fake.handleEventMutex.RLock()
 	defer fake.handleEventMutex.RUnlock()
 	fake.refreshDesiredMutex.RLock()
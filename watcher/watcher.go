@@ -31,32 +31,60 @@ type RouteHandler interface {
 	)
 	EmitExternal(logger lager.Logger)
 	EmitInternal(logger lager.Logger)
+	EmitTCP(logger lager.Logger)
+	EmitHTTP(logger lager.Logger)
 	ShouldRefreshDesired(*models.ActualLRP) bool
 	RefreshDesired(lager.Logger, []*models.DesiredLRP)
 }
 
+// HealthReporter is told about the sync and event subscription state so
+// that it can be served on the readiness and health endpoints.
+//
+//go:generate counterfeiter -o fakes/fake_health_reporter.go . HealthReporter
+type HealthReporter interface {
+	WatcherStarted()
+	EventSourceSubscribed()
+	EventSourceFailed()
+	SyncSucceeded()
+}
+
+// BBSClient is the subset of bbs.Client the watcher uses.
+type BBSClient interface {
+	ActualLRPs(logger lager.Logger, traceID string, filter models.ActualLRPFilter) ([]*models.ActualLRP, error)
+	DesiredLRPs(logger lager.Logger, traceID string, filter models.DesiredLRPFilter) ([]*models.DesiredLRP, error)
+	DesiredLRPRoutingInfos(logger lager.Logger, traceID string, filter models.DesiredLRPFilter) ([]*models.DesiredLRP, error)
+	Domains(logger lager.Logger, traceID string) ([]string, error)
+	SubscribeToInstanceEventsByCellID(logger lager.Logger, cellID string) (events.EventSource, error)
+}
+
 type Watcher struct {
 	cellID         string
-	bbsClient      bbs.Client
+	bbsClient      BBSClient
 	clock          clock.Clock
 	routeHandler   RouteHandler
 	syncCh         chan struct{}
 	emitExternalCh chan struct{}
 	emitInternalCh chan struct{}
+	emitTCPCh      chan struct{}
+	emitHTTPCh     chan struct{}
 	logger         lager.Logger
 	metronClient   loggingclient.IngressClient
+	healthReporter HealthReporter
 }
 
 func NewWatcher(
 	cellID string,
-	bbsClient bbs.Client,
+	bbsClient BBSClient,
 	clock clock.Clock,
 	routeHandler RouteHandler,
 	syncCh chan struct{},
 	emitExternalCh chan struct{},
 	emitInternalCh chan struct{},
+	emitTCPCh chan struct{},
+	emitHTTPCh chan struct{},
 	logger lager.Logger,
 	metronClient loggingclient.IngressClient,
+	healthReporter HealthReporter,
 ) *Watcher {
 	return &Watcher{
 		cellID:         cellID,
@@ -66,8 +94,11 @@ func NewWatcher(
 		syncCh:         syncCh,
 		emitExternalCh: emitExternalCh,
 		emitInternalCh: emitInternalCh,
+		emitTCPCh:      emitTCPCh,
+		emitHTTPCh:     emitHTTPCh,
 		logger:         logger.Session("watcher"),
 		metronClient:   metronClient,
+		healthReporter: healthReporter,
 	}
 }
 
@@ -89,6 +120,7 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 	eventSource := &atomic.Value{}
 	var stopEventSource int32
 
+	watcher.healthReporter.WatcherStarted()
 	go watcher.checkForEvents(resubscribeChannel, eventChan, eventSource, watcher.logger)
 	watcher.logger.Debug("listening-on-channels")
 	close(ready)
@@ -116,6 +148,12 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 		case <-watcher.emitInternalCh:
 			logger := watcher.logger.Session("emit-internal")
 			watcher.routeHandler.EmitInternal(logger)
+		case <-watcher.emitTCPCh:
+			logger := watcher.logger.Session("emit-tcp")
+			watcher.routeHandler.EmitTCP(logger)
+		case <-watcher.emitHTTPCh:
+			logger := watcher.logger.Session("emit-http")
+			watcher.routeHandler.EmitHTTP(logger)
 		case syncEvent := <-syncEnd:
 			syncing = false
 			logger := watcher.logger.Session("sync")
@@ -149,6 +187,7 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 				watcher.logger.Error("failed-to-send-route-sync-duration-metric", err)
 			}
 
+			watcher.healthReporter.SyncSucceeded()
 			cachedEvents = make(map[string]models.Event)
 			logger.Info("complete")
 		case <-watcher.syncCh:
@@ -162,6 +201,7 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 			syncing = true
 		case err := <-resubscribeChannel:
 			watcher.logger.Error("event-source-error", err)
+			watcher.healthReporter.EventSourceFailed()
 			if es := eventSource.Load(); es != nil {
 				err := es.(events.EventSource).Close()
 				if err != nil {
@@ -335,6 +375,7 @@ func (w *Watcher) checkForEvents(resubscribeChannel chan error, eventChan chan m
 		return
 	}
 	logger.Info("subscribed-to-bbs-events")
+	w.healthReporter.EventSourceSubscribed()
 
 	eventSource.Store(es)
 
@@ -357,7 +398,7 @@ func (w *Watcher) checkForEvents(resubscribeChannel chan error, eventChan chan m
 	}
 }
 
-func getDesiredLRPs(logger lager.Logger, bbsClient bbs.Client, guids []string) ([]*models.DesiredLRP, error) {
+func getDesiredLRPs(logger lager.Logger, bbsClient BBSClient, guids []string) ([]*models.DesiredLRP, error) {
 	logger.Debug("getting-desired-lrps-routing-info", lager.Data{"guids-length": len(guids)})
 	filter := models.DesiredLRPFilter{ProcessGuids: guids}
 
@@ -18,10 +18,10 @@ import (
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/unregistration"
 	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/route-emitter/watcher/fakes"
 	"code.cloudfoundry.org/routing-api/fake_routing_api"
 	"code.cloudfoundry.org/routing-api/uaaclient"
 	"code.cloudfoundry.org/routing-info/cfroutes"
-	"code.cloudfoundry.org/workpool"
 	"github.com/nats-io/nats.go"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -38,11 +38,13 @@ var _ = Describe("Watcher Integration", func() {
 		syncCh           chan struct{}
 		emitExternalCh   chan struct{}
 		emitInternalCh   chan struct{}
+		emitTCPCh        chan struct{}
 		cellID           string
 		testWatcher      *watcher.Watcher
 		process          ifrit.Process
 		logger           *lagertest.TestLogger
 		fakeMetronClient *mfakes.FakeIngressClient
+		healthReporter   *fakes.FakeHealthReporter
 	)
 
 	BeforeEach(func() {
@@ -56,12 +58,14 @@ var _ = Describe("Watcher Integration", func() {
 		syncCh = make(chan struct{})
 		emitExternalCh = make(chan struct{})
 		emitInternalCh = make(chan struct{})
+		emitTCPCh = make(chan struct{})
 
 		logger = lagertest.NewTestLogger("test")
-		workPool, err := workpool.NewWorkPool(1)
+		lanes, err := emitter.NewEmitLanes(1)
 		Expect(err).NotTo(HaveOccurred())
 		fakeMetronClient = &mfakes.FakeIngressClient{}
-		natsEmitter := emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetronClient, false)
+		healthReporter = &fakes.FakeHealthReporter{}
+		natsEmitter := emitter.NewNATSEmitter(natsClient, lanes, logger, fakeMetronClient, false, nil)
 		natsTable := routingtable.NewRoutingTable(false, fakeMetronClient)
 
 		clock := fakeclock.NewFakeClock(time.Now())
@@ -69,7 +73,7 @@ var _ = Describe("Watcher Integration", func() {
 		Expect(err).NotTo(HaveOccurred())
 		routingAPIEmitter := emitter.NewRoutingAPIEmitter(logger, routingApiClient, uaaTokenFetcher, 100)
 		unregistrationCache := unregistration.NewCache(logger)
-		handler := routehandlers.NewHandler(natsTable, natsEmitter, routingAPIEmitter, false, fakeMetronClient, unregistrationCache)
+		handler := routehandlers.NewHandler(natsTable, natsEmitter, routingAPIEmitter, nil, false, fakeMetronClient, unregistrationCache, 0, 1, nil)
 		testWatcher = watcher.NewWatcher(
 			cellID,
 			bbsClient,
@@ -78,8 +82,11 @@ var _ = Describe("Watcher Integration", func() {
 			syncCh,
 			emitExternalCh,
 			emitInternalCh,
+			emitTCPCh,
+			nil,
 			logger,
 			fakeMetronClient,
+			healthReporter,
 		)
 	})
 
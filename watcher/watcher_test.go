@@ -81,7 +81,10 @@ var _ = Describe("Watcher", func() {
 		syncCh           chan struct{}
 		emitExternalCh   chan struct{}
 		emitInternalCh   chan struct{}
+		emitTCPCh        chan struct{}
+		emitHTTPCh       chan struct{}
 		fakeMetronClient *mfakes.FakeIngressClient
+		healthReporter   *fakes.FakeHealthReporter
 	)
 
 	BeforeEach(func() {
@@ -96,8 +99,11 @@ var _ = Describe("Watcher", func() {
 		syncCh = make(chan struct{})
 		emitExternalCh = make(chan struct{})
 		emitInternalCh = make(chan struct{})
+		emitTCPCh = make(chan struct{})
+		emitHTTPCh = make(chan struct{})
 		cellID = ""
 		fakeMetronClient = &mfakes.FakeIngressClient{}
+		healthReporter = &fakes.FakeHealthReporter{}
 	})
 
 	JustBeforeEach(func() {
@@ -109,8 +115,11 @@ var _ = Describe("Watcher", func() {
 			syncCh,
 			emitExternalCh,
 			emitInternalCh,
+			emitTCPCh,
+			emitHTTPCh,
 			logger,
 			fakeMetronClient,
+			healthReporter,
 		)
 		process = ifrit.Invoke(testWatcher)
 	})
@@ -144,6 +153,11 @@ var _ = Describe("Watcher", func() {
 				Expect(actualCellID).To(Equal(""))
 			})
 		})
+
+		It("reports the subscription to the health reporter", func() {
+			Eventually(healthReporter.EventSourceSubscribedCallCount).Should(Equal(1))
+			Expect(healthReporter.WatcherStartedCallCount()).To(Equal(1))
+		})
 	})
 
 	Context("handle DesiredLRPCreatedEvent", func() {
@@ -294,6 +308,10 @@ var _ = Describe("Watcher", func() {
 			Eventually(bbsClient.SubscribeToInstanceEventsByCellIDCallCount, 5*time.Second, 300*time.Millisecond).Should(BeNumerically(">=", 2))
 			Eventually(logger).Should(gbytes.Say("event-source-error"))
 		})
+
+		It("reports the failure to the health reporter", func() {
+			Eventually(healthReporter.EventSourceFailedCallCount).Should(BeNumerically(">=", 1))
+		})
 	})
 
 	Context("when subscribe to events fails", func() {
@@ -339,6 +357,20 @@ var _ = Describe("Watcher", func() {
 		})
 	})
 
+	Describe("emit tcp event", func() {
+		It("refreshes the tcp routes", func() {
+			emitTCPCh <- struct{}{}
+			Eventually(routeHandler.EmitTCPCallCount).Should(Equal(1))
+		})
+	})
+
+	Describe("emit http event", func() {
+		It("refreshes the http routes in the routing api", func() {
+			emitHTTPCh <- struct{}{}
+			Eventually(routeHandler.EmitHTTPCallCount).Should(Equal(1))
+		})
+	})
+
 	Describe("Sync Events", func() {
 		var (
 			errCh   chan error
@@ -551,6 +583,16 @@ var _ = Describe("Watcher", func() {
 				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
 				Expect(bbsClient.ActualLRPsCallCount()).To(Equal(2))
 			})
+
+			It("only reports successful syncs to the health reporter", func() {
+				Eventually(bbsClient.ActualLRPsCallCount).Should(Equal(1))
+				Consistently(healthReporter.SyncSucceededCallCount).Should(Equal(0))
+
+				close(errCh)
+				syncCh <- struct{}{}
+
+				Eventually(healthReporter.SyncSucceededCallCount).Should(Equal(1))
+			})
 		})
 
 		Context("when one of the actual lrps is invalid", func() {
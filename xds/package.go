@@ -0,0 +1 @@
+package xds // import "code.cloudfoundry.org/route-emitter/xds"
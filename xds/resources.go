@@ -0,0 +1,219 @@
+package xds
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// RouteConfigName is the name of the route configuration that holds a
+// virtual host for every hostname in the routing table.
+const RouteConfigName = "route-emitter"
+
+const connectTimeout = 5 * time.Second
+
+// ClusterName returns the name of the cluster for a routing key.
+func ClusterName(key routingtable.RoutingKey) string {
+	return fmt.Sprintf("%s-%d", key.ProcessGUID, key.ContainerPort)
+}
+
+type routeTarget struct {
+	domain   string
+	prefix   string
+	clusters []string
+}
+
+// translate converts the http entries of the routing table into clusters,
+// load assignments and a single route configuration. The output is sorted so
+// that the same table always produces the same resources.
+func translate(entries map[string]routingtable.Entry, directInstanceRoutes bool) map[resource.Type][]types.Resource {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	clusters := []types.Resource{}
+	loadAssignments := []types.Resource{}
+	targets := map[string]*routeTarget{}
+
+	for _, key := range keys {
+		entry := entries[key]
+		if len(entry.Routes) == 0 {
+			continue
+		}
+
+		name := ClusterName(entry.RoutingKey)
+		clusters = append(clusters, newCluster(name))
+		loadAssignments = append(loadAssignments, newLoadAssignment(name, entry.Endpoints, directInstanceRoutes))
+
+		for _, route := range entry.Routes {
+			domain, prefix := splitHostname(route.Hostname)
+			target, ok := targets[route.Hostname]
+			if !ok {
+				target = &routeTarget{domain: domain, prefix: prefix}
+				targets[route.Hostname] = target
+			}
+			target.clusters = append(target.clusters, name)
+		}
+	}
+
+	return map[resource.Type][]types.Resource{
+		resource.ClusterType:  clusters,
+		resource.EndpointType: loadAssignments,
+		resource.RouteType:    {newRouteConfiguration(targets)},
+	}
+}
+
+func newCluster(name string) *clusterv3.Cluster {
+	return &clusterv3.Cluster{
+		Name:                 name,
+		ConnectTimeout:       durationpb.New(connectTimeout),
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_EDS},
+		LbPolicy:             clusterv3.Cluster_ROUND_ROBIN,
+		EdsClusterConfig: &clusterv3.Cluster_EdsClusterConfig{
+			EdsConfig: &corev3.ConfigSource{
+				ResourceApiVersion: corev3.ApiVersion_V3,
+				ConfigSourceSpecifier: &corev3.ConfigSource_Ads{
+					Ads: &corev3.AggregatedConfigSource{},
+				},
+			},
+		},
+	}
+}
+
+func newLoadAssignment(name string, endpoints []routingtable.Endpoint, directInstanceRoutes bool) *endpointv3.ClusterLoadAssignment {
+	lbEndpoints := make([]*endpointv3.LbEndpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		host, port := endpointAddress(endpoint, directInstanceRoutes)
+		lbEndpoints = append(lbEndpoints, &endpointv3.LbEndpoint{
+			HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+				Endpoint: &endpointv3.Endpoint{
+					Address: &corev3.Address{
+						Address: &corev3.Address_SocketAddress{
+							SocketAddress: &corev3.SocketAddress{
+								Protocol:      corev3.SocketAddress_TCP,
+								Address:       host,
+								PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: port},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	return &endpointv3.ClusterLoadAssignment{
+		ClusterName: name,
+		Endpoints:   []*endpointv3.LocalityLbEndpoints{{LbEndpoints: lbEndpoints}},
+	}
+}
+
+// endpointAddress mirrors the addresses emitted to gorouter: the container
+// address for direct instance routes and the host address otherwise,
+// preferring the TLS proxy port when there is one.
+func endpointAddress(endpoint routingtable.Endpoint, directInstanceRoutes bool) (string, uint32) {
+	if endpoint.IsDirectInstanceRoute(directInstanceRoutes) {
+		if endpoint.ContainerTlsProxyPort != 0 {
+			return endpoint.ContainerIP, endpoint.ContainerTlsProxyPort
+		}
+		return endpoint.ContainerIP, endpoint.ContainerPort
+	}
+
+	if endpoint.TlsProxyPort != 0 {
+		return endpoint.Host, endpoint.TlsProxyPort
+	}
+	return endpoint.Host, endpoint.Port
+}
+
+// splitHostname separates the context path from a route hostname, e.g.
+// foo.example.com/bar is matched on the foo.example.com domain with the /bar
+// prefix.
+func splitHostname(hostname string) (string, string) {
+	idx := strings.Index(hostname, "/")
+	if idx < 0 {
+		return hostname, "/"
+	}
+	return hostname[:idx], hostname[idx:]
+}
+
+func newRouteConfiguration(targets map[string]*routeTarget) *routev3.RouteConfiguration {
+	byDomain := map[string][]*routeTarget{}
+	for _, target := range targets {
+		byDomain[target.domain] = append(byDomain[target.domain], target)
+	}
+
+	domains := make([]string, 0, len(byDomain))
+	for domain := range byDomain {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	virtualHosts := make([]*routev3.VirtualHost, 0, len(domains))
+	for _, domain := range domains {
+		domainTargets := byDomain[domain]
+		// the longest prefix has to be matched first
+		sort.Slice(domainTargets, func(i, j int) bool {
+			if len(domainTargets[i].prefix) != len(domainTargets[j].prefix) {
+				return len(domainTargets[i].prefix) > len(domainTargets[j].prefix)
+			}
+			return domainTargets[i].prefix < domainTargets[j].prefix
+		})
+
+		routes := make([]*routev3.Route, 0, len(domainTargets))
+		for _, target := range domainTargets {
+			routes = append(routes, &routev3.Route{
+				Match: &routev3.RouteMatch{
+					PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: target.prefix},
+				},
+				Action: &routev3.Route_Route{Route: newRouteAction(target.clusters)},
+			})
+		}
+
+		virtualHosts = append(virtualHosts, &routev3.VirtualHost{
+			Name:    domain,
+			Domains: []string{domain},
+			Routes:  routes,
+		})
+	}
+
+	return &routev3.RouteConfiguration{
+		Name:         RouteConfigName,
+		VirtualHosts: virtualHosts,
+	}
+}
+
+// newRouteAction balances equally between all clusters that claim the same
+// hostname, the same way gorouter does for a shared route.
+func newRouteAction(clusters []string) *routev3.RouteAction {
+	if len(clusters) == 1 {
+		return &routev3.RouteAction{
+			ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: clusters[0]},
+		}
+	}
+
+	weights := make([]*routev3.WeightedCluster_ClusterWeight, 0, len(clusters))
+	for _, cluster := range clusters {
+		weights = append(weights, &routev3.WeightedCluster_ClusterWeight{
+			Name:   cluster,
+			Weight: wrapperspb.UInt32(1),
+		})
+	}
+	return &routev3.RouteAction{
+		ClusterSpecifier: &routev3.RouteAction_WeightedClusters{
+			WeightedClusters: &routev3.WeightedCluster{Clusters: weights},
+		},
+	}
+}
@@ -0,0 +1,260 @@
+package xds
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	endpointservice "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	routeservice "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// nodeGroup is used as the snapshot key for every node, all Envoys receive
+// the same configuration.
+const nodeGroup = "route-emitter"
+
+// updateDelay is how long the server waits after an emit before it rebuilds
+// the snapshot, the emits in between are folded into the same rebuild. It
+// also bounds how often the snapshot is rebuilt.
+const updateDelay = 100 * time.Millisecond
+
+type nodeHash struct{}
+
+func (nodeHash) ID(*corev3.Node) string {
+	return nodeGroup
+}
+
+// Server is an xDS control plane that serves the http routes of the routing
+// table as clusters, endpoints and routes. It implements
+// emitter.NATSEmitter so it can be notified of every change to the table.
+type Server struct {
+	logger               lager.Logger
+	table                routingtable.RoutingTable
+	listenAddress        string
+	directInstanceRoutes bool
+	tlsConfig            *tls.Config
+	cache                cachev3.SnapshotCache
+	updates              chan struct{}
+
+	mutex   sync.Mutex
+	version string
+	addr    net.Addr
+}
+
+// NewServer returns a server that listens on listenAddress. It serves over
+// TLS with tlsConfig, a nil tlsConfig serves plain text.
+func NewServer(
+	logger lager.Logger,
+	table routingtable.RoutingTable,
+	listenAddress string,
+	directInstanceRoutes bool,
+	tlsConfig *tls.Config,
+) *Server {
+	return &Server{
+		logger:               logger.Session("xds-server"),
+		table:                table,
+		listenAddress:        listenAddress,
+		directInstanceRoutes: directInstanceRoutes,
+		tlsConfig:            tlsConfig,
+		cache:                cachev3.NewSnapshotCache(true, nodeHash{}, &cacheLogger{logger: logger.Session("xds-cache")}),
+		updates:              make(chan struct{}, 1),
+	}
+}
+
+// Emit ignores the messages and schedules a rebuild of the snapshot from the
+// routing table. The rebuild runs off the caller's goroutine updateDelay
+// later, so a burst of events rebuilds the snapshot once.
+func (s *Server) Emit(routingtable.MessagesToEmit) error {
+	select {
+	case s.updates <- struct{}{}:
+	default:
+		// a rebuild is already scheduled and will see this change
+	}
+	return nil
+}
+
+// Update rebuilds the snapshot from the routing table and pushes it to all
+// connected clients when it changed. The snapshot cache versions every
+// resource, so clients using incremental xDS only receive the resources that
+// changed.
+func (s *Server) Update() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	resources := translate(s.table.Entries(routingtable.EntryFilter{}).HTTP, s.directInstanceRoutes)
+	version, err := snapshotVersion(resources)
+	if err != nil {
+		s.logger.Error("failed-to-version-snapshot", err)
+		return err
+	}
+
+	if version == s.version {
+		return nil
+	}
+
+	snapshot, err := cachev3.NewSnapshot(version, resources)
+	if err != nil {
+		s.logger.Error("failed-to-create-snapshot", err)
+		return err
+	}
+
+	err = s.cache.SetSnapshot(context.Background(), nodeGroup, snapshot)
+	if err != nil {
+		s.logger.Error("failed-to-set-snapshot", err)
+		return err
+	}
+
+	s.version = version
+	s.logger.Debug("updated-snapshot", lager.Data{
+		"version":  version,
+		"clusters": len(resources[resource.ClusterType]),
+	})
+	return nil
+}
+
+// Addr returns the address the server is listening on once it is ready.
+func (s *Server) Addr() net.Addr {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.addr
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := s.logger.Session("run")
+
+	listener, err := net.Listen("tcp", s.listenAddress)
+	if err != nil {
+		logger.Error("failed-to-listen", err)
+		return err
+	}
+
+	s.mutex.Lock()
+	s.addr = listener.Addr()
+	s.mutex.Unlock()
+
+	err = s.Update()
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	xdsServer := serverv3.NewServer(ctx, s.cache, serverv3.CallbackFuncs{})
+	var options []grpc.ServerOption
+	if s.tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	grpcServer := grpc.NewServer(options...)
+	discoverygrpc.RegisterAggregatedDiscoveryServiceServer(grpcServer, xdsServer)
+	clusterservice.RegisterClusterDiscoveryServiceServer(grpcServer, xdsServer)
+	endpointservice.RegisterEndpointDiscoveryServiceServer(grpcServer, xdsServer)
+	routeservice.RegisterRouteDiscoveryServiceServer(grpcServer, xdsServer)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- grpcServer.Serve(listener)
+	}()
+	go s.updateLoop(ctx)
+
+	logger.Info("started", lager.Data{"address": listener.Addr().String(), "tls": s.tlsConfig != nil})
+	close(ready)
+
+	select {
+	case err := <-errCh:
+		logger.Error("failed-serving", err)
+		return err
+	case <-signals:
+		logger.Info("stopping")
+		cancel()
+		grpcServer.Stop()
+		return nil
+	}
+}
+
+// updateLoop rebuilds the snapshot for the emits scheduled by Emit.
+func (s *Server) updateLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.updates:
+		}
+
+		timer := time.NewTimer(updateDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// the emits that arrived while waiting are covered by this rebuild
+		select {
+		case <-s.updates:
+		default:
+		}
+
+		// errors are logged by Update, the next emit retries
+		_ = s.Update()
+	}
+}
+
+// cacheLogger adapts lager to the logger interface of the snapshot cache.
+type cacheLogger struct {
+	logger lager.Logger
+}
+
+func (l *cacheLogger) Debugf(format string, args ...interface{}) {
+	l.logger.Debug("debug", lager.Data{"message": fmt.Sprintf(format, args...)})
+}
+
+func (l *cacheLogger) Infof(format string, args ...interface{}) {
+	l.logger.Debug("info", lager.Data{"message": fmt.Sprintf(format, args...)})
+}
+
+func (l *cacheLogger) Warnf(format string, args ...interface{}) {
+	l.logger.Info("warn", lager.Data{"message": fmt.Sprintf(format, args...)})
+}
+
+func (l *cacheLogger) Errorf(format string, args ...interface{}) {
+	l.logger.Error("error", fmt.Errorf(format, args...))
+}
+
+func snapshotVersion(resources map[resource.Type][]types.Resource) (string, error) {
+	hash := fnv.New64a()
+	marshaler := proto.MarshalOptions{Deterministic: true}
+
+	for _, typeURL := range []resource.Type{resource.ClusterType, resource.EndpointType, resource.RouteType} {
+		hash.Write([]byte(typeURL))
+		for _, res := range resources[typeURL] {
+			payload, err := marshaler.Marshal(res)
+			if err != nil {
+				return "", err
+			}
+			hash.Write(payload)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,286 @@
+package xds_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	"code.cloudfoundry.org/route-emitter/tlsreload"
+	"code.cloudfoundry.org/route-emitter/xds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		fakeTable *fakeroutingtable.FakeRoutingTable
+		server    *xds.Server
+		process   ifrit.Process
+		conn      *grpc.ClientConn
+		stream    discoverygrpc.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+		cancel    context.CancelFunc
+
+		serverTLSConfig   *tls.Config
+		clientCredentials credentials.TransportCredentials
+
+		keyA, keyB routingtable.RoutingKey
+	)
+
+	node := &corev3.Node{Id: "envoy-1"}
+
+	entriesFor := func(entries ...routingtable.Entry) routingtable.TableEntries {
+		http := map[string]routingtable.Entry{}
+		for _, entry := range entries {
+			http[entry.RoutingKey.String()] = entry
+		}
+		return routingtable.TableEntries{HTTP: http}
+	}
+
+	request := func(typeURL string, versionInfo, nonce string, names ...string) {
+		Expect(stream.Send(&discoverygrpc.DiscoveryRequest{
+			Node:          node,
+			TypeUrl:       typeURL,
+			VersionInfo:   versionInfo,
+			ResponseNonce: nonce,
+			ResourceNames: names,
+		})).To(Succeed())
+	}
+
+	receive := func() *discoverygrpc.DiscoveryResponse {
+		responses := make(chan *discoverygrpc.DiscoveryResponse, 1)
+		go func() {
+			defer GinkgoRecover()
+			response, err := stream.Recv()
+			Expect(err).NotTo(HaveOccurred())
+			responses <- response
+		}()
+
+		var response *discoverygrpc.DiscoveryResponse
+		Eventually(responses, 5*time.Second).Should(Receive(&response))
+		return response
+	}
+
+	clustersIn := func(response *discoverygrpc.DiscoveryResponse) []string {
+		names := []string{}
+		for _, res := range response.Resources {
+			cluster := &clusterv3.Cluster{}
+			Expect(res.UnmarshalTo(cluster)).To(Succeed())
+			names = append(names, cluster.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		keyA = routingtable.RoutingKey{ProcessGUID: "process-a", ContainerPort: 8080}
+		keyB = routingtable.RoutingKey{ProcessGUID: "process-b", ContainerPort: 8080}
+
+		fakeTable = &fakeroutingtable.FakeRoutingTable{}
+		fakeTable.EntriesReturns(entriesFor(
+			routingtable.Entry{
+				RoutingKey: keyA,
+				Routes: []routingtable.Route{
+					{Hostname: "a.example.com", LogGUID: "log-a"},
+					{Hostname: "shared.example.com/path", LogGUID: "log-a"},
+				},
+				Endpoints: []routingtable.Endpoint{
+					{InstanceGUID: "ig-a-0", Host: "10.0.0.1", Port: 61000, ContainerIP: "172.16.0.1", ContainerPort: 8080},
+					{InstanceGUID: "ig-a-1", Host: "10.0.0.2", Port: 61001, TlsProxyPort: 61443, ContainerIP: "172.16.0.2", ContainerPort: 8080},
+				},
+			},
+			routingtable.Entry{
+				RoutingKey: keyB,
+				Routes: []routingtable.Route{
+					{Hostname: "shared.example.com/path", LogGUID: "log-b"},
+				},
+				Endpoints: []routingtable.Endpoint{
+					{
+						InstanceGUID:     "ig-b-0",
+						Host:             "10.0.0.3",
+						Port:             61002,
+						ContainerIP:      "172.16.0.3",
+						ContainerPort:    8080,
+						PreferredAddress: models.ActualLRPNetInfo_PreferredAddressInstance,
+					},
+				},
+			},
+		))
+
+		serverTLSConfig = nil
+		clientCredentials = insecure.NewCredentials()
+	})
+
+	JustBeforeEach(func() {
+		server = xds.NewServer(lagertest.NewTestLogger("test"), fakeTable, "127.0.0.1:0", false, serverTLSConfig)
+		process = ifrit.Invoke(server)
+
+		var err error
+		conn, err = grpc.Dial(server.Addr().String(), grpc.WithTransportCredentials(clientCredentials))
+		Expect(err).NotTo(HaveOccurred())
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		stream, err = discoverygrpc.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cancel()
+		conn.Close()
+		ifrit.Interrupt(process)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("serves a cluster per routing key", func() {
+		request(resource.ClusterType, "", "")
+		response := receive()
+		Expect(clustersIn(response)).To(ConsistOf("process-a-8080", "process-b-8080"))
+	})
+
+	It("serves the endpoints of every cluster", func() {
+		request(resource.EndpointType, "", "", "process-a-8080", "process-b-8080")
+		response := receive()
+
+		addresses := map[string][]string{}
+		for _, res := range response.Resources {
+			assignment := &endpointv3.ClusterLoadAssignment{}
+			Expect(res.UnmarshalTo(assignment)).To(Succeed())
+			for _, locality := range assignment.Endpoints {
+				for _, lbEndpoint := range locality.LbEndpoints {
+					socket := lbEndpoint.GetEndpoint().Address.GetSocketAddress()
+					addresses[assignment.ClusterName] = append(addresses[assignment.ClusterName], fmt.Sprintf("%s:%d", socket.Address, socket.GetPortValue()))
+				}
+			}
+		}
+
+		Expect(addresses).To(Equal(map[string][]string{
+			"process-a-8080": {"10.0.0.1:61000", "10.0.0.2:61443"},
+			"process-b-8080": {"172.16.0.3:8080"},
+		}))
+	})
+
+	It("serves a virtual host per hostname", func() {
+		request(resource.RouteType, "", "", xds.RouteConfigName)
+		response := receive()
+		Expect(response.Resources).To(HaveLen(1))
+
+		routeConfig := &routev3.RouteConfiguration{}
+		Expect(response.Resources[0].UnmarshalTo(routeConfig)).To(Succeed())
+		Expect(routeConfig.VirtualHosts).To(HaveLen(2))
+
+		Expect(routeConfig.VirtualHosts[0].Domains).To(Equal([]string{"a.example.com"}))
+		Expect(routeConfig.VirtualHosts[0].Routes[0].GetMatch().GetPrefix()).To(Equal("/"))
+		Expect(routeConfig.VirtualHosts[0].Routes[0].GetRoute().GetCluster()).To(Equal("process-a-8080"))
+
+		Expect(routeConfig.VirtualHosts[1].Domains).To(Equal([]string{"shared.example.com"}))
+		Expect(routeConfig.VirtualHosts[1].Routes[0].GetMatch().GetPrefix()).To(Equal("/path"))
+		weighted := routeConfig.VirtualHosts[1].Routes[0].GetRoute().GetWeightedClusters().GetClusters()
+		Expect(weighted).To(HaveLen(2))
+		Expect(weighted[0].Name).To(Equal("process-a-8080"))
+		Expect(weighted[1].Name).To(Equal("process-b-8080"))
+	})
+
+	Context("when TLS is configured", func() {
+		var certDepot string
+
+		BeforeEach(func() {
+			var err error
+			certDepot, err = os.MkdirTemp("", "xds")
+			Expect(err).NotTo(HaveOccurred())
+
+			certAuthority, err := certauthority.NewCertAuthority(certDepot, "ca")
+			Expect(err).NotTo(HaveOccurred())
+			_, caFile := certAuthority.CAAndKey()
+
+			serverKeyFile, serverCertFile, err := certAuthority.GenerateSelfSignedCertAndKey("xds", []string{"xds"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			serverSource, err := tlsreload.NewSource("XDSServerCertificateExpiry", metrics.Labels{}, serverCertFile, serverKeyFile, caFile)
+			Expect(err).NotTo(HaveOccurred())
+			serverTLSConfig, err = serverSource.ServerTLSConfig()
+			Expect(err).NotTo(HaveOccurred())
+
+			clientKeyFile, clientCertFile, err := certAuthority.GenerateSelfSignedCertAndKey("envoy", []string{"envoy"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			clientSource, err := tlsreload.NewSource("XDSClientCertificateExpiry", metrics.Labels{}, clientCertFile, clientKeyFile, caFile)
+			Expect(err).NotTo(HaveOccurred())
+			clientTLSConfig, err := clientSource.ClientTLSConfig()
+			Expect(err).NotTo(HaveOccurred())
+			clientTLSConfig.ServerName = "xds"
+			clientCredentials = credentials.NewTLS(clientTLSConfig)
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(certDepot)).To(Succeed())
+		})
+
+		It("serves over mutual TLS", func() {
+			request(resource.ClusterType, "", "")
+			response := receive()
+			Expect(clustersIn(response)).To(HaveLen(2))
+		})
+	})
+
+	Context("when the routing table changes", func() {
+		It("pushes the update to connected clients", func() {
+			request(resource.ClusterType, "", "")
+			response := receive()
+			Expect(clustersIn(response)).To(HaveLen(2))
+
+			request(resource.ClusterType, response.VersionInfo, response.Nonce)
+			fakeTable.EntriesReturns(entriesFor(routingtable.Entry{
+				RoutingKey: keyA,
+				Routes:     []routingtable.Route{{Hostname: "a.example.com", LogGUID: "log-a"}},
+			}))
+			Expect(server.Emit(routingtable.MessagesToEmit{})).To(Succeed())
+
+			update := receive()
+			Expect(update.VersionInfo).NotTo(Equal(response.VersionInfo))
+			Expect(clustersIn(update)).To(ConsistOf("process-a-8080"))
+		})
+
+		It("does not push an update when the resources are unchanged", func() {
+			request(resource.ClusterType, "", "")
+			response := receive()
+
+			request(resource.ClusterType, response.VersionInfo, response.Nonce)
+			Expect(server.Emit(routingtable.MessagesToEmit{})).To(Succeed())
+
+			received := make(chan struct{})
+			go func() {
+				stream.Recv()
+				close(received)
+			}()
+			Consistently(received).ShouldNot(BeClosed())
+		})
+
+		It("rebuilds the snapshot once for a burst of emits, off the emitting goroutine", func() {
+			Expect(fakeTable.EntriesCallCount()).To(Equal(1))
+
+			for i := 0; i < 10; i++ {
+				Expect(server.Emit(routingtable.MessagesToEmit{})).To(Succeed())
+			}
+			Expect(fakeTable.EntriesCallCount()).To(Equal(1))
+
+			Eventually(fakeTable.EntriesCallCount).Should(Equal(2))
+			Consistently(fakeTable.EntriesCallCount).Should(Equal(2))
+		})
+	})
+})